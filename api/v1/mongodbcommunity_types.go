@@ -9,23 +9,38 @@ import (
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/contains"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/scale"
+	"github.com/stretchr/objx"
 
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"k8s.io/apimachinery/pkg/types"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type Type string
 
 const (
-	ReplicaSet Type = "ReplicaSet"
+	ReplicaSet     Type = "ReplicaSet"
+	ShardedCluster Type = "ShardedCluster"
+	Standalone     Type = "Standalone"
 )
 
+// defaultExternalHorizonName is the name of the horizon of the external addresses when it isn't set.
+const defaultExternalHorizonName = "external"
+
+// DefaultPort is the port mongod and mongos listen on when spec.port isn't set.
+const DefaultPort = 27017
+
+// defaultConfigServerMembers is the number of members of the config server replica set when it isn't set.
+const defaultConfigServerMembers = 3
+
 type Phase string
 
 const (
@@ -43,12 +58,29 @@ type MongoDBCommunitySpec struct {
 	// Members is the number of members in the replica set
 	// +optional
 	Members int `json:"members"`
+	// Arbiters is the number of arbiters, which vote in elections but hold no data. They run in a separate StatefulSet
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Arbiters int `json:"arbiters,omitempty"`
+	// AnalyticsMembers adds hidden members dedicated to analytics to the replica set. They run in a separate StatefulSet
+	// +optional
+	AnalyticsMembers AnalyticsMembersConfiguration `json:"analyticsMembers,omitempty"`
 	// Type defines which type of MongoDB deployment the resource should create
-	// +kubebuilder:validation:Enum=ReplicaSet
+	// +kubebuilder:validation:Enum=ReplicaSet;ShardedCluster;Standalone
 	Type Type `json:"type"`
+	// ShardedCluster configures the components of the deployment when the type is ShardedCluster.
+	// +optional
+	ShardedCluster ShardedClusterConfiguration `json:"shardedCluster,omitempty"`
 	// Version defines which version of MongoDB will be used
 	Version string `json:"version"`
 
+	// Port is the port the members listen on, and the port of the Services which expose them. It defaults to
+	// net.port of additionalMongodConfig, or 27017
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int `json:"port,omitempty"`
+
 	// FeatureCompatibilityVersion configures the feature compatibility version that will
 	// be set for the deployment
 	// +optional
@@ -63,6 +95,24 @@ type MongoDBCommunitySpec struct {
 	// +optional
 	ReplicaSetHorizons ReplicaSetHorizonConfiguration `json:"replicaSetHorizons,omitempty"`
 
+	// ExternalAccess creates a Service for each member, which makes it reachable from outside of the cluster,
+	// and adds the external address of each member to its horizons.
+	// +optional
+	ExternalAccess ExternalAccess `json:"externalAccess,omitempty"`
+
+	// Services creates additional ClusterIP Services, for the clients which can't discover the members of the replica set.
+	// +optional
+	Services Services `json:"services,omitempty"`
+
+	// MemberConfig overrides the replica set configuration of the members, indexed by the ordinal of their pod.
+	// Members without an entry keep the default configuration.
+	// +optional
+	MemberConfig []MemberConfiguration `json:"memberConfig,omitempty"`
+
+	// ReplicaSetSettings configures the replica set settings and the cluster-wide default read and write concern.
+	// +optional
+	ReplicaSetSettings ReplicaSetSettings `json:"replicaSetSettings,omitempty"`
+
 	// Security configures security features, such as TLS, and authentication settings for a deployment
 	// +required
 	Security Security `json:"security"`
@@ -81,12 +131,298 @@ type MongoDBCommunitySpec struct {
 	// +optional
 	// +nullable
 	AdditionalMongodConfig MongodConfiguration `json:"additionalMongodConfig,omitempty"`
+
+	// Metrics deploys a Prometheus exporter next to each member, together with the user it connects with
+	// +optional
+	Metrics Metrics `json:"metrics,omitempty"`
+
+	// Backup schedules a CronJob which dumps the deployment, together with the user it connects with
+	// +optional
+	Backup Backup `json:"backup,omitempty"`
+}
+
+// Metrics configures the Prometheus exporter.
+type Metrics struct {
+	// Enabled deploys the exporter, its Service and ServiceMonitor, and creates its user
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// User configures the user the exporter connects with. Defaults to the "metrics" user,
+	// with the clusterMonitor role and read access to the local database
+	// +optional
+	User InternalUser `json:"user,omitempty"`
+}
+
+// ExternalAccess configures the Services which expose each member outside of the cluster.
+type ExternalAccess struct {
+	// Enabled creates a Service for each member
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Type is the type of the Services. Defaults to LoadBalancer
+	// +kubebuilder:validation:Enum=LoadBalancer;NodePort
+	// +optional
+	Type corev1.ServiceType `json:"type,omitempty"`
+
+	// Annotations are added to the Services, for example to configure the load balancers of the cloud provider
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// HorizonName is the name of the horizon the external addresses are added to. Defaults to "external"
+	// +optional
+	HorizonName string `json:"horizonName,omitempty"`
+
+	// NodeHostname is the address of the nodes the clients reach the NodePort of each member through.
+	// It is required when the type is NodePort
+	// +optional
+	NodeHostname string `json:"nodeHostname,omitempty"`
+}
+
+// Services configures the additional ClusterIP Services of a replica set.
+type Services struct {
+	// Client creates the "<name>-client" Service, which selects the ready members
+	// +optional
+	Client bool `json:"client,omitempty"`
+
+	// Primary creates the "<name>-primary" Service, which selects the primary
+	// +optional
+	Primary bool `json:"primary,omitempty"`
+
+	// Secondary creates the "<name>-secondary" Service, which selects the secondaries, for the read-only clients
+	// +optional
+	Secondary bool `json:"secondary,omitempty"`
+}
+
+// ServiceType returns the type of the Services, which defaults to LoadBalancer.
+func (e ExternalAccess) ServiceType() corev1.ServiceType {
+	if e.Type == "" {
+		return corev1.ServiceTypeLoadBalancer
+	}
+	return e.Type
+}
+
+// GetHorizonName returns the name of the horizon of the external addresses, which defaults to "external".
+func (e ExternalAccess) GetHorizonName() string {
+	if e.HorizonName == "" {
+		return defaultExternalHorizonName
+	}
+	return e.HorizonName
+}
+
+// Backup configures the scheduled backups.
+type Backup struct {
+	// Enabled schedules the backup CronJob and creates its user
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// User configures the user the backup job connects with. Defaults to the "backup" user, with the backup role
+	// +optional
+	User InternalUser `json:"user,omitempty"`
+}
+
+// InternalUser configures a user the operator creates for one of its own features. The user is stored in the admin database.
+type InternalUser struct {
+	// Name is the username of the user
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Roles replaces the default roles of the user
+	// +optional
+	Roles []Role `json:"roles,omitempty"`
+
+	// PasswordSecretRef is a reference to the secret containing the password of the user. A password is generated
+	// if the secret doesn't exist. Defaults to the "<metadata.name>-<metrics|backup>-user" secret
+	// +optional
+	PasswordSecretRef SecretKeyReference `json:"passwordSecretRef,omitempty"`
+
+	// ScramCredentialsSecretName appended by string "scram-credentials" is the name of the secret object created by the operator
+	// for storing SCRAM credentials. Defaults to "<metadata.name>-<metrics|backup>-user"
+	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$
+	// +optional
+	ScramCredentialsSecretName string `json:"scramCredentialsSecretName,omitempty"`
 }
 
 // ReplicaSetHorizonConfiguration holds the split horizon DNS settings for
 // replica set members.
 type ReplicaSetHorizonConfiguration []automationconfig.ReplicaSetHorizons
 
+// AnalyticsMembersConfiguration defines the analytics members of a replica set. They are hidden, never become primary
+// and don't vote, and are tagged with nodeType: ANALYTICS. The analytics clients connect to them directly.
+type AnalyticsMembersConfiguration struct {
+	// Members is the number of analytics members
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Members int `json:"members,omitempty"`
+	// Resources are the resource requirements of the mongod container of the analytics members
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector schedules the analytics members on a dedicated pool of nodes
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations allow the analytics members on the tainted nodes of their pool
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// ShardedClusterConfiguration defines the components of a sharded cluster.
+type ShardedClusterConfiguration struct {
+	// ConfigServerMembers is the number of members of the config server replica set. It defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ConfigServerMembers int `json:"configServerMembers,omitempty"`
+	// Shards is the number of shards. Shards can be added but not removed.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Shards int `json:"shards,omitempty"`
+	// MembersPerShard is the number of members of the replica set of each shard.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MembersPerShard int `json:"membersPerShard,omitempty"`
+	// MongosReplicas is the number of mongos routers the clients connect to.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MongosReplicas int `json:"mongosReplicas,omitempty"`
+}
+
+// ReplicaSetSettings defines the settings of the replica set.
+type ReplicaSetSettings struct {
+	// ChainingAllowed allows the secondaries to replicate from other secondaries. It defaults to true.
+	// +optional
+	ChainingAllowed *bool `json:"chainingAllowed,omitempty"`
+	// ElectionTimeoutMillis is the time limit for detecting that the primary is unreachable.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ElectionTimeoutMillis int `json:"electionTimeoutMillis,omitempty"`
+	// HeartbeatTimeoutSecs is the time the members wait for a heartbeat before marking another member as unreachable.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	HeartbeatTimeoutSecs int `json:"heartbeatTimeoutSecs,omitempty"`
+	// GetLastErrorDefaults is the default write concern of the replica set. It is not supported from MongoDB 5.0.
+	// +optional
+	GetLastErrorDefaults *WriteConcern `json:"getLastErrorDefaults,omitempty"`
+	// DefaultReadConcern is the cluster-wide default read concern level. It requires MongoDB 4.4 or later.
+	// +kubebuilder:validation:Enum=local;available;majority
+	// +optional
+	DefaultReadConcern string `json:"defaultReadConcern,omitempty"`
+	// DefaultWriteConcern is the cluster-wide default write concern. It requires MongoDB 4.4 or later.
+	// +optional
+	DefaultWriteConcern *WriteConcern `json:"defaultWriteConcern,omitempty"`
+}
+
+// WriteConcern defines a MongoDB write concern.
+type WriteConcern struct {
+	// W is the number of members which must acknowledge the writes, "majority" or the name of a custom write concern.
+	// +kubebuilder:validation:XIntOrString
+	// +optional
+	W *intstr.IntOrString `json:"w,omitempty"`
+	// J requests the acknowledgement that the writes have been written to the journal.
+	// +optional
+	J *bool `json:"j,omitempty"`
+	// WTimeout is the time limit in milliseconds for the write concern.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	WTimeout int `json:"wtimeout,omitempty"`
+}
+
+// ConvertToAutomationConfigWriteConcern converts between a write concern defined by the crd and a write concern
+// that can be used in the automation config.
+func (w WriteConcern) ConvertToAutomationConfigWriteConcern() *automationconfig.WriteConcern {
+	ac := &automationconfig.WriteConcern{J: w.J, WTimeout: w.WTimeout}
+	if w.W != nil {
+		if w.W.Type == intstr.Int {
+			ac.W = w.W.IntValue()
+		} else {
+			ac.W = w.W.StrVal
+		}
+	}
+	return ac
+}
+
+// ConvertToAutomationConfigReplicaSetSettings returns the settings of the automation config replica set,
+// or nil if none is configured.
+func (s ReplicaSetSettings) ConvertToAutomationConfigReplicaSetSettings() *automationconfig.ReplicaSetSettings {
+	if s.ChainingAllowed == nil && s.ElectionTimeoutMillis == 0 && s.HeartbeatTimeoutSecs == 0 && s.GetLastErrorDefaults == nil {
+		return nil
+	}
+	settings := &automationconfig.ReplicaSetSettings{
+		ChainingAllowed:       s.ChainingAllowed,
+		ElectionTimeoutMillis: s.ElectionTimeoutMillis,
+		HeartbeatTimeoutSecs:  s.HeartbeatTimeoutSecs,
+	}
+	if s.GetLastErrorDefaults != nil {
+		settings.GetLastErrorDefaults = s.GetLastErrorDefaults.ConvertToAutomationConfigWriteConcern()
+	}
+	return settings
+}
+
+// ConvertToAutomationConfigDefaultRWConcern returns the cluster-wide default read and write concern of the
+// automation config, or nil if none is configured.
+func (s ReplicaSetSettings) ConvertToAutomationConfigDefaultRWConcern() *automationconfig.DefaultRWConcern {
+	if s.DefaultReadConcern == "" && s.DefaultWriteConcern == nil {
+		return nil
+	}
+	rwConcern := &automationconfig.DefaultRWConcern{}
+	if s.DefaultReadConcern != "" {
+		rwConcern.DefaultReadConcern = &automationconfig.ReadConcern{Level: s.DefaultReadConcern}
+	}
+	if s.DefaultWriteConcern != nil {
+		rwConcern.DefaultWriteConcern = s.DefaultWriteConcern.ConvertToAutomationConfigWriteConcern()
+	}
+	return rwConcern
+}
+
+// MemberConfiguration defines the replica set configuration of a member.
+type MemberConfiguration struct {
+	// Votes is the number of votes of the member in elections, either 0 or 1.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1
+	// +optional
+	Votes *int `json:"votes,omitempty"`
+	// Priority is the eligibility of the member to become primary, from 0 to 1000. It defaults to 0 for
+	// members which are hidden, delayed, don't vote or don't build indexes, and to 1 otherwise.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1000
+	// +optional
+	Priority *int `json:"priority,omitempty"`
+	// Hidden hides the member from the clients.
+	// +optional
+	Hidden bool `json:"hidden,omitempty"`
+	// SecondaryDelaySecs is the number of seconds the member lags behind the primary.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SecondaryDelaySecs int `json:"secondaryDelaySecs,omitempty"`
+	// BuildIndexes can be set to false on members which never become primary. It can't be changed
+	// once the member is part of the replica set.
+	// +optional
+	BuildIndexes *bool `json:"buildIndexes,omitempty"`
+	// Tags are used by read preferences and write concerns to target the member.
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// ConvertToAutomationConfigMemberOptions converts between a member configuration defined by the crd and
+// the member options of the automation config.
+func (c MemberConfiguration) ConvertToAutomationConfigMemberOptions() automationconfig.MemberOptions {
+	return automationconfig.MemberOptions{
+		Votes:              c.Votes,
+		Priority:           c.Priority,
+		Hidden:             c.Hidden,
+		SecondaryDelaySecs: c.SecondaryDelaySecs,
+		BuildIndexes:       c.BuildIndexes,
+		Tags:               c.Tags,
+	}
+}
+
+// ConvertMemberConfigToAutomationConfigMemberOptions converts the member configurations to member options
+// that can be used in the automation config.
+func ConvertMemberConfigToAutomationConfigMemberOptions(memberConfig []MemberConfiguration) []automationconfig.MemberOptions {
+	options := []automationconfig.MemberOptions{}
+	for _, config := range memberConfig {
+		options = append(options, config.ConvertToAutomationConfigMemberOptions())
+	}
+	return options
+}
+
 // CustomRole defines a custom MongoDB role.
 type CustomRole struct {
 	// The name of the role.
@@ -230,6 +566,19 @@ func (m *MongodConfiguration) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &m.Object)
 }
 
+// GetDBPort returns net.port, or 0 if it isn't set.
+func (m MongodConfiguration) GetDBPort() int {
+	switch port := objx.New(m.Object).Get("net.port").Data().(type) {
+	case int:
+		return port
+	case int64:
+		return int(port)
+	case float64:
+		return int(port)
+	}
+	return 0
+}
+
 func (m *MongodConfiguration) DeepCopy() *MongodConfiguration {
 	return &MongodConfiguration{
 		Object: runtime.DeepCopyJSON(m.Object),
@@ -253,6 +602,14 @@ type MongoDBUser struct {
 	// ScramCredentialsSecretName appended by string "scram-credentials" is the name of the secret object created by the mongoDB operator for storing SCRAM credentials
 	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$
 	ScramCredentialsSecretName string `json:"scramCredentialsSecretName"`
+
+	// Scram overrides the SCRAM mechanisms and iteration counts configured in spec.security.authentication.scram for this user.
+	// +optional
+	Scram ScramSettings `json:"scram,omitempty"`
+
+	// AuthenticationRestrictions restricts the IP addresses and CIDR ranges the user can connect from and to
+	// +optional
+	AuthenticationRestrictions []AuthenticationRestriction `json:"authenticationRestrictions,omitempty"`
 }
 
 func (m MongoDBUser) GetPasswordSecretKey() string {
@@ -295,6 +652,88 @@ type Security struct {
 	// User-specified custom MongoDB roles that should be configured in the deployment.
 	// +optional
 	Roles []CustomRole `json:"roles,omitempty"`
+	// LDAP configures authentication, and optionally authorization, against an LDAP server.
+	// +optional
+	LDAP LDAP `json:"ldap,omitempty"`
+	// AllowedNamespaces is the list of namespaces, other than the namespace of this resource,
+	// whose MongoDBCommunityUser and MongoDBCommunityRole resources can reference this resource.
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+}
+
+// LDAPTransportSecurity is the transport security used to connect to the LDAP servers.
+// +kubebuilder:validation:Enum=tls;none
+type LDAPTransportSecurity string
+
+const (
+	LDAPTransportSecurityTLS  LDAPTransportSecurity = "tls"
+	LDAPTransportSecurityNone LDAPTransportSecurity = "none"
+)
+
+// LDAP is the configuration used to proxy PLAIN authentication to an LDAP server.
+// LDAP authentication and authorization require MongoDB Enterprise.
+type LDAP struct {
+	// Enabled enables the PLAIN authentication mechanism, proxied to the LDAP servers.
+	// +optional
+	Enabled bool `json:"enabled"`
+
+	// Servers is the list of LDAP servers, in the host[:port] format.
+	// +optional
+	Servers []string `json:"servers,omitempty"`
+
+	// TransportSecurity is the transport security used to connect to the LDAP servers. Defaults to "tls".
+	// +optional
+	TransportSecurity LDAPTransportSecurity `json:"transportSecurity,omitempty"`
+
+	// BindQueryUser is the distinguished name mongod binds as when querying the LDAP servers.
+	// +optional
+	BindQueryUser string `json:"bindQueryUser,omitempty"`
+
+	// BindQueryPasswordSecretRef is a reference to the Secret containing the password of the BindQueryUser.
+	// The key defaults to "password".
+	// +optional
+	BindQueryPasswordSecretRef SecretKeyReference `json:"bindQueryPasswordSecretRef,omitempty"`
+
+	// UserToDNMapping maps the username provided to mongod to an LDAP distinguished name.
+	// It is a JSON array, see https://docs.mongodb.com/manual/reference/configuration-options/#security.ldap.userToDNMapping
+	// +optional
+	UserToDNMapping string `json:"userToDNMapping,omitempty"`
+
+	// AuthzQueryTemplate is the query template used to find the LDAP groups of a user.
+	// When set, LDAP authorization is enabled and the groups are mapped to roles in the admin database.
+	// +optional
+	AuthzQueryTemplate string `json:"authzQueryTemplate,omitempty"`
+
+	// TimeoutMS is the amount of time in milliseconds mongod waits for an LDAP server to respond.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutMS int `json:"timeoutMS,omitempty"`
+
+	// ValidateLDAPServerConfig makes mongod check that the LDAP servers are reachable on startup. Defaults to true.
+	// +optional
+	// +nullable
+	ValidateLDAPServerConfig *bool `json:"validateLDAPServerConfig,omitempty"`
+}
+
+// GetTransportSecurity returns the transport security used to connect to the LDAP servers.
+func (l LDAP) GetTransportSecurity() LDAPTransportSecurity {
+	if l.TransportSecurity == "" {
+		return LDAPTransportSecurityTLS
+	}
+	return l.TransportSecurity
+}
+
+// GetBindQueryPasswordSecretKey returns the key of the bind password in the BindQueryPasswordSecretRef.
+func (l LDAP) GetBindQueryPasswordSecretKey() string {
+	if l.BindQueryPasswordSecretRef.Key == "" {
+		return defaultPasswordKey
+	}
+	return l.BindQueryPasswordSecretRef.Key
+}
+
+// HasBindQueryPassword returns true if mongod binds to the LDAP servers with a password.
+func (l LDAP) HasBindQueryPassword() bool {
+	return l.BindQueryPasswordSecretRef.Name != ""
 }
 
 // TLS is the configuration used to set up TLS encryption
@@ -305,6 +744,13 @@ type TLS struct {
 	// +optional
 	Optional bool `json:"optional"`
 
+	// Mode pins the TLS mode of the deployment. Takes precedence over Optional.
+	// When the mode of an existing deployment changes, including when TLS is enabled or disabled,
+	// the operator walks through requireTLS, preferTLS, allowTLS and disabled one step at a time.
+	// +kubebuilder:validation:Enum=requireTLS;preferTLS;allowTLS
+	// +optional
+	Mode automationconfig.TLSMode `json:"mode,omitempty"`
+
 	// CertificateKeySecret is a reference to a Secret containing a private key and certificate to use for TLS.
 	// The key and cert are expected to be PEM encoded and available at "tls.key" and "tls.crt".
 	// This is the same format used for the standard "kubernetes.io/tls" Secret type, but no specific type is required.
@@ -315,6 +761,111 @@ type TLS struct {
 	// The certificate is expected to be available under the key "ca.crt"
 	// +optional
 	CaConfigMap LocalObjectReference `json:"caConfigMapRef"`
+
+	// CaCertificateSecret is a reference to a Secret containing the certificate for the CA which signed the server certificates.
+	// It can't be used together with CaConfigMap. It may reference the same Secret as CertificateKeySecret,
+	// which is the layout used by cert-manager.
+	// The certificate is expected to be available under the key "ca.crt"
+	// +optional
+	CaCertificateSecret LocalObjectReference `json:"caCertificateSecretRef,omitempty"`
+
+	// Keys overrides the keys under which the certificate, private key and CA certificate are stored.
+	// +optional
+	Keys TLSKeys `json:"keys,omitempty"`
+
+	// RequireClientCertificates configures the servers to reject clients which don't present a certificate signed by the CA.
+	// The agents use the server certificate as their client certificate.
+	// +optional
+	RequireClientCertificates bool `json:"requireClientCertificates,omitempty"`
+
+	// DisabledProtocols is the list of TLS protocols the servers won't accept connections with.
+	// +optional
+	DisabledProtocols []TLSProtocol `json:"disabledProtocols,omitempty"`
+
+	// AllowInvalidHostnames disables the validation of the hostnames in the certificates presented by other members.
+	// +optional
+	AllowInvalidHostnames bool `json:"allowInvalidHostnames,omitempty"`
+
+	// FIPSMode enables the FIPS mode of the TLS library used by mongod.
+	// +optional
+	FIPSMode bool `json:"fipsMode,omitempty"`
+
+	// CipherConfig is the OpenSSL cipher string used for TLS 1.2 and earlier connections, e.g. "HIGH:!EXPORT:!aNULL@STRENGTH".
+	// +optional
+	CipherConfig string `json:"cipherConfig,omitempty"`
+}
+
+// TLSKeys holds the keys under which the TLS materials are stored in their Secrets and ConfigMaps.
+type TLSKeys struct {
+	// Certificate is the key of the server certificate in the CertificateKeySecret. Defaults to "tls.crt".
+	// +optional
+	Certificate string `json:"certificate,omitempty"`
+
+	// PrivateKey is the key of the private key in the CertificateKeySecret. Defaults to "tls.key".
+	// +optional
+	PrivateKey string `json:"privateKey,omitempty"`
+
+	// CA is the key of the CA certificate in the CaConfigMap or CaCertificateSecret. Defaults to "ca.crt".
+	// +optional
+	CA string `json:"ca,omitempty"`
+}
+
+// CertificateKey returns the key of the server certificate in the CertificateKeySecret.
+func (t TLS) CertificateKey() string {
+	if t.Keys.Certificate == "" {
+		return "tls.crt"
+	}
+	return t.Keys.Certificate
+}
+
+// PrivateKeyKey returns the key of the private key in the CertificateKeySecret.
+func (t TLS) PrivateKeyKey() string {
+	if t.Keys.PrivateKey == "" {
+		return "tls.key"
+	}
+	return t.Keys.PrivateKey
+}
+
+// CAKey returns the key of the CA certificate in the CaConfigMap or CaCertificateSecret.
+func (t TLS) CAKey() string {
+	if t.Keys.CA == "" {
+		return "ca.crt"
+	}
+	return t.Keys.CA
+}
+
+// HasCASecret returns true if the CA certificate is read from a Secret instead of a ConfigMap.
+func (t TLS) HasCASecret() bool {
+	return t.CaCertificateSecret.Name != ""
+}
+
+// TLSProtocol is a TLS protocol version which can be disabled.
+// +kubebuilder:validation:Enum=TLS1_0;TLS1_1;TLS1_2;TLS1_3
+type TLSProtocol string
+
+const (
+	TLSProtocol10 TLSProtocol = "TLS1_0"
+	TLSProtocol11 TLSProtocol = "TLS1_1"
+	TLSProtocol12 TLSProtocol = "TLS1_2"
+	TLSProtocol13 TLSProtocol = "TLS1_3"
+)
+
+// TLSProtocols is the list of all the TLS protocols which can be disabled.
+var TLSProtocols = []TLSProtocol{TLSProtocol10, TLSProtocol11, TLSProtocol12, TLSProtocol13}
+
+// GetMode returns the desired TLS mode of the deployment.
+func (t TLS) GetMode() automationconfig.TLSMode {
+	if !t.Enabled {
+		return automationconfig.TLSModeDisabled
+	}
+	if t.Mode != "" {
+		return t.Mode
+	}
+	if t.Optional {
+		// TLSModePreferred requires server-server connections to use TLS but makes it optional for clients.
+		return automationconfig.TLSModePreferred
+	}
+	return automationconfig.TLSModeRequired
 }
 
 // LocalObjectReference is a reference to another Kubernetes object by name.
@@ -338,11 +889,127 @@ type Authentication struct {
 	// +kubebuilder:default:=true
 	// +nullable
 	IgnoreUnknownUsers *bool `json:"ignoreUnknownUsers"`
+
+	// InternalCluster configures how the members of the deployment authenticate to each other.
+	// +optional
+	InternalCluster InternalClusterAuthentication `json:"internalCluster,omitempty"`
+
+	// Scram configures the SCRAM mechanisms enabled in the deployment, and the credentials generated for the users.
+	// +optional
+	Scram ScramSettings `json:"scram,omitempty"`
+
+	// KeyRotation configures the rotation of the keyfile and of the password of the MongoDB Agent.
+	// +optional
+	KeyRotation KeyRotation `json:"keyRotation,omitempty"`
+}
+
+// KeyRotation configures when the keyfile the members use to authenticate to each other, and the password
+// of the MongoDB Agent, are replaced by newly generated ones.
+type KeyRotation struct {
+	// RotationID triggers a rotation whenever it is changed to a new value, e.g. the current date.
+	// +optional
+	RotationID string `json:"rotationId,omitempty"`
+
+	// Interval triggers a rotation once this duration has passed since the last rotation, e.g. "2160h".
+	// Must be at least 1h.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// ScramMechanism is a SCRAM authentication mechanism.
+// +kubebuilder:validation:Enum=SCRAM-SHA-1;SCRAM-SHA-256
+type ScramMechanism string
+
+const (
+	ScramSha1   ScramMechanism = "SCRAM-SHA-1"
+	ScramSha256 ScramMechanism = "SCRAM-SHA-256"
+)
+
+// ScramSettings configures the SCRAM credentials generated for users.
+type ScramSettings struct {
+	// Mechanisms is the list of SCRAM mechanisms credentials are generated for.
+	// When set for the deployment, only these mechanisms are enabled, otherwise SCRAM-SHA-256 is enabled
+	// and credentials are generated for both mechanisms.
+	// +optional
+	Mechanisms []ScramMechanism `json:"mechanisms,omitempty"`
+
+	// Sha1Iterations is the iteration count of the SCRAM-SHA-1 credentials. Defaults to 10000.
+	// +kubebuilder:validation:Minimum=5000
+	// +optional
+	Sha1Iterations int `json:"sha1Iterations,omitempty"`
+
+	// Sha256Iterations is the iteration count of the SCRAM-SHA-256 credentials. Defaults to 15000.
+	// +kubebuilder:validation:Minimum=5000
+	// +optional
+	Sha256Iterations int `json:"sha256Iterations,omitempty"`
+}
+
+// toAutomationConfigMechanisms converts the mechanisms to the names used in the automation config.
+func toAutomationConfigMechanisms(mechanisms []ScramMechanism) []string {
+	var converted []string
+	for _, mechanism := range mechanisms {
+		switch mechanism {
+		case ScramSha1:
+			converted = append(converted, scram.Sha1)
+		case ScramSha256:
+			converted = append(converted, scram.Sha256)
+		}
+	}
+	return converted
+}
+
+// GetDeploymentScramMechanisms returns the SCRAM mechanisms enabled in the deployment.
+func (a Authentication) GetDeploymentScramMechanisms() []ScramMechanism {
+	if len(a.Scram.Mechanisms) == 0 {
+		return []ScramMechanism{ScramSha256}
+	}
+	return a.Scram.Mechanisms
 }
 
 // +kubebuilder:validation:Enum=SCRAM
 type AuthMode string
 
+// ClusterAuthMode is the authentication mode used for intra-cluster authentication.
+// The intermediate modes sendKeyFile and sendX509 are used when transitioning between
+// keyFile and x509 without downtime.
+// +kubebuilder:validation:Enum=keyFile;sendKeyFile;sendX509;x509
+type ClusterAuthMode string
+
+const (
+	ClusterAuthModeKeyFile     ClusterAuthMode = "keyFile"
+	ClusterAuthModeSendKeyFile ClusterAuthMode = "sendKeyFile"
+	ClusterAuthModeSendX509    ClusterAuthMode = "sendX509"
+	ClusterAuthModeX509        ClusterAuthMode = "x509"
+)
+
+// ClusterAuthModeTransition is the order in which the cluster authentication mode is changed.
+var ClusterAuthModeTransition = []ClusterAuthMode{
+	ClusterAuthModeKeyFile,
+	ClusterAuthModeSendKeyFile,
+	ClusterAuthModeSendX509,
+	ClusterAuthModeX509,
+}
+
+// UsesX509 returns true if members need a cluster certificate for the given mode.
+func (c ClusterAuthMode) UsesX509() bool {
+	return c == ClusterAuthModeSendKeyFile || c == ClusterAuthModeSendX509 || c == ClusterAuthModeX509
+}
+
+// InternalClusterAuthentication configures the authentication between members of the deployment.
+type InternalClusterAuthentication struct {
+	// Mode is the desired cluster authentication mode. Defaults to "keyFile".
+	// When changing the mode of an existing deployment, the operator walks through
+	// keyFile -> sendKeyFile -> sendX509 -> x509 (or the reverse), one step at a time.
+	// +optional
+	Mode ClusterAuthMode `json:"mode,omitempty"`
+
+	// CertificateKeySecret is a reference to a Secret containing the private key and certificate
+	// each member uses to authenticate to the other members. The key and cert are expected to be
+	// PEM encoded and available at "tls.key" and "tls.crt".
+	// +optional
+	CertificateKeySecret LocalObjectReference `json:"certificateKeySecretRef,omitempty"`
+}
+
 // MongoDBCommunityStatus defines the observed state of MongoDB
 type MongoDBCommunityStatus struct {
 	MongoURI string `json:"mongoUri"`
@@ -351,7 +1018,87 @@ type MongoDBCommunityStatus struct {
 	CurrentStatefulSetReplicas int `json:"currentStatefulSetReplicas"`
 	CurrentMongoDBMembers      int `json:"currentMongoDBMembers"`
 
+	// +optional
+	CurrentStatefulSetArbitersReplicas int `json:"currentStatefulSetArbitersReplicas,omitempty"`
+	// +optional
+	CurrentMongoDBArbiters int `json:"currentMongoDBArbiters,omitempty"`
+
 	Message string `json:"message,omitempty"`
+
+	// TLS tracks the rollout of the CA used by the members.
+	// +optional
+	TLS TLSStatus `json:"tls,omitempty"`
+
+	// KeyRotation tracks the rotation of the keyfile and of the agent password.
+	// +optional
+	KeyRotation KeyRotationStatus `json:"keyRotation,omitempty"`
+
+	// ShardedCluster reports the StatefulSets of the components of a sharded cluster.
+	// +optional
+	ShardedCluster []ComponentStatus `json:"shardedCluster,omitempty"`
+}
+
+// ComponentStatus reports the readiness of the StatefulSet of a component of a sharded cluster.
+type ComponentStatus struct {
+	Name          string `json:"name"`
+	Replicas      int    `json:"replicas"`
+	ReadyReplicas int    `json:"readyReplicas"`
+}
+
+// IsReady returns true if all the replicas of the component are ready.
+func (c ComponentStatus) IsReady() bool {
+	return c.ReadyReplicas == c.Replicas
+}
+
+// KeyRotationStage is a stage of the rotation of the keyfile and of the agent password.
+type KeyRotationStage string
+
+const (
+	// KeyRotationStageKeyfileBundle means the members are being updated with a keyfile containing both the old and the new key,
+	// so that they accept both keys.
+	KeyRotationStageKeyfileBundle KeyRotationStage = "KeyfileBundleRollout"
+	// KeyRotationStageNewCredentials means the members are being updated with a keyfile which only contains the new key,
+	// and the agents with the new password.
+	KeyRotationStageNewCredentials KeyRotationStage = "NewCredentialsRollout"
+)
+
+// KeyRotationStatus holds the state of the rotation of the keyfile and of the agent password.
+type KeyRotationStatus struct {
+	// Stage is the current stage of the rotation, empty if no rotation is in progress.
+	// +optional
+	Stage KeyRotationStage `json:"stage,omitempty"`
+
+	// RotationID is the rotationId of the last completed rotation.
+	// +optional
+	RotationID string `json:"rotationId,omitempty"`
+
+	// LastRotationTime is the time the last rotation completed.
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+}
+
+// CARotationStage is a stage of the rotation of the CA used by the members.
+type CARotationStage string
+
+const (
+	// CARotationStageBundleRollout means the members are being restarted with a CA bundle containing both the old and the new CA.
+	CARotationStageBundleRollout CARotationStage = "CABundleRollout"
+	// CARotationStageServerCertificate means the members trust both CAs, and the server certificate can be replaced
+	// by one signed by the new CA. Once done, the old CA can be removed from the bundle.
+	CARotationStageServerCertificate CARotationStage = "ServerCertificateRotation"
+	// CARotationStageOldCARemoval means the members are being restarted with a bundle which no longer contains the old CA.
+	CARotationStageOldCARemoval CARotationStage = "OldCARemoval"
+)
+
+// TLSStatus holds the state of the CA used by the members.
+type TLSStatus struct {
+	// CAHash is the hash of the CA the members were last rolled out with.
+	// +optional
+	CAHash string `json:"caHash,omitempty"`
+
+	// CARotationStage is the current stage of the CA rotation, empty if no rotation is in progress.
+	// +optional
+	CARotationStage CARotationStage `json:"caRotationStage,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -387,12 +1134,20 @@ func (m MongoDBCommunity) GetScramOptions() scram.Options {
 		ignoreUnknownUsers = *m.Spec.Security.Authentication.IgnoreUnknownUsers
 	}
 
+	// the agent uses SCRAM-SHA-256 unless it has been disabled for the deployment
+	deploymentMechanisms := toAutomationConfigMechanisms(m.Spec.Security.Authentication.Scram.Mechanisms)
+	autoAuthMechanism := scram.Sha256
+	if len(deploymentMechanisms) > 0 && !contains.String(deploymentMechanisms, scram.Sha256) {
+		autoAuthMechanism = scram.Sha1
+	}
+
 	return scram.Options{
-		AuthoritativeSet:   !ignoreUnknownUsers,
-		KeyFile:            scram.AutomationAgentKeyFilePathInContainer,
-		AutoAuthMechanisms: []string{scram.Sha256},
-		AgentName:          scram.AgentName,
-		AutoAuthMechanism:  scram.Sha256,
+		AuthoritativeSet:         !ignoreUnknownUsers,
+		KeyFile:                  scram.AutomationAgentKeyFilePathInContainer,
+		AutoAuthMechanisms:       []string{autoAuthMechanism},
+		AgentName:                scram.AgentName,
+		AutoAuthMechanism:        autoAuthMechanism,
+		DeploymentAuthMechanisms: deploymentMechanisms,
 	}
 }
 
@@ -401,25 +1156,69 @@ func (m MongoDBCommunity) GetScramOptions() scram.Options {
 func (m MongoDBCommunity) GetScramUsers() []scram.User {
 	users := make([]scram.User, len(m.Spec.Users))
 	for i, u := range m.Spec.Users {
-		roles := make([]scram.Role, len(u.Roles))
-		for j, r := range u.Roles {
-			roles[j] = scram.Role{
-				Name:     r.Name,
-				Database: r.DB,
-			}
-		}
-		users[i] = scram.User{
-			Username:                   u.Name,
-			Database:                   u.DB,
-			Roles:                      roles,
-			PasswordSecretKey:          u.GetPasswordSecretKey(),
-			PasswordSecretName:         u.PasswordSecretRef.Name,
-			ScramCredentialsSecretName: u.GetScramCredentialsSecretName(),
-		}
+		users[i] = m.toScramUser(u)
 	}
 	return users
 }
 
+// GetCommunityUserScramUser converts a user declared in a MongoDBCommunityUser resource into a user
+// that can be used to configure scram authentication.
+func (m MongoDBCommunity) GetCommunityUserScramUser(u MongoDBCommunityUser) scram.User {
+	user := m.toScramUser(u.Spec.MongoDBUser)
+	user.PasswordSecretNamespace = u.Namespace
+	return user
+}
+
+// toScramUser converts the user into a scram user, using the deployment SCRAM settings
+// for those not set on the user.
+func (m MongoDBCommunity) toScramUser(u MongoDBUser) scram.User {
+	deploymentScram := m.Spec.Security.Authentication.Scram
+	userScram := u.Scram
+	if len(userScram.Mechanisms) == 0 {
+		userScram.Mechanisms = deploymentScram.Mechanisms
+	}
+	if userScram.Sha1Iterations == 0 {
+		userScram.Sha1Iterations = deploymentScram.Sha1Iterations
+	}
+	if userScram.Sha256Iterations == 0 {
+		userScram.Sha256Iterations = deploymentScram.Sha256Iterations
+	}
+
+	roles := make([]scram.Role, len(u.Roles))
+	for j, r := range u.Roles {
+		roles[j] = scram.Role{
+			Name:     r.Name,
+			Database: r.DB,
+		}
+	}
+
+	var restrictions []automationconfig.AuthenticationRestriction
+	for _, restriction := range u.AuthenticationRestrictions {
+		restrictions = append(restrictions, automationconfig.AuthenticationRestriction{
+			ClientSource:  restriction.ClientSource,
+			ServerAddress: restriction.ServerAddress,
+		})
+	}
+	return scram.User{
+		Username:                   u.Name,
+		Database:                   u.DB,
+		Roles:                      roles,
+		PasswordSecretKey:          u.GetPasswordSecretKey(),
+		PasswordSecretName:         u.PasswordSecretRef.Name,
+		ScramCredentialsSecretName: u.GetScramCredentialsSecretName(),
+		Mechanisms:                 toAutomationConfigMechanisms(userScram.Mechanisms),
+		ScramSha1Iterations:        userScram.Sha1Iterations,
+		ScramSha256Iterations:      userScram.Sha256Iterations,
+		AuthenticationRestrictions: restrictions,
+	}
+}
+
+// IsNamespaceAllowed returns true if MongoDBCommunityUser and MongoDBCommunityRole resources in the given namespace
+// can reference this resource.
+func (m MongoDBCommunity) IsNamespaceAllowed(namespace string) bool {
+	return namespace == m.Namespace || contains.String(m.Spec.Security.AllowedNamespaces, namespace)
+}
+
 func (m MongoDBCommunity) AutomationConfigMembersThisReconciliation() int {
 	// determine the correct number of automation config replica set members
 	// based on our desired number, and our current number
@@ -429,25 +1228,174 @@ func (m MongoDBCommunity) AutomationConfigMembersThisReconciliation() int {
 	})
 }
 
+// AutomationConfigArbitersThisReconciliation returns the number of arbiters of the replica set this reconciliation.
+func (m MongoDBCommunity) AutomationConfigArbitersThisReconciliation() int {
+	return arbitersThisReconciliation(m.Spec.Members, m.Status.CurrentMongoDBMembers, m.Spec.Arbiters, m.Status.CurrentMongoDBArbiters)
+}
+
+// StatefulSetArbitersThisReconciliation returns the number of replicas of the arbiters StatefulSet this reconciliation.
+func (m MongoDBCommunity) StatefulSetArbitersThisReconciliation() int {
+	return arbitersThisReconciliation(m.Spec.Members, m.Status.CurrentStatefulSetReplicas, m.Spec.Arbiters, m.Status.CurrentStatefulSetArbitersReplicas)
+}
+
+// arbitersThisReconciliation scales the arbiters one at a time, like the data-bearing members, as a single voting member
+// can be added or removed at a time. The arbiters are only scaled once the data-bearing members are done scaling, and
+// are only added all at once when the replica set is created.
+func arbitersThisReconciliation(desiredMembers, currentMembers, desiredArbiters, currentArbiters int) int {
+	if currentMembers == 0 {
+		return desiredArbiters
+	}
+	if currentMembers != desiredMembers {
+		return currentArbiters
+	}
+	if currentArbiters == 0 && desiredArbiters > 0 {
+		return 1
+	}
+	return scale.ReplicasThisReconciliation(automationConfigReplicasScaler{
+		desired: desiredArbiters,
+		current: currentArbiters,
+	})
+}
+
+// IsStillScaling returns true if the data-bearing members or the arbiters haven't reached the desired number yet,
+// or, for a sharded cluster, the members of the config servers or of a shard.
+func (m *MongoDBCommunity) IsStillScaling() bool {
+	if m.IsShardedCluster() {
+		return m.isShardedClusterStillScaling()
+	}
+	return scale.IsStillScaling(m) || m.AutomationConfigArbitersThisReconciliation() != m.Spec.Arbiters
+}
+
+// ArbitersNamespacedName returns the NamespacedName of the StatefulSet of the arbiters.
+func (m MongoDBCommunity) ArbitersNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Name + "-arb", Namespace: m.Namespace}
+}
+
+// AnalyticsNamespacedName returns the NamespacedName of the StatefulSet of the analytics members.
+func (m MongoDBCommunity) AnalyticsNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Name + "-analytics", Namespace: m.Namespace}
+}
+
 // MongoURI returns a mongo uri which can be used to connect to this deployment
 func (m MongoDBCommunity) MongoURI() string {
-	members := make([]string, m.Spec.Members)
-	clusterDomain := "svc.cluster.local" // TODO: make this configurable
-	for i := 0; i < m.Spec.Members; i++ {
-		members[i] = fmt.Sprintf("%s-%d.%s.%s.%s:%d", m.Name, i, m.ServiceName(), m.Namespace, clusterDomain, 27017)
+	return fmt.Sprintf("mongodb://%s", strings.Join(m.Hosts(), ","))
+}
+
+// GetPort returns the port the members listen on.
+func (m MongoDBCommunity) GetPort() int {
+	if m.Spec.Port != 0 {
+		return m.Spec.Port
 	}
-	return fmt.Sprintf("mongodb://%s", strings.Join(members, ","))
+	if port := m.Spec.AdditionalMongodConfig.GetDBPort(); port != 0 {
+		return port
+	}
+	return DefaultPort
 }
 
+// Hosts returns the hosts the clients connect to: the members of a replica set, or the mongos routers
+// of a sharded cluster.
 func (m MongoDBCommunity) Hosts() []string {
-	hosts := make([]string, m.Spec.Members)
+	return m.HostsWithPort(m.GetPort())
+}
+
+// HostsWithPort returns the hosts the clients connect to, with the given port rather than the one of the spec.
+func (m MongoDBCommunity) HostsWithPort(port int) []string {
+	name, count := m.Name, m.Spec.Members
+	if m.IsShardedCluster() {
+		name, count = m.MongosNamespacedName().Name, m.Spec.ShardedCluster.MongosReplicas
+	}
+	hosts := make([]string, count)
 	clusterDomain := "svc.cluster.local" // TODO: make this configurable
-	for i := 0; i < m.Spec.Members; i++ {
-		hosts[i] = fmt.Sprintf("%s-%d.%s.%s.%s:%d", m.Name, i, m.ServiceName(), m.Namespace, clusterDomain, 27017)
+	for i := 0; i < count; i++ {
+		hosts[i] = fmt.Sprintf("%s-%d.%s.%s.%s:%d", name, i, m.ServiceName(), m.Namespace, clusterDomain, port)
 	}
 	return hosts
 }
 
+// IsShardedCluster returns true if the resource deploys a sharded cluster rather than a replica set.
+func (m MongoDBCommunity) IsShardedCluster() bool {
+	return m.Spec.Type == ShardedCluster
+}
+
+// IsStandalone returns true if the resource deploys a single mongod without replication.
+func (m MongoDBCommunity) IsStandalone() bool {
+	return m.Spec.Type == Standalone
+}
+
+// ConfigServerMembers returns the number of members of the config server replica set.
+func (m MongoDBCommunity) ConfigServerMembers() int {
+	if m.Spec.ShardedCluster.ConfigServerMembers == 0 {
+		return defaultConfigServerMembers
+	}
+	return m.Spec.ShardedCluster.ConfigServerMembers
+}
+
+// ConfigServerNamespacedName returns the NamespacedName of the StatefulSet of the config servers.
+func (m MongoDBCommunity) ConfigServerNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Name + "-configsrv", Namespace: m.Namespace}
+}
+
+// ShardNamespacedName returns the NamespacedName of the StatefulSet of the shard with the given index.
+func (m MongoDBCommunity) ShardNamespacedName(shard int) types.NamespacedName {
+	return types.NamespacedName{Name: fmt.Sprintf("%s-%d", m.Name, shard), Namespace: m.Namespace}
+}
+
+// MongosNamespacedName returns the NamespacedName of the StatefulSet of the mongos routers.
+func (m MongoDBCommunity) MongosNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Name + "-mongos", Namespace: m.Namespace}
+}
+
+// ConfigServerMembersThisReconciliation returns the number of members of the config server replica set this reconciliation.
+func (m MongoDBCommunity) ConfigServerMembersThisReconciliation() int {
+	return m.shardedClusterMembersThisReconciliation(m.ConfigServerNamespacedName().Name, m.ConfigServerMembers())
+}
+
+// ShardMembersThisReconciliation returns the number of members of the shard with the given index this reconciliation.
+func (m MongoDBCommunity) ShardMembersThisReconciliation(shard int) int {
+	return m.shardedClusterMembersThisReconciliation(m.ShardNamespacedName(shard).Name, m.Spec.ShardedCluster.MembersPerShard)
+}
+
+// shardedClusterMembersThisReconciliation scales the members of a replica set of the sharded cluster one at a time,
+// starting from the replicas reported in status.shardedCluster. A new replica set is created with all of its members.
+func (m MongoDBCommunity) shardedClusterMembersThisReconciliation(name string, desired int) int {
+	current := 0
+	for _, component := range m.Status.ShardedCluster {
+		if component.Name == name {
+			current = component.Replicas
+		}
+	}
+	return scale.ReplicasThisReconciliation(automationConfigReplicasScaler{
+		desired: desired,
+		current: current,
+	})
+}
+
+// isShardedClusterStillScaling returns true if the config servers or a shard haven't reached the desired number of members yet.
+func (m MongoDBCommunity) isShardedClusterStillScaling() bool {
+	if m.ConfigServerMembersThisReconciliation() != m.ConfigServerMembers() {
+		return true
+	}
+	for i := 0; i < m.Spec.ShardedCluster.Shards; i++ {
+		if m.ShardMembersThisReconciliation(i) != m.Spec.ShardedCluster.MembersPerShard {
+			return true
+		}
+	}
+	return false
+}
+
+// AutomationConfigShardedCluster returns the components of the sharded cluster, named after their StatefulSets.
+func (m MongoDBCommunity) AutomationConfigShardedCluster() automationconfig.ShardedClusterSpec {
+	shards := make([]automationconfig.ReplicaSetSpec, m.Spec.ShardedCluster.Shards)
+	for i := range shards {
+		shards[i] = automationconfig.ReplicaSetSpec{Name: m.ShardNamespacedName(i).Name, Members: m.ShardMembersThisReconciliation(i)}
+	}
+	return automationconfig.ShardedClusterSpec{
+		ConfigServer: automationconfig.ReplicaSetSpec{Name: m.ConfigServerNamespacedName().Name, Members: m.ConfigServerMembersThisReconciliation()},
+		Shards:       shards,
+		Mongos:       automationconfig.MongosSpec{Name: m.MongosNamespacedName().Name, Replicas: m.Spec.ShardedCluster.MongosReplicas},
+	}
+}
+
 // ServiceName returns the name of the Service that should be created for
 // this resource
 func (m MongoDBCommunity) ServiceName() string {
@@ -464,6 +1412,17 @@ func (m MongoDBCommunity) TLSConfigMapNamespacedName() types.NamespacedName {
 	return types.NamespacedName{Name: m.Spec.Security.TLS.CaConfigMap.Name, Namespace: m.Namespace}
 }
 
+// TLSCASecretNamespacedName will get the namespaced name of the Secret containing the CA certificate
+// As the Secret will be mounted to our pods, it has to be in the same namespace as the MongoDB resource
+func (m MongoDBCommunity) TLSCASecretNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Spec.Security.TLS.CaCertificateSecret.Name, Namespace: m.Namespace}
+}
+
+// LDAPBindQueryPasswordSecretNamespacedName will get the namespaced name of the Secret containing the LDAP bind password
+func (m MongoDBCommunity) LDAPBindQueryPasswordSecretNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Spec.Security.LDAP.BindQueryPasswordSecretRef.Name, Namespace: m.Namespace}
+}
+
 // TLSSecretNamespacedName will get the namespaced name of the Secret containing the server certificate and key
 func (m MongoDBCommunity) TLSSecretNamespacedName() types.NamespacedName {
 	return types.NamespacedName{Name: m.Spec.Security.TLS.CertificateKeySecret.Name, Namespace: m.Namespace}
@@ -475,6 +1434,31 @@ func (m MongoDBCommunity) TLSOperatorSecretNamespacedName() types.NamespacedName
 	return types.NamespacedName{Name: m.Name + "-server-certificate-key", Namespace: m.Namespace}
 }
 
+// GetClusterAuthMode returns the desired intra-cluster authentication mode, defaulting to keyFile.
+func (m MongoDBCommunity) GetClusterAuthMode() ClusterAuthMode {
+	if m.Spec.Security.Authentication.InternalCluster.Mode == "" {
+		return ClusterAuthModeKeyFile
+	}
+	return m.Spec.Security.Authentication.InternalCluster.Mode
+}
+
+// HasClusterCertificate returns true if a member certificate for x509 cluster authentication has been configured.
+func (m MongoDBCommunity) HasClusterCertificate() bool {
+	return m.Spec.Security.Authentication.InternalCluster.CertificateKeySecret.Name != ""
+}
+
+// ClusterCertificateSecretNamespacedName will get the namespaced name of the Secret containing the
+// member certificate and key used for x509 cluster authentication.
+func (m MongoDBCommunity) ClusterCertificateSecretNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Spec.Security.Authentication.InternalCluster.CertificateKeySecret.Name, Namespace: m.Namespace}
+}
+
+// ClusterOperatorSecretNamespacedName will get the namespaced name of the Secret created by the operator
+// containing the combined member certificate and key.
+func (m MongoDBCommunity) ClusterOperatorSecretNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Name + "-cluster-certificate-key", Namespace: m.Namespace}
+}
+
 func (m MongoDBCommunity) NamespacedName() types.NamespacedName {
 	return types.NamespacedName{Name: m.Name, Namespace: m.Namespace}
 }
@@ -0,0 +1,80 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// MongoDBCommunityUserSpec defines the desired state of a user created in a MongoDBCommunity resource
+type MongoDBCommunityUserSpec struct {
+	// MongoDBResourceRef is a reference to the MongoDBCommunity resource the user is created in
+	MongoDBResourceRef MongoDBResourceReference `json:"mongodbResourceRef"`
+
+	// The user is configured in the same way as the users in spec.users of the MongoDBCommunity resource.
+	// The password secret is read from the namespace of this resource.
+	MongoDBUser `json:",inline"`
+}
+
+// MongoDBResourceReference is a reference to a MongoDBCommunity resource
+type MongoDBResourceReference struct {
+	// Name is the name of the MongoDBCommunity resource
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the MongoDBCommunity resource. Defaults to the namespace of this resource.
+	// The MongoDBCommunity resource must list this namespace in spec.security.allowedNamespaces
+	// when it is in a different namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// MongoDBCommunityUserStatus defines the observed state of MongoDBCommunityUser
+type MongoDBCommunityUserStatus struct {
+	Phase   Phase  `json:"phase"`
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// MongoDBCommunityUser is the Schema for a user created in a MongoDBCommunity resource
+// +kubebuilder:resource:path=mongodbcommunityusers,scope=Namespaced,shortName=mdbcu,singular=mongodbcommunityuser
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Current state of the user"
+// +kubebuilder:printcolumn:name="MongoDBCommunity",type="string",JSONPath=".spec.mongodbResourceRef.name",description="The MongoDBCommunity resource the user is created in"
+type MongoDBCommunityUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MongoDBCommunityUserSpec   `json:"spec,omitempty"`
+	Status MongoDBCommunityUserStatus `json:"status,omitempty"`
+}
+
+// MongoDBResourceNamespacedName returns the namespaced name of the MongoDBCommunity resource the user is created in
+func (u MongoDBCommunityUser) MongoDBResourceNamespacedName() types.NamespacedName {
+	namespace := u.Spec.MongoDBResourceRef.Namespace
+	if namespace == "" {
+		namespace = u.Namespace
+	}
+	return types.NamespacedName{Name: u.Spec.MongoDBResourceRef.Name, Namespace: namespace}
+}
+
+// PasswordSecretNamespacedName returns the namespaced name of the secret containing the user's password
+func (u MongoDBCommunityUser) PasswordSecretNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: u.Spec.PasswordSecretRef.Name, Namespace: u.Namespace}
+}
+
+func (u MongoDBCommunityUser) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: u.Name, Namespace: u.Namespace}
+}
+
+// +kubebuilder:object:root=true
+
+// MongoDBCommunityUserList contains a list of MongoDBCommunityUser
+type MongoDBCommunityUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MongoDBCommunityUser `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MongoDBCommunityUser{}, &MongoDBCommunityUserList{})
+}
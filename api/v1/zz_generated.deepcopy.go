@@ -22,9 +22,42 @@ package v1
 
 import (
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AnalyticsMembersConfiguration) DeepCopyInto(out *AnalyticsMembersConfiguration) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnalyticsMembersConfiguration.
+func (in *AnalyticsMembersConfiguration) DeepCopy() *AnalyticsMembersConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(AnalyticsMembersConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Authentication) DeepCopyInto(out *Authentication) {
 	*out = *in
@@ -38,6 +71,9 @@ func (in *Authentication) DeepCopyInto(out *Authentication) {
 		*out = new(bool)
 		**out = **in
 	}
+	out.InternalCluster = in.InternalCluster
+	in.Scram.DeepCopyInto(&out.Scram)
+	in.KeyRotation.DeepCopyInto(&out.KeyRotation)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Authentication.
@@ -75,6 +111,37 @@ func (in *AuthenticationRestriction) DeepCopy() *AuthenticationRestriction {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Backup) DeepCopyInto(out *Backup) {
+	*out = *in
+	in.User.DeepCopyInto(&out.User)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Backup.
+func (in *Backup) DeepCopy() *Backup {
+	if in == nil {
+		return nil
+	}
+	out := new(Backup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentStatus) DeepCopyInto(out *ComponentStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentStatus.
+func (in *ComponentStatus) DeepCopy() *ComponentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CustomRole) DeepCopyInto(out *CustomRole) {
 	*out = *in
@@ -109,6 +176,130 @@ func (in *CustomRole) DeepCopy() *CustomRole {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalAccess) DeepCopyInto(out *ExternalAccess) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalAccess.
+func (in *ExternalAccess) DeepCopy() *ExternalAccess {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalAccess)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InternalClusterAuthentication) DeepCopyInto(out *InternalClusterAuthentication) {
+	*out = *in
+	out.CertificateKeySecret = in.CertificateKeySecret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InternalClusterAuthentication.
+func (in *InternalClusterAuthentication) DeepCopy() *InternalClusterAuthentication {
+	if in == nil {
+		return nil
+	}
+	out := new(InternalClusterAuthentication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InternalUser) DeepCopyInto(out *InternalUser) {
+	*out = *in
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]Role, len(*in))
+		copy(*out, *in)
+	}
+	out.PasswordSecretRef = in.PasswordSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InternalUser.
+func (in *InternalUser) DeepCopy() *InternalUser {
+	if in == nil {
+		return nil
+	}
+	out := new(InternalUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyRotation) DeepCopyInto(out *KeyRotation) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyRotation.
+func (in *KeyRotation) DeepCopy() *KeyRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyRotationStatus) DeepCopyInto(out *KeyRotationStatus) {
+	*out = *in
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyRotationStatus.
+func (in *KeyRotationStatus) DeepCopy() *KeyRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LDAP) DeepCopyInto(out *LDAP) {
+	*out = *in
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.BindQueryPasswordSecretRef = in.BindQueryPasswordSecretRef
+	if in.ValidateLDAPServerConfig != nil {
+		in, out := &in.ValidateLDAPServerConfig, &out.ValidateLDAPServerConfig
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAP.
+func (in *LDAP) DeepCopy() *LDAP {
+	if in == nil {
+		return nil
+	}
+	out := new(LDAP)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LocalObjectReference) DeepCopyInto(out *LocalObjectReference) {
 	*out = *in
@@ -124,13 +315,66 @@ func (in *LocalObjectReference) DeepCopy() *LocalObjectReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemberConfiguration) DeepCopyInto(out *MemberConfiguration) {
+	*out = *in
+	if in.Votes != nil {
+		in, out := &in.Votes, &out.Votes
+		*out = new(int)
+		**out = **in
+	}
+	if in.Priority != nil {
+		in, out := &in.Priority, &out.Priority
+		*out = new(int)
+		**out = **in
+	}
+	if in.BuildIndexes != nil {
+		in, out := &in.BuildIndexes, &out.BuildIndexes
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemberConfiguration.
+func (in *MemberConfiguration) DeepCopy() *MemberConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(MemberConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Metrics) DeepCopyInto(out *Metrics) {
+	*out = *in
+	in.User.DeepCopyInto(&out.User)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Metrics.
+func (in *Metrics) DeepCopy() *Metrics {
+	if in == nil {
+		return nil
+	}
+	out := new(Metrics)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MongoDBCommunity) DeepCopyInto(out *MongoDBCommunity) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunity.
@@ -183,9 +427,102 @@ func (in *MongoDBCommunityList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDBCommunityRole) DeepCopyInto(out *MongoDBCommunityRole) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunityRole.
+func (in *MongoDBCommunityRole) DeepCopy() *MongoDBCommunityRole {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDBCommunityRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MongoDBCommunityRole) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDBCommunityRoleList) DeepCopyInto(out *MongoDBCommunityRoleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MongoDBCommunityRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunityRoleList.
+func (in *MongoDBCommunityRoleList) DeepCopy() *MongoDBCommunityRoleList {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDBCommunityRoleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MongoDBCommunityRoleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDBCommunityRoleSpec) DeepCopyInto(out *MongoDBCommunityRoleSpec) {
+	*out = *in
+	out.MongoDBResourceRef = in.MongoDBResourceRef
+	in.CustomRole.DeepCopyInto(&out.CustomRole)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunityRoleSpec.
+func (in *MongoDBCommunityRoleSpec) DeepCopy() *MongoDBCommunityRoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDBCommunityRoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDBCommunityRoleStatus) DeepCopyInto(out *MongoDBCommunityRoleStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunityRoleStatus.
+func (in *MongoDBCommunityRoleStatus) DeepCopy() *MongoDBCommunityRoleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDBCommunityRoleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MongoDBCommunitySpec) DeepCopyInto(out *MongoDBCommunitySpec) {
 	*out = *in
+	in.AnalyticsMembers.DeepCopyInto(&out.AnalyticsMembers)
+	out.ShardedCluster = in.ShardedCluster
 	if in.ReplicaSetHorizons != nil {
 		in, out := &in.ReplicaSetHorizons, &out.ReplicaSetHorizons
 		*out = make(ReplicaSetHorizonConfiguration, len(*in))
@@ -199,6 +536,16 @@ func (in *MongoDBCommunitySpec) DeepCopyInto(out *MongoDBCommunitySpec) {
 			}
 		}
 	}
+	in.ExternalAccess.DeepCopyInto(&out.ExternalAccess)
+	out.Services = in.Services
+	if in.MemberConfig != nil {
+		in, out := &in.MemberConfig, &out.MemberConfig
+		*out = make([]MemberConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.ReplicaSetSettings.DeepCopyInto(&out.ReplicaSetSettings)
 	in.Security.DeepCopyInto(&out.Security)
 	if in.Users != nil {
 		in, out := &in.Users, &out.Users
@@ -209,6 +556,8 @@ func (in *MongoDBCommunitySpec) DeepCopyInto(out *MongoDBCommunitySpec) {
 	}
 	in.StatefulSetConfiguration.DeepCopyInto(&out.StatefulSetConfiguration)
 	in.AdditionalMongodConfig.DeepCopyInto(&out.AdditionalMongodConfig)
+	in.Metrics.DeepCopyInto(&out.Metrics)
+	in.Backup.DeepCopyInto(&out.Backup)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunitySpec.
@@ -224,6 +573,13 @@ func (in *MongoDBCommunitySpec) DeepCopy() *MongoDBCommunitySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MongoDBCommunityStatus) DeepCopyInto(out *MongoDBCommunityStatus) {
 	*out = *in
+	out.TLS = in.TLS
+	in.KeyRotation.DeepCopyInto(&out.KeyRotation)
+	if in.ShardedCluster != nil {
+		in, out := &in.ShardedCluster, &out.ShardedCluster
+		*out = make([]ComponentStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunityStatus.
@@ -236,6 +592,112 @@ func (in *MongoDBCommunityStatus) DeepCopy() *MongoDBCommunityStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDBCommunityUser) DeepCopyInto(out *MongoDBCommunityUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunityUser.
+func (in *MongoDBCommunityUser) DeepCopy() *MongoDBCommunityUser {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDBCommunityUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MongoDBCommunityUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDBCommunityUserList) DeepCopyInto(out *MongoDBCommunityUserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MongoDBCommunityUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunityUserList.
+func (in *MongoDBCommunityUserList) DeepCopy() *MongoDBCommunityUserList {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDBCommunityUserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MongoDBCommunityUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDBCommunityUserSpec) DeepCopyInto(out *MongoDBCommunityUserSpec) {
+	*out = *in
+	out.MongoDBResourceRef = in.MongoDBResourceRef
+	in.MongoDBUser.DeepCopyInto(&out.MongoDBUser)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunityUserSpec.
+func (in *MongoDBCommunityUserSpec) DeepCopy() *MongoDBCommunityUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDBCommunityUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDBCommunityUserStatus) DeepCopyInto(out *MongoDBCommunityUserStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCommunityUserStatus.
+func (in *MongoDBCommunityUserStatus) DeepCopy() *MongoDBCommunityUserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDBCommunityUserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDBResourceReference) DeepCopyInto(out *MongoDBResourceReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBResourceReference.
+func (in *MongoDBResourceReference) DeepCopy() *MongoDBResourceReference {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDBResourceReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MongoDBUser) DeepCopyInto(out *MongoDBUser) {
 	*out = *in
@@ -245,6 +707,14 @@ func (in *MongoDBUser) DeepCopyInto(out *MongoDBUser) {
 		*out = make([]Role, len(*in))
 		copy(*out, *in)
 	}
+	in.Scram.DeepCopyInto(&out.Scram)
+	if in.AuthenticationRestrictions != nil {
+		in, out := &in.AuthenticationRestrictions, &out.AuthenticationRestrictions
+		*out = make([]AuthenticationRestriction, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBUser.
@@ -311,6 +781,36 @@ func (in ReplicaSetHorizonConfiguration) DeepCopy() ReplicaSetHorizonConfigurati
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicaSetSettings) DeepCopyInto(out *ReplicaSetSettings) {
+	*out = *in
+	if in.ChainingAllowed != nil {
+		in, out := &in.ChainingAllowed, &out.ChainingAllowed
+		*out = new(bool)
+		**out = **in
+	}
+	if in.GetLastErrorDefaults != nil {
+		in, out := &in.GetLastErrorDefaults, &out.GetLastErrorDefaults
+		*out = new(WriteConcern)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultWriteConcern != nil {
+		in, out := &in.DefaultWriteConcern, &out.DefaultWriteConcern
+		*out = new(WriteConcern)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicaSetSettings.
+func (in *ReplicaSetSettings) DeepCopy() *ReplicaSetSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicaSetSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Resource) DeepCopyInto(out *Resource) {
 	*out = *in
@@ -351,6 +851,26 @@ func (in *Role) DeepCopy() *Role {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScramSettings) DeepCopyInto(out *ScramSettings) {
+	*out = *in
+	if in.Mechanisms != nil {
+		in, out := &in.Mechanisms, &out.Mechanisms
+		*out = make([]ScramMechanism, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScramSettings.
+func (in *ScramSettings) DeepCopy() *ScramSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ScramSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
 	*out = *in
@@ -370,7 +890,7 @@ func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
 func (in *Security) DeepCopyInto(out *Security) {
 	*out = *in
 	in.Authentication.DeepCopyInto(&out.Authentication)
-	out.TLS = in.TLS
+	in.TLS.DeepCopyInto(&out.TLS)
 	if in.Roles != nil {
 		in, out := &in.Roles, &out.Roles
 		*out = make([]CustomRole, len(*in))
@@ -378,6 +898,12 @@ func (in *Security) DeepCopyInto(out *Security) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.LDAP.DeepCopyInto(&out.LDAP)
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Security.
@@ -390,6 +916,36 @@ func (in *Security) DeepCopy() *Security {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Services) DeepCopyInto(out *Services) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Services.
+func (in *Services) DeepCopy() *Services {
+	if in == nil {
+		return nil
+	}
+	out := new(Services)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShardedClusterConfiguration) DeepCopyInto(out *ShardedClusterConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShardedClusterConfiguration.
+func (in *ShardedClusterConfiguration) DeepCopy() *ShardedClusterConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ShardedClusterConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StatefulSetConfiguration) DeepCopyInto(out *StatefulSetConfiguration) {
 	*out = *in
@@ -417,6 +973,13 @@ func (in *TLS) DeepCopyInto(out *TLS) {
 	*out = *in
 	out.CertificateKeySecret = in.CertificateKeySecret
 	out.CaConfigMap = in.CaConfigMap
+	out.CaCertificateSecret = in.CaCertificateSecret
+	out.Keys = in.Keys
+	if in.DisabledProtocols != nil {
+		in, out := &in.DisabledProtocols, &out.DisabledProtocols
+		*out = make([]TLSProtocol, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLS.
@@ -428,3 +991,58 @@ func (in *TLS) DeepCopy() *TLS {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSKeys) DeepCopyInto(out *TLSKeys) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSKeys.
+func (in *TLSKeys) DeepCopy() *TLSKeys {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSKeys)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSStatus) DeepCopyInto(out *TLSStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSStatus.
+func (in *TLSStatus) DeepCopy() *TLSStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WriteConcern) DeepCopyInto(out *WriteConcern) {
+	*out = *in
+	if in.W != nil {
+		in, out := &in.W, &out.W
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.J != nil {
+		in, out := &in.J, &out.J
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WriteConcern.
+func (in *WriteConcern) DeepCopy() *WriteConcern {
+	if in == nil {
+		return nil
+	}
+	out := new(WriteConcern)
+	in.DeepCopyInto(out)
+	return out
+}
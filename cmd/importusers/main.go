@@ -0,0 +1,246 @@
+// importusers lists the users of a running MongoDBCommunity deployment which are not configured in the resource,
+// and prints them as spec.users entries or as MongoDBCommunityUser resources, so that they can be brought under
+// the management of the operator. The existing SCRAM credentials of the users are stored in the secrets
+// the operator reads them from, so the users keep their passwords.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/yaml"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+)
+
+const (
+	outputSpec      = "spec"
+	outputResources = "resources"
+
+	connectTimeout = 30 * time.Second
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(mdbv1.AddToScheme(scheme))
+}
+
+func main() {
+	name := flag.String("name", "", "Name of the MongoDBCommunity resource")
+	namespace := flag.String("namespace", "default", "Namespace of the MongoDBCommunity resource")
+	uri := flag.String("uri", "", "Connection string of the deployment. Defaults to the connection string of the resource, which is only reachable from inside the cluster")
+	caFile := flag.String("ca-file", "", "CA used to verify the certificates of the deployment when TLS is enabled")
+	output := flag.String("output", outputSpec, fmt.Sprintf("Either %q to print spec.users entries, or %q to print MongoDBCommunityUser resources", outputSpec, outputResources))
+	dryRun := flag.Bool("dry-run", false, "Only print the users, without storing their credentials")
+	flag.Parse()
+
+	log := zap.S()
+	if logger, err := zap.NewDevelopment(); err == nil {
+		log = logger.Sugar()
+	}
+
+	if *name == "" {
+		log.Fatal("The -name flag is required")
+	}
+	if *output != outputSpec && *output != outputResources {
+		log.Fatalf("Unknown output %s", *output)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		log.Fatalf("Could not get the Kubernetes config: %s", err)
+	}
+	c, err := k8sClient.New(cfg, k8sClient.Options{Scheme: scheme})
+	if err != nil {
+		log.Fatalf("Could not create the Kubernetes client: %s", err)
+	}
+	kubeClient := client.NewClient(c)
+
+	mdb := mdbv1.MongoDBCommunity{}
+	if err := kubeClient.Get(context.TODO(), types.NamespacedName{Name: *name, Namespace: *namespace}, &mdb); err != nil {
+		log.Fatalf("Could not get MongoDBCommunity %s/%s: %s", *namespace, *name, err)
+	}
+
+	secretBackend, err := secret.BackendFromEnv(kubeClient)
+	if err != nil {
+		log.Fatalf("Could not configure the secret backend: %s", err)
+	}
+
+	users, err := listUsers(secretBackend, mdb, *uri, *caFile)
+	if err != nil {
+		log.Fatalf("Could not list the users of the deployment: %s", err)
+	}
+
+	communityUsers, err := listCommunityUsers(kubeClient, mdb)
+	if err != nil {
+		log.Fatalf("Could not list the MongoDBCommunityUser resources: %s", err)
+	}
+	managed, err := managedUserKeys(mdb, communityUsers)
+	if err != nil {
+		log.Fatalf("Could not determine the users managed by the operator: %s", err)
+	}
+
+	var imported []importedUser
+	for _, u := range users {
+		if isManaged(u, managed) {
+			continue
+		}
+		importedUser, err := toImportedUser(u, mdb.Spec.Security.Authentication.GetDeploymentScramMechanisms())
+		if err != nil {
+			log.Warnf("Skipping user: %s", err)
+			continue
+		}
+		imported = append(imported, importedUser)
+	}
+
+	if !*dryRun {
+		for _, u := range imported {
+			if err := scram.ImportCredentials(secretBackend, mdb.NamespacedName(), u.user.GetScramCredentialsSecretName(), u.sha1Creds, u.sha256Creds); err != nil {
+				log.Fatalf("Could not store the credentials of user %s.%s: %s", u.user.DB, u.user.Name, err)
+			}
+			log.Infof("Stored the credentials of user %s.%s in secret %s", u.user.DB, u.user.Name, u.user.GetScramCredentialsSecretName())
+		}
+	}
+
+	if err := printUsers(os.Stdout, mdb, imported, *output); err != nil {
+		log.Fatalf("Could not print the users: %s", err)
+	}
+}
+
+// listCommunityUsers returns the MongoDBCommunityUser resources referencing the MongoDBCommunity resource.
+func listCommunityUsers(c k8sClient.Client, mdb mdbv1.MongoDBCommunity) ([]mdbv1.MongoDBCommunityUser, error) {
+	userList := mdbv1.MongoDBCommunityUserList{}
+	if err := c.List(context.TODO(), &userList); err != nil {
+		return nil, err
+	}
+	var users []mdbv1.MongoDBCommunityUser
+	for _, u := range userList.Items {
+		if u.MongoDBResourceNamespacedName() == mdb.NamespacedName() {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+// listUsers returns the users of all the databases of the deployment, together with their credentials.
+// The deployment is accessed with the credentials of the agent.
+func listUsers(getter secret.Getter, mdb mdbv1.MongoDBCommunity, uri, caFile string) ([]dbUser, error) {
+	agentPassword, err := secret.ReadKey(getter, scram.AgentPasswordKey, mdb.GetAgentPasswordSecretNamespacedName())
+	if err != nil {
+		return nil, errors.Errorf("could not read the agent password: %s", err)
+	}
+
+	if uri == "" {
+		uri = mdb.MongoURI()
+	}
+	opts := options.Client().
+		ApplyURI(uri).
+		SetAuth(options.Credential{Username: scram.AgentName, Password: agentPassword, AuthSource: defaultDatabase})
+	if mdb.Spec.Security.TLS.Enabled {
+		tlsConfig, err := getTLSConfig(caFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	mongoClient, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, errors.Errorf("could not connect to the deployment: %s", err)
+	}
+	defer func() {
+		_ = mongoClient.Disconnect(context.Background())
+	}()
+
+	result := struct {
+		Users []dbUser `bson:"users"`
+	}{}
+	command := bson.D{
+		{Key: "usersInfo", Value: bson.D{{Key: "forAllDBs", Value: true}}},
+		{Key: "showCredentials", Value: true},
+		{Key: "showAuthenticationRestrictions", Value: true},
+	}
+	if err := mongoClient.Database(defaultDatabase).RunCommand(ctx, command).Decode(&result); err != nil {
+		return nil, errors.Errorf("could not run usersInfo: %s", err)
+	}
+	return result.Users, nil
+}
+
+func getTLSConfig(caFile string) (*tls.Config, error) {
+	if caFile == "" {
+		return nil, errors.New("the -ca-file flag is required when TLS is enabled")
+	}
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Errorf("could not read the CA: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.Errorf("could not parse the CA in %s", caFile)
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
+// printUsers writes the imported users either as spec.users entries of the resource, or as MongoDBCommunityUser resources.
+func printUsers(w io.Writer, mdb mdbv1.MongoDBCommunity, imported []importedUser, output string) error {
+	if output == outputSpec {
+		users := []mdbv1.MongoDBUser{}
+		for _, u := range imported {
+			users = append(users, u.user)
+		}
+		data, err := yaml.Marshal(map[string]interface{}{"users": users})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	for _, u := range imported {
+		communityUser := mdbv1.MongoDBCommunityUser{
+			Spec: mdbv1.MongoDBCommunityUserSpec{
+				MongoDBResourceRef: mdbv1.MongoDBResourceReference{Name: mdb.Name},
+				MongoDBUser:        u.user,
+			},
+		}
+		document := map[string]interface{}{
+			"apiVersion": mdbv1.GroupVersion.String(),
+			"kind":       "MongoDBCommunityUser",
+			"metadata":   map[string]string{"name": u.user.ScramCredentialsSecretName, "namespace": mdb.Namespace},
+			"spec":       communityUser.Spec,
+		}
+		data, err := yaml.Marshal(document)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
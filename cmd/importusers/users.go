@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scramcredentials"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
+)
+
+const (
+	externalDatabase = "$external"
+	defaultDatabase  = "admin"
+)
+
+var invalidSecretNameCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// dbUser is a user as returned by the usersInfo command.
+type dbUser struct {
+	User                       string                        `bson:"user"`
+	DB                         string                        `bson:"db"`
+	Roles                      []dbRole                      `bson:"roles"`
+	Credentials                map[string]dbCredentials      `bson:"credentials"`
+	AuthenticationRestrictions []dbAuthenticationRestriction `bson:"authenticationRestrictions"`
+}
+
+type dbRole struct {
+	Role string `bson:"role"`
+	DB   string `bson:"db"`
+}
+
+type dbCredentials struct {
+	IterationCount int    `bson:"iterationCount"`
+	Salt           string `bson:"salt"`
+	StoredKey      string `bson:"storedKey"`
+	ServerKey      string `bson:"serverKey"`
+}
+
+type dbAuthenticationRestriction struct {
+	ClientSource  []string `bson:"clientSource"`
+	ServerAddress []string `bson:"serverAddress"`
+}
+
+// importedUser is a user of the deployment converted into a user which can be configured by the operator,
+// together with its existing credentials.
+type importedUser struct {
+	user        mdbv1.MongoDBUser
+	sha1Creds   scramcredentials.ScramCreds
+	sha256Creds scramcredentials.ScramCreds
+}
+
+// managedUser is an entry of the managedUsers annotation of the resource.
+type managedUser struct {
+	User string `json:"user"`
+	DB   string `json:"db"`
+}
+
+// managedUserKeys returns the users the operator configures in the deployment, in the <db>.<username> format: the agent
+// user, the users of the spec and of the MongoDBCommunityUser resources, the metrics and backup users, and the users
+// recorded in the managedUsers annotation, which includes the ones created by the operator in the past.
+func managedUserKeys(mdb mdbv1.MongoDBCommunity, communityUsers []mdbv1.MongoDBCommunityUser) (map[string]bool, error) {
+	keys := map[string]bool{automationconfig.UserKey(defaultDatabase, scram.AgentName): true}
+	for _, u := range mdb.Spec.Users {
+		keys[automationconfig.UserKey(u.DB, u.Name)] = true
+	}
+	for _, u := range communityUsers {
+		keys[automationconfig.UserKey(u.Spec.DB, u.Spec.Name)] = true
+	}
+	if mdb.Spec.Metrics.Enabled {
+		keys[automationconfig.UserKey(defaultDatabase, internalUserName(mdb.Spec.Metrics.User, "metrics"))] = true
+	}
+	if mdb.Spec.Backup.Enabled {
+		keys[automationconfig.UserKey(defaultDatabase, internalUserName(mdb.Spec.Backup.User, "backup"))] = true
+	}
+
+	if saved, ok := mdb.Annotations[annotations.ManagedUsers]; ok {
+		var managedUsers []managedUser
+		if err := json.Unmarshal([]byte(saved), &managedUsers); err != nil {
+			return nil, errors.Errorf("could not read the %s annotation: %s", annotations.ManagedUsers, err)
+		}
+		for _, u := range managedUsers {
+			keys[automationconfig.UserKey(u.DB, u.User)] = true
+		}
+	}
+	return keys, nil
+}
+
+// isManaged returns true if the user is one of the given users configured by the operator.
+func isManaged(u dbUser, managedUserKeys map[string]bool) bool {
+	return managedUserKeys[automationconfig.UserKey(u.DB, u.User)]
+}
+
+// internalUserName returns the name of the user of one of the operator's features.
+func internalUserName(config mdbv1.InternalUser, defaultName string) string {
+	if config.Name != "" {
+		return config.Name
+	}
+	return defaultName
+}
+
+// toImportedUser converts a user of the deployment into a user which keeps its existing SCRAM credentials
+// when it is configured by the operator. The password secret of the user is not created, as the password can't be read
+// from the deployment. The operator reads the credentials from the SCRAM credentials secret until it is created.
+func toImportedUser(u dbUser, deploymentMechanisms []mdbv1.ScramMechanism) (importedUser, error) {
+	if u.DB == externalDatabase {
+		return importedUser{}, errors.Errorf("user %s.%s is not a SCRAM user", u.DB, u.User)
+	}
+
+	imported := importedUser{}
+	var mechanisms []mdbv1.ScramMechanism
+	if creds, ok := u.Credentials[string(mdbv1.ScramSha1)]; ok {
+		imported.sha1Creds = toScramCreds(creds)
+		mechanisms = append(mechanisms, mdbv1.ScramSha1)
+	}
+	if creds, ok := u.Credentials[string(mdbv1.ScramSha256)]; ok {
+		imported.sha256Creds = toScramCreds(creds)
+		mechanisms = append(mechanisms, mdbv1.ScramSha256)
+	}
+	if len(mechanisms) == 0 {
+		return importedUser{}, errors.Errorf("user %s.%s has no SCRAM credentials", u.DB, u.User)
+	}
+
+	// the operator stores the credentials of both mechanisms by default, the mechanisms are only set
+	// on the user when it has the credentials of a single one.
+	if len(mechanisms) == 1 {
+		if !containsMechanism(deploymentMechanisms, mechanisms[0]) {
+			return importedUser{}, errors.Errorf("user %s.%s only has %s credentials, which is not enabled in the deployment", u.DB, u.User, mechanisms[0])
+		}
+		imported.user.Scram.Mechanisms = mechanisms
+	}
+
+	secretName := credentialsSecretBaseName(u.DB, u.User)
+	imported.user.Name = u.User
+	imported.user.DB = u.DB
+	imported.user.PasswordSecretRef = mdbv1.SecretKeyReference{Name: secretName + "-password", Key: "password"}
+	imported.user.ScramCredentialsSecretName = secretName
+	imported.user.Roles = []mdbv1.Role{}
+	for _, role := range u.Roles {
+		imported.user.Roles = append(imported.user.Roles, mdbv1.Role{Name: role.Role, DB: role.DB})
+	}
+	for _, restriction := range u.AuthenticationRestrictions {
+		imported.user.AuthenticationRestrictions = append(imported.user.AuthenticationRestrictions, mdbv1.AuthenticationRestriction{
+			ClientSource:  restriction.ClientSource,
+			ServerAddress: restriction.ServerAddress,
+		})
+	}
+	return imported, nil
+}
+
+func toScramCreds(creds dbCredentials) scramcredentials.ScramCreds {
+	return scramcredentials.ScramCreds{
+		IterationCount: creds.IterationCount,
+		Salt:           creds.Salt,
+		StoredKey:      creds.StoredKey,
+		ServerKey:      creds.ServerKey,
+	}
+}
+
+func containsMechanism(mechanisms []mdbv1.ScramMechanism, mechanism mdbv1.ScramMechanism) bool {
+	for _, m := range mechanisms {
+		if m == mechanism {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialsSecretBaseName returns a valid secret name for the user. When the username or database
+// contain characters which are not valid in a secret name, a hash of the user is appended so that
+// different users don't end up sharing the same secret.
+func credentialsSecretBaseName(db, user string) string {
+	original := fmt.Sprintf("%s-%s", db, user)
+	name := strings.Trim(invalidSecretNameCharacters.ReplaceAllString(strings.ToLower(original), "-"), "-")
+	if name == original {
+		return name
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s.%s", db, user)))
+	return strings.TrimPrefix(fmt.Sprintf("%s-%x", name, hash[:4]), "-")
+}
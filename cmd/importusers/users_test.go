@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scramcredentials"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDBUser(credentials ...string) dbUser {
+	u := dbUser{
+		User:        "app-user",
+		DB:          "admin",
+		Roles:       []dbRole{{Role: "readWrite", DB: "app"}},
+		Credentials: map[string]dbCredentials{},
+	}
+	for _, mechanism := range credentials {
+		u.Credentials[mechanism] = dbCredentials{IterationCount: 15000, Salt: "salt", StoredKey: "stored", ServerKey: "server"}
+	}
+	return u
+}
+
+func TestToImportedUser(t *testing.T) {
+	t.Run("Users with the credentials of both mechanisms use the default mechanisms", func(t *testing.T) {
+		imported, err := toImportedUser(newDBUser("SCRAM-SHA-1", "SCRAM-SHA-256"), []mdbv1.ScramMechanism{mdbv1.ScramSha256})
+		assert.NoError(t, err)
+		assert.Equal(t, "app-user", imported.user.Name)
+		assert.Equal(t, "admin", imported.user.DB)
+		assert.Equal(t, "admin-app-user", imported.user.ScramCredentialsSecretName)
+		assert.Equal(t, "admin-app-user-password", imported.user.PasswordSecretRef.Name)
+		assert.Equal(t, []mdbv1.Role{{Name: "readWrite", DB: "app"}}, imported.user.Roles)
+		assert.Empty(t, imported.user.Scram.Mechanisms)
+		assert.Equal(t, scramcredentials.ScramCreds{IterationCount: 15000, Salt: "salt", StoredKey: "stored", ServerKey: "server"}, imported.sha1Creds)
+		assert.Equal(t, imported.sha1Creds, imported.sha256Creds)
+	})
+
+	t.Run("Users with the credentials of a single mechanism only use this mechanism", func(t *testing.T) {
+		imported, err := toImportedUser(newDBUser("SCRAM-SHA-256"), []mdbv1.ScramMechanism{mdbv1.ScramSha256})
+		assert.NoError(t, err)
+		assert.Equal(t, []mdbv1.ScramMechanism{mdbv1.ScramSha256}, imported.user.Scram.Mechanisms)
+		assert.Equal(t, scramcredentials.ScramCreds{}, imported.sha1Creds)
+	})
+
+	t.Run("Users with the credentials of a mechanism which is not enabled are skipped", func(t *testing.T) {
+		_, err := toImportedUser(newDBUser("SCRAM-SHA-1"), []mdbv1.ScramMechanism{mdbv1.ScramSha256})
+		assert.Error(t, err)
+	})
+
+	t.Run("Users without SCRAM credentials are skipped", func(t *testing.T) {
+		_, err := toImportedUser(newDBUser(), []mdbv1.ScramMechanism{mdbv1.ScramSha256})
+		assert.Error(t, err)
+
+		external := newDBUser()
+		external.DB = "$external"
+		_, err = toImportedUser(external, []mdbv1.ScramMechanism{mdbv1.ScramSha256})
+		assert.Error(t, err)
+	})
+
+	t.Run("Authentication restrictions are imported", func(t *testing.T) {
+		u := newDBUser("SCRAM-SHA-256")
+		u.AuthenticationRestrictions = []dbAuthenticationRestriction{{ClientSource: []string{"10.0.0.0/8"}}}
+		imported, err := toImportedUser(u, []mdbv1.ScramMechanism{mdbv1.ScramSha256})
+		assert.NoError(t, err)
+		assert.Equal(t, []mdbv1.AuthenticationRestriction{{ClientSource: []string{"10.0.0.0/8"}}}, imported.user.AuthenticationRestrictions)
+	})
+}
+
+func TestIsManaged(t *testing.T) {
+	mdb := mdbv1.MongoDBCommunity{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "my-rs",
+			Namespace:   "my-ns",
+			Annotations: map[string]string{annotations.ManagedUsers: `[{"user":"previous-user","db":"app","scramCredentialsSecretName":"previous-user-scram-credentials"}]`},
+		},
+		Spec: mdbv1.MongoDBCommunitySpec{
+			Users:   []mdbv1.MongoDBUser{{Name: "spec-user"}},
+			Metrics: mdbv1.Metrics{Enabled: true},
+			Backup:  mdbv1.Backup{Enabled: true, User: mdbv1.InternalUser{Name: "my-backup"}},
+		},
+	}
+	communityUsers := []mdbv1.MongoDBCommunityUser{{Spec: mdbv1.MongoDBCommunityUserSpec{MongoDBUser: mdbv1.MongoDBUser{Name: "cr-user", DB: "app"}}}}
+	managed, err := managedUserKeys(mdb, communityUsers)
+	assert.NoError(t, err)
+
+	assert.True(t, isManaged(dbUser{User: "mms-automation", DB: "admin"}, managed))
+	assert.True(t, isManaged(dbUser{User: "spec-user", DB: "admin"}, managed))
+	assert.True(t, isManaged(dbUser{User: "cr-user", DB: "app"}, managed))
+	assert.True(t, isManaged(dbUser{User: "metrics", DB: "admin"}, managed))
+	assert.True(t, isManaged(dbUser{User: "my-backup", DB: "admin"}, managed))
+	assert.True(t, isManaged(dbUser{User: "previous-user", DB: "app"}, managed))
+	assert.False(t, isManaged(dbUser{User: "backup", DB: "admin"}, managed))
+	assert.False(t, isManaged(dbUser{User: "spec-user", DB: "app"}, managed))
+	assert.False(t, isManaged(dbUser{User: "app-user", DB: "admin"}, managed))
+}
+
+func TestCredentialsSecretBaseName(t *testing.T) {
+	assert.Equal(t, "admin-app-user", credentialsSecretBaseName("admin", "app-user"))
+
+	// names which are not valid secret names are sanitized, and don't collide
+	sanitized := credentialsSecretBaseName("admin", "App_User")
+	assert.Regexp(t, `^admin-app-user-[0-9a-f]{8}$`, sanitized)
+	assert.NotEqual(t, sanitized, credentialsSecretBaseName("admin", "app_user"))
+}
+
+func TestPrintUsers(t *testing.T) {
+	mdb := mdbv1.MongoDBCommunity{ObjectMeta: metav1.ObjectMeta{Name: "my-rs", Namespace: "my-ns"}}
+	imported, err := toImportedUser(newDBUser("SCRAM-SHA-1", "SCRAM-SHA-256"), []mdbv1.ScramMechanism{mdbv1.ScramSha256})
+	assert.NoError(t, err)
+
+	t.Run("As spec.users entries", func(t *testing.T) {
+		out := bytes.Buffer{}
+		assert.NoError(t, printUsers(&out, mdb, []importedUser{imported}, outputSpec))
+		assert.Contains(t, out.String(), "users:\n- db: admin\n")
+		assert.Contains(t, out.String(), "scramCredentialsSecretName: admin-app-user\n")
+	})
+
+	t.Run("As MongoDBCommunityUser resources", func(t *testing.T) {
+		out := bytes.Buffer{}
+		assert.NoError(t, printUsers(&out, mdb, []importedUser{imported}, outputResources))
+		assert.Contains(t, out.String(), "---\napiVersion: mongodbcommunity.mongodb.com/v1\nkind: MongoDBCommunityUser\n")
+		assert.Contains(t, out.String(), "name: admin-app-user\n  namespace: my-ns\n")
+		assert.Contains(t, out.String(), "mongodbResourceRef:\n    name: my-rs\n")
+	})
+}
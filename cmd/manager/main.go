@@ -7,6 +7,8 @@ import (
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
 	"github.com/mongodb/mongodb-kubernetes-operator/controllers"
 	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	kubernetesClient "github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -109,8 +111,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	secretBackend, err := secret.BackendFromEnv(kubernetesClient.NewClient(mgr.GetClient()))
+	if err != nil {
+		setupLog.Error(err, "Unable to configure the secret backend")
+		os.Exit(1)
+	}
+	secretResyncInterval, err := secret.ResyncIntervalFromEnv()
+	if err != nil {
+		setupLog.Error(err, "Unable to configure the secret backend")
+		os.Exit(1)
+	}
+
 	// Setup Controller.
-	if err = controllers.NewReconciler(mgr).SetupWithManager(mgr); err != nil {
+	if err = controllers.NewReconciler(mgr).WithSecretBackend(secretBackend).WithSecretResyncInterval(secretResyncInterval).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "Unable to create controller")
 		os.Exit(1)
 	}
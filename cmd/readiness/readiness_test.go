@@ -113,6 +113,7 @@ func TestHeadlessAgentReachedGoal(t *testing.T) {
 	assert.True(t, isPodReady(c))
 	thePod, _ := c.ClientSet.CoreV1().Pods(c.Namespace).Get(context.TODO(), c.Hostname, metav1.GetOptions{})
 	assert.Equal(t, map[string]string{"agent.mongodb.com/version": "5"}, thePod.Annotations)
+	assert.Equal(t, map[string]string{"mongodb.com/role": "none"}, thePod.Labels, "the agent publishes no replication status")
 
 	os.Unsetenv(headlessAgent)
 }
@@ -133,6 +134,10 @@ func TestPodReadiness(t *testing.T) {
 	t.Run("If replication state is readable", func(t *testing.T) {
 		assert.True(t, isPodReady(testConfig("testdata/health-status-readable-state.json")))
 	})
+
+	t.Run("A standalone, whose replication state is undefined, is ready", func(t *testing.T) {
+		assert.True(t, isPodReady(testConfig("testdata/health-status-standalone.json")))
+	})
 }
 
 func readHealthinessFile(path string) health.Status {
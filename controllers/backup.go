@@ -9,11 +9,14 @@ import (
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
 
+	"github.com/pkg/errors"
+
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/cronjob"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -73,7 +76,7 @@ func buildBackupCronJob(mdb mdbv1.MongoDBCommunity) batchv1beta1.CronJob {
 						ValueFrom: &corev1.EnvVarSource{
 							SecretKeyRef: &corev1.SecretKeySelector{
 								LocalObjectReference: corev1.LocalObjectReference{
-									Name: mdb.Name + "-backup-uri",
+									Name: backupURISecretName(mdb),
 								},
 								Key: "mongodb-uri",
 							},
@@ -116,8 +119,9 @@ func buildBackupCronJob(mdb mdbv1.MongoDBCommunity) batchv1beta1.CronJob {
 		Build()
 }
 
-func insertBackupUser(mdb *mdbv1.MongoDBCommunity) mdbv1.MongoDBUser {
-	backupUser := mdbv1.MongoDBUser{
+// backupUser returns the user the backup CronJob connects with.
+func backupUser(mdb mdbv1.MongoDBCommunity) mdbv1.MongoDBUser {
+	return toInternalUser(mdb.Spec.Backup.User, mdbv1.MongoDBUser{
 		Name:              backupUsername,
 		DB:                "admin",
 		PasswordSecretRef: mdbv1.SecretKeyReference{Name: mdb.Name + "-backup-user"},
@@ -128,20 +132,30 @@ func insertBackupUser(mdb *mdbv1.MongoDBCommunity) mdbv1.MongoDBUser {
 			},
 		},
 		ScramCredentialsSecretName: mdb.Name + "-backup-user",
-	}
+	})
+}
 
-	contains := false
-	for i, value := range mdb.Spec.Users {
-		if value.Name == backupUsername {
-			contains = true
-			mdb.Spec.Users[i] = backupUser
-			break
-		}
-	}
-	if contains == false {
-		mdb.Spec.Users = append(mdb.Spec.Users, backupUser)
-	}
+// backupURISecretName returns the name of the secret containing the connection string of the backup CronJob.
+func backupURISecretName(mdb mdbv1.MongoDBCommunity) string {
+	return mdb.Name + "-backup-uri"
+}
 
-	return backupUser
+// deleteBackupCronJob removes the backup CronJob once backups are disabled.
+func (r *ReplicaSetReconciler) deleteBackupCronJob(mdb mdbv1.MongoDBCommunity) error {
+	cj := batchv1beta1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: mdb.Name + "-backup", Namespace: mdb.Namespace}}
+	return r.deleteIfExists(&cj)
+}
 
+// ensureBackupResources creates the backup CronJob when backups are enabled, and removes it otherwise.
+func (r *ReplicaSetReconciler) ensureBackupResources(mdb mdbv1.MongoDBCommunity) error {
+	if !backupEnabled(mdb) {
+		r.log.Debug("Ensuring the backup CronJob doesn't exist")
+		return r.deleteBackupCronJob(mdb)
+	}
+
+	r.log.Debug("Ensuring the backup CronJob exists")
+	if err := r.ensureBackupCronJob(mdb); err != nil {
+		return errors.Errorf("error ensuring the backup cronjob exists: %s", err)
+	}
+	return nil
 }
@@ -55,7 +55,6 @@ const (
 	ExporterImageTag        = "0.20.4"
 	ExporterImagePullPolicy = corev1.PullIfNotPresent
 	ExporterPort            = 9216
-	ExporterUsername        = "metrics"
 
 	MongodbUserCommand = `current_uid=$(id -u)
 declare -r current_uid
@@ -175,7 +174,6 @@ func BuildMongoDBReplicaSetStatefulSetModificationFunction(mdb MongoDBStatefulSe
 				podtemplatespec.WithServiceAccount(operatorServiceAccountName),
 				podtemplatespec.WithContainer(AgentName, mongodbAgentContainer(mdb.AutomationConfigSecretName(), mongodbAgentVolumeMounts)),
 				podtemplatespec.WithContainer(MongodbName, mongodbContainer(mdb.GetMongoDBVersion(), mongodVolumeMounts)),
-				podtemplatespec.WithContainer(ExporterName, exporterContainer(mdb.GetName())),
 				podtemplatespec.WithInitContainer(versionUpgradeHookName, versionUpgradeHookInit([]corev1.VolumeMount{hooksVolumeMount})),
 				podtemplatespec.WithInitContainer(ReadinessProbeContainerName, readinessProbeInit([]corev1.VolumeMount{scriptsVolumeMount})),
 			),
@@ -286,7 +284,9 @@ func getMongoDBImage(version string) string {
 	return fmt.Sprintf("%s/%s:%s", repoUrl, mongoImageName, version)
 }
 
-func mongodbContainer(version string, volumeMounts []corev1.VolumeMount) container.Modification {
+// mongodbContainerCommand returns the command which starts the given MongoDB binary, mongod or mongos,
+// with the configuration written by the agent.
+func mongodbContainerCommand(binary string) []string {
 	mongoDbCommand := fmt.Sprintf(`
 #run post-start hook to handle version changes
 /hooks/version-upgrade
@@ -298,16 +298,25 @@ while ! [ -f %s -a -f %s ]; do sleep 3 ; done ; sleep 2 ;
 # mongod does not write to stdout and a log file
 tail -F /var/log/mongodb-mms-automation/mongodb.log > /dev/stdout &
 
-# start mongod with this configuration
-exec mongod -f %s;
+# start %s with this configuration
+exec %s -f %s;
 
-`, automationconfFilePath, keyfileFilePath, automationconfFilePath)
+`, automationconfFilePath, keyfileFilePath, binary, binary, automationconfFilePath)
 
-	containerCommand := []string{
+	return []string{
 		"/bin/sh",
 		"-c",
 		mongoDbCommand,
 	}
+}
+
+// WithMongosCommand runs mongos instead of mongod in the MongoDB container, for the pods of the mongos StatefulSet.
+func WithMongosCommand() podtemplatespec.Modification {
+	return podtemplatespec.WithContainer(MongodbName, container.WithCommand(mongodbContainerCommand("mongos")))
+}
+
+func mongodbContainer(version string, volumeMounts []corev1.VolumeMount) container.Modification {
+	containerCommand := mongodbContainerCommand("mongod")
 
 	securityContext := container.NOOP()
 	managedSecurityContext := envvar.ReadBool(ManagedSecurityContextEnv)
@@ -332,7 +341,8 @@ exec mongod -f %s;
 	)
 }
 
-func exporterContainer(mongoName string) container.Modification {
+// ExporterContainer returns the Prometheus exporter container, which connects with the connection string stored in the given secret.
+func ExporterContainer(uriSecretName string) container.Modification {
 
 	return container.Apply(
 		container.WithName(ExporterName),
@@ -385,7 +395,7 @@ func exporterContainer(mongoName string) container.Modification {
 				ValueFrom: &corev1.EnvVarSource{
 					SecretKeyRef: &corev1.SecretKeySelector{
 						LocalObjectReference: corev1.LocalObjectReference{
-							Name: fmt.Sprintf("%s-%s-uri", mongoName, ExporterUsername),
+							Name: uriSecretName,
 						},
 						Key: "mongodb-uri",
 					},
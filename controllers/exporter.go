@@ -5,10 +5,15 @@ import (
 
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 
+	"github.com/pkg/errors"
+
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/service"
 	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -44,8 +49,9 @@ func (r *ReplicaSetReconciler) ensureExporterService(mdb mdbv1.MongoDBCommunity)
 	return err
 }
 
-func (r *ReplicaSetReconciler) ensureExporterServiceMonitor(mdb mdbv1.MongoDBCommunity) error {
-	svcMon := &monitoringv1.ServiceMonitor{
+// buildExporterServiceMonitor creates the ServiceMonitor which lets Prometheus scrape the exporters
+func buildExporterServiceMonitor(mdb mdbv1.MongoDBCommunity) *monitoringv1.ServiceMonitor {
+	return &monitoringv1.ServiceMonitor{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "monitoring.coreos.com/v1",
 			Kind:       "ServiceMonitor",
@@ -76,6 +82,10 @@ func (r *ReplicaSetReconciler) ensureExporterServiceMonitor(mdb mdbv1.MongoDBCom
 			},
 		},
 	}
+}
+
+func (r *ReplicaSetReconciler) ensureExporterServiceMonitor(mdb mdbv1.MongoDBCommunity) error {
+	svcMon := buildExporterServiceMonitor(mdb)
 	err := r.client.Create(context.TODO(), svcMon)
 	if err != nil && apiErrors.IsAlreadyExists(err) {
 		r.log.Infof("The service monitor already exists... moving forward: %s", err)
@@ -84,8 +94,9 @@ func (r *ReplicaSetReconciler) ensureExporterServiceMonitor(mdb mdbv1.MongoDBCom
 	return err
 }
 
-func insertMetricsUser(mdb *mdbv1.MongoDBCommunity) mdbv1.MongoDBUser {
-	metricsUser := mdbv1.MongoDBUser{
+// metricsUser returns the user the exporter connects with.
+func metricsUser(mdb mdbv1.MongoDBCommunity) mdbv1.MongoDBUser {
+	return toInternalUser(mdb.Spec.Metrics.User, mdbv1.MongoDBUser{
 		Name:              metricsUsername,
 		DB:                "admin",
 		PasswordSecretRef: mdbv1.SecretKeyReference{Name: mdb.Name + "-metrics-user"},
@@ -104,20 +115,54 @@ func insertMetricsUser(mdb *mdbv1.MongoDBCommunity) mdbv1.MongoDBUser {
 			},
 		},
 		ScramCredentialsSecretName: mdb.Name + "-metrics-user",
+	})
+}
+
+// metricsURISecretName returns the name of the secret containing the connection string of the exporter.
+func metricsURISecretName(mdb mdbv1.MongoDBCommunity) string {
+	return mdb.Name + "-metrics-uri"
+}
+
+// deleteExporterResources removes the exporter Service and ServiceMonitor once the exporter is disabled.
+// The ServiceMonitor is skipped when its CRD is not installed, or when the operator is not allowed to access
+// the namespace of Prometheus, as it can't have been created then.
+func (r *ReplicaSetReconciler) deleteExporterResources(mdb mdbv1.MongoDBCommunity) error {
+	svc := buildExporterService(mdb)
+	if err := r.deleteIfExists(&svc); err != nil {
+		return err
 	}
 
-	contains := false
-	for i, value := range mdb.Spec.Users {
-		if value.Name == metricsUsername {
-			contains = true
-			mdb.Spec.Users[i] = metricsUser
-			break
-		}
+	err := r.deleteIfExists(buildExporterServiceMonitor(mdb))
+	if meta.IsNoMatchError(err) || apiErrors.IsForbidden(err) {
+		r.log.Debugf("Skipping the deletion of the exporter service monitor: %s", err)
+		return nil
 	}
-	if contains == false {
-		mdb.Spec.Users = append(mdb.Spec.Users, metricsUser)
+	return err
+}
+
+// ensureMetricsResources creates the exporter Service and ServiceMonitor when the exporter is enabled, and removes them otherwise.
+func (r *ReplicaSetReconciler) ensureMetricsResources(mdb mdbv1.MongoDBCommunity) error {
+	if !metricsEnabled(mdb) {
+		r.log.Debug("Ensuring the exporter service and service monitor don't exist")
+		return r.deleteExporterResources(mdb)
 	}
 
-	return metricsUser
+	r.log.Debug("Ensuring the exporter service exists")
+	if err := r.ensureExporterService(mdb); err != nil {
+		return errors.Errorf("error ensuring the exporter service exists: %s", err)
+	}
 
+	r.log.Debug("Ensuring the exporter service monitor exists")
+	if err := r.ensureExporterServiceMonitor(mdb); err != nil {
+		return errors.Errorf("error ensuring the exporter service monitor exists: %s", err)
+	}
+	return nil
+}
+
+// buildExporterPodSpecModification adds the exporter container to the members when the exporter is enabled.
+func buildExporterPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	if !metricsEnabled(mdb) {
+		return podtemplatespec.NOOP()
+	}
+	return podtemplatespec.WithContainer(construct.ExporterName, construct.ExporterContainer(metricsURISecretName(mdb)))
 }
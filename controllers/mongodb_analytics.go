@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/container"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/statefulset"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// analyticsMemberEnv tells the readiness probe of the analytics members not to label them as secondaries.
+const analyticsMemberEnv = "ANALYTICS_MEMBER"
+
+func buildAnalyticsStatefulSetModificationFunction(mdb mdbv1.MongoDBCommunity) statefulset.Modification {
+	analytics := mdb.Spec.AnalyticsMembers
+	commonModification := construct.BuildMongoDBReplicaSetStatefulSetModificationFunction(
+		&componentStatefulSetOwner{MongoDBCommunity: mdb, nsName: mdb.AnalyticsNamespacedName()},
+		fixedReplicasScaler{replicas: analytics.Members},
+	)
+
+	// spec.statefulSet is not applied, as its affinity and node selector place the pods on the nodes of the members.
+	resources, nodeSelector, tolerations := podtemplatespec.NOOP(), podtemplatespec.NOOP(), podtemplatespec.NOOP()
+	if len(analytics.Resources.Limits) > 0 || len(analytics.Resources.Requests) > 0 {
+		resources = podtemplatespec.WithContainer(construct.MongodbName, container.WithResourceRequirements(analytics.Resources))
+	}
+	if len(analytics.NodeSelector) > 0 {
+		nodeSelector = podtemplatespec.WithNodeSelector(analytics.NodeSelector)
+	}
+	if len(analytics.Tolerations) > 0 {
+		tolerations = podtemplatespec.WithTolerations(analytics.Tolerations)
+	}
+
+	return withComponentLabel(componentAnalytics, statefulset.Apply(
+		commonModification,
+		statefulset.WithOwnerReference([]metav1.OwnerReference{getOwnerReference(mdb)}),
+		statefulset.WithPodSpecTemplate(
+			podtemplatespec.Apply(
+				buildTLSPodSpecModification(mdb),
+				buildClusterAuthPodSpecModification(mdb),
+				podtemplatespec.WithContainer(construct.AgentName, container.WithEnvs(corev1.EnvVar{Name: analyticsMemberEnv, Value: "true"})),
+			),
+		),
+		statefulset.WithPodSpecTemplate(
+			podtemplatespec.Apply(
+				resources,
+				nodeSelector,
+				tolerations,
+			),
+		),
+	))
+}
+
+// createOrUpdateAnalyticsStatefulSet creates the StatefulSet of the analytics members once some are requested, and
+// keeps it afterwards so that they can be scaled down.
+func (r *ReplicaSetReconciler) createOrUpdateAnalyticsStatefulSet(mdb mdbv1.MongoDBCommunity, ca string) error {
+	set := appsv1.StatefulSet{}
+	err := r.client.Get(context.TODO(), mdb.AnalyticsNamespacedName(), &set)
+	if apiErrors.IsNotFound(err) && mdb.Spec.AnalyticsMembers.Members == 0 {
+		return nil
+	}
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return errors.Errorf("error getting the StatefulSet of the analytics members: %s", err)
+	}
+	buildAnalyticsStatefulSetModificationFunction(mdb)(&set)
+	withCAHashAnnotation(mdb, ca, err == nil)(&set)
+	if _, err = statefulset.CreateOrUpdate(r.client, set); err != nil {
+		return errors.Errorf("error creating/updating the StatefulSet of the analytics members: %s", err)
+	}
+	return nil
+}
+
+// isAnalyticsStatefulSetReady returns true if the StatefulSet of the analytics members, when it exists, has the
+// expected number of ready replicas.
+func (r *ReplicaSetReconciler) isAnalyticsStatefulSetReady(mdb mdbv1.MongoDBCommunity) (bool, error) {
+	sts, err := r.client.GetStatefulSet(mdb.AnalyticsNamespacedName())
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, errors.Errorf("error getting the StatefulSet of the analytics members: %s", err)
+	}
+	return statefulset.IsReady(sts, mdb.Spec.AnalyticsMembers.Members) || sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType, nil
+}
+
+// isScalingUpAnalyticsMembers returns true if analytics members are being added, in which case their StatefulSet
+// is updated before the automation config.
+func (r *ReplicaSetReconciler) isScalingUpAnalyticsMembers(mdb mdbv1.MongoDBCommunity) (bool, error) {
+	if mdb.Spec.AnalyticsMembers.Members == 0 {
+		return false, nil
+	}
+	sts, err := r.client.GetStatefulSet(mdb.AnalyticsNamespacedName())
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return mdb.Status.CurrentStatefulSetReplicas != 0, nil
+		}
+		return false, errors.Errorf("error getting the StatefulSet of the analytics members: %s", err)
+	}
+	return sts.Spec.Replicas == nil || int(*sts.Spec.Replicas) < mdb.Spec.AnalyticsMembers.Members, nil
+}
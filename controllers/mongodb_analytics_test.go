@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/container"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newAnalyticsReplicaSet() mdbv1.MongoDBCommunity {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Arbiters = 1
+	mdb.Spec.AnalyticsMembers = mdbv1.AnalyticsMembersConfiguration{
+		Members: 2,
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+		},
+		NodeSelector: map[string]string{"pool": "analytics"},
+		Tolerations:  []corev1.Toleration{{Key: "analytics", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+	}
+	return mdb
+}
+
+func TestReplicaSet_IsCreatedWithAnalyticsMembers(t *testing.T) {
+	mdb := newAnalyticsReplicaSet()
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Len(t, ac.Processes, 6)
+	members := ac.ReplicaSets[0].Members
+	assert.Len(t, members, 6)
+	for i, member := range members[4:] {
+		assert.Equal(t, ac.Processes[4+i].Name, member.Host)
+		assert.Equal(t, 0, member.Votes)
+		assert.Equal(t, 0, member.Priority)
+		assert.Equal(t, map[string]string{"nodeType": "ANALYTICS"}, member.Tags)
+	}
+	assert.Equal(t, "my-rs-analytics-0", members[4].Host)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.AnalyticsNamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), *sts.Spec.Replicas)
+	assert.Equal(t, mdb.ServiceName(), sts.Spec.ServiceName)
+	assert.Equal(t, map[string]string{"pool": "analytics"}, sts.Spec.Template.Spec.NodeSelector)
+	assert.Equal(t, mdb.Spec.AnalyticsMembers.Tolerations, sts.Spec.Template.Spec.Tolerations)
+
+	mongod := container.GetByName(construct.MongodbName, sts.Spec.Template.Spec.Containers)
+	assert.Equal(t, mdb.Spec.AnalyticsMembers.Resources, mongod.Resources)
+	agent := container.GetByName(construct.AgentName, sts.Spec.Template.Spec.Containers)
+	assert.Contains(t, agent.Env, corev1.EnvVar{Name: analyticsMemberEnv, Value: "true"})
+
+	members0, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Empty(t, members0.Spec.Template.Spec.NodeSelector, "the settings of the analytics members only apply to them")
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+}
+
+func TestReplicaSet_AnalyticsMembersAreAddedAndRemoved(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	_, err = mgr.Client.GetStatefulSet(mdb.AnalyticsNamespacedName())
+	assert.True(t, apiErrors.IsNotFound(err), "the StatefulSet is only created for analytics members")
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.AnalyticsMembers.Members = 2
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+	inOrder, err := r.shouldRunInOrder(mdb)
+	assert.NoError(t, err)
+	assert.False(t, inOrder, "the StatefulSet is scaled up before the automation config")
+
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	makeAnalyticsStatefulSetReady(t, mgr, mdb)
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	ac := readAutomationConfig(t, mgr, mdb)
+	assert.Len(t, ac.ReplicaSets[0].Members, 5)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.AnalyticsMembers.Members = 0
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+	inOrder, err = r.shouldRunInOrder(mdb)
+	assert.NoError(t, err)
+	assert.True(t, inOrder, "the automation config is scaled down before the StatefulSet")
+
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	makeAnalyticsStatefulSetReady(t, mgr, mdb)
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	ac = readAutomationConfig(t, mgr, mdb)
+	assert.Len(t, ac.ReplicaSets[0].Members, 3)
+	sts, err := mgr.Client.GetStatefulSet(mdb.AnalyticsNamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), *sts.Spec.Replicas)
+}
+
+func TestAnalyticsMembers_DontUseTheStatefulSetOfTheMembers(t *testing.T) {
+	mdb := newAnalyticsReplicaSet()
+	mdb.Spec.AnalyticsMembers.NodeSelector = nil
+	mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.Template.Spec.NodeSelector = map[string]string{"disktype": "ssd"}
+	mgr := client.NewManager(&mdb)
+	_ = reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"disktype": "ssd"}, sts.Spec.Template.Spec.NodeSelector)
+
+	analytics, err := mgr.Client.GetStatefulSet(mdb.AnalyticsNamespacedName())
+	assert.NoError(t, err)
+	assert.Empty(t, analytics.Spec.Template.Spec.NodeSelector)
+}
+
+// makeAnalyticsStatefulSetReady sets the ready replicas of the StatefulSet of the analytics members, which the
+// mocked client only does when it is created.
+func makeAnalyticsStatefulSetReady(t *testing.T, mgr *client.MockedManager, mdb mdbv1.MongoDBCommunity) {
+	sts, err := mgr.Client.GetStatefulSet(mdb.AnalyticsNamespacedName())
+	assert.NoError(t, err)
+	sts.Status.ReadyReplicas = int32(mdb.Spec.AnalyticsMembers.Members)
+	sts.Status.UpdatedReplicas = int32(mdb.Spec.AnalyticsMembers.Members)
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &sts))
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/persistentvolumeclaim"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/resourcerequirements"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/statefulset"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// lightweightVolumeSize is the size of the single volume of the arbiters, which hold no data.
+const lightweightVolumeSize = "1G"
+
+// componentStatefulSetOwner is the MongoDBCommunity resource seen as the owner of one of its additional StatefulSets,
+// for the arbiters, the analytics members or the components of a sharded cluster.
+type componentStatefulSetOwner struct {
+	mdbv1.MongoDBCommunity
+	nsName types.NamespacedName
+	// singleVolume stores the logs in the data volume, for the components which hold no data.
+	singleVolume bool
+}
+
+func (c *componentStatefulSetOwner) GetName() string {
+	return c.nsName.Name
+}
+
+func (c componentStatefulSetOwner) NamespacedName() types.NamespacedName {
+	return c.nsName
+}
+
+func (c componentStatefulSetOwner) HasSeparateDataAndLogsVolumes() bool {
+	return !c.singleVolume && c.MongoDBCommunity.HasSeparateDataAndLogsVolumes()
+}
+
+// fixedReplicasScaler sets the replicas of a StatefulSet which are already scaled by the caller.
+type fixedReplicasScaler struct {
+	replicas int
+}
+
+func (f fixedReplicasScaler) DesiredReplicas() int {
+	return f.replicas
+}
+
+func (f fixedReplicasScaler) CurrentReplicas() int {
+	return f.replicas
+}
+
+// buildArbitersStatefulSetModificationFunction builds the StatefulSet of the arbiters. spec.statefulSet is not applied,
+// as its resources, affinity and volumes are sized for the members which hold data.
+func buildArbitersStatefulSetModificationFunction(mdb mdbv1.MongoDBCommunity) statefulset.Modification {
+	commonModification := construct.BuildMongoDBReplicaSetStatefulSetModificationFunction(
+		&componentStatefulSetOwner{MongoDBCommunity: mdb, nsName: mdb.ArbitersNamespacedName(), singleVolume: true},
+		fixedReplicasScaler{replicas: mdb.StatefulSetArbitersThisReconciliation()},
+	)
+	return withComponentLabel(componentArbiter, statefulset.Apply(
+		commonModification,
+		statefulset.WithOwnerReference([]metav1.OwnerReference{getOwnerReference(mdb)}),
+		statefulset.WithPodSpecTemplate(
+			podtemplatespec.Apply(
+				buildTLSPodSpecModification(mdb),
+				buildClusterAuthPodSpecModification(mdb),
+			),
+		),
+		statefulset.WithVolumeClaim(mdb.DataVolumeName(),
+			persistentvolumeclaim.WithResourceRequests(resourcerequirements.BuildStorageRequirements(lightweightVolumeSize)),
+		),
+	))
+}
+
+// createOrUpdateArbitersStatefulSet creates the StatefulSet of the arbiters once some are requested, and
+// keeps it afterwards so that they can be scaled down.
+func (r *ReplicaSetReconciler) createOrUpdateArbitersStatefulSet(mdb mdbv1.MongoDBCommunity, ca string) error {
+	set := appsv1.StatefulSet{}
+	err := r.client.Get(context.TODO(), mdb.ArbitersNamespacedName(), &set)
+	if apiErrors.IsNotFound(err) && mdb.StatefulSetArbitersThisReconciliation() == 0 {
+		return nil
+	}
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return errors.Errorf("error getting the StatefulSet of the arbiters: %s", err)
+	}
+	buildArbitersStatefulSetModificationFunction(mdb)(&set)
+	withCAHashAnnotation(mdb, ca, err == nil)(&set)
+	if _, err = statefulset.CreateOrUpdate(r.client, set); err != nil {
+		return errors.Errorf("error creating/updating the StatefulSet of the arbiters: %s", err)
+	}
+	return nil
+}
+
+// resetUpdateStrategy resets the UpdateStrategy of the StatefulSets of the members, of the arbiters and of the
+// analytics members once the version change is complete.
+func (r *ReplicaSetReconciler) resetUpdateStrategy(mdb mdbv1.MongoDBCommunity) error {
+	if mdb.IsShardedCluster() {
+		return r.resetShardedClusterUpdateStrategy(mdb)
+	}
+	if err := statefulset.ResetUpdateStrategy(&mdb, r.client); err != nil {
+		return err
+	}
+	if err := statefulset.ResetUpdateStrategy(&componentStatefulSetOwner{MongoDBCommunity: mdb, nsName: mdb.ArbitersNamespacedName()}, r.client); err != nil && !apiErrors.IsNotFound(err) {
+		return err
+	}
+	if err := statefulset.ResetUpdateStrategy(&componentStatefulSetOwner{MongoDBCommunity: mdb, nsName: mdb.AnalyticsNamespacedName()}, r.client); err != nil && !apiErrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// isArbitersStatefulSetReady returns true if the StatefulSet of the arbiters, when it exists, has the expected number
+// of ready replicas.
+func (r *ReplicaSetReconciler) isArbitersStatefulSetReady(mdb mdbv1.MongoDBCommunity) (bool, error) {
+	sts, err := r.client.GetStatefulSet(mdb.ArbitersNamespacedName())
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, errors.Errorf("error getting the StatefulSet of the arbiters: %s", err)
+	}
+	return statefulset.IsReady(sts, mdb.StatefulSetArbitersThisReconciliation()) || sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType, nil
+}
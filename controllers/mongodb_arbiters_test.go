@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestArbitersThisReconciliation(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Arbiters = 2
+	assert.Equal(t, 2, mdb.AutomationConfigArbitersThisReconciliation(), "a new replica set is created with all of its arbiters")
+
+	mdb.Status.CurrentMongoDBMembers = 3
+	assert.Equal(t, 1, mdb.AutomationConfigArbitersThisReconciliation(), "arbiters are added one at a time")
+
+	mdb.Status.CurrentMongoDBArbiters = 1
+	mdb.Spec.Members = 4
+	assert.Equal(t, 1, mdb.AutomationConfigArbitersThisReconciliation(), "arbiters wait for the members to be scaled")
+	assert.True(t, mdb.IsStillScaling())
+
+	mdb.Spec.Members = 3
+	mdb.Spec.Arbiters = 0
+	assert.Equal(t, 0, mdb.AutomationConfigArbitersThisReconciliation())
+}
+
+func TestReplicaSet_IsCreatedWithArbiters(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Arbiters = 1
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Len(t, ac.Processes, 4)
+	assert.Equal(t, mdb.Name+"-arb-0", ac.Processes[3].Name)
+
+	members := ac.ReplicaSets[0].Members
+	assert.Len(t, members, 4)
+	for i := 0; i < 3; i++ {
+		assert.False(t, members[i].ArbiterOnly)
+	}
+	assert.True(t, members[3].ArbiterOnly)
+	assert.Equal(t, 1, members[3].Votes)
+	assert.Equal(t, 0.0, float64(members[3].Priority))
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.ArbitersNamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *sts.Spec.Replicas)
+	assert.Equal(t, mdb.ServiceName(), sts.Spec.ServiceName)
+	assert.Len(t, sts.Spec.VolumeClaimTemplates, 1)
+	storage := sts.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[corev1.ResourceStorage]
+	assert.Equal(t, lightweightVolumeSize, storage.String())
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+	assert.Equal(t, 1, mdb.Status.CurrentMongoDBArbiters)
+	assert.Equal(t, 1, mdb.Status.CurrentStatefulSetArbitersReplicas)
+}
+
+func TestReplicaSet_ArbitersAreScaledUp_OneAtATime(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	// the StatefulSet of the arbiters is only created once some are requested
+	_, err = mgr.Client.GetStatefulSet(mdb.ArbitersNamespacedName())
+	assert.True(t, apiErrors.IsNotFound(err))
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.Arbiters = 2
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+	// the StatefulSet is created with the first arbiter, which is added to the replica set once it is ready.
+	// The mocked client creates StatefulSets ready.
+	mdb = reconcileArbitersPending(t, r, mgr, mdb, 1)
+	sts, err := mgr.Client.GetStatefulSet(mdb.ArbitersNamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *sts.Spec.Replicas)
+	assert.Len(t, readAutomationConfig(t, mgr, mdb).ReplicaSets[0].Members, 4)
+
+	// then the second one
+	reconcileArbitersPending(t, r, mgr, mdb, 1)
+	makeArbitersStatefulSetReady(t, mgr.GetClient(), mdb)
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+	assert.Equal(t, 2, mdb.Status.CurrentMongoDBArbiters)
+	assert.Len(t, readAutomationConfig(t, mgr, mdb).ReplicaSets[0].Members, 5)
+}
+
+func TestArbiters_DontUseTheStatefulSetOfTheMembers(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Arbiters = 1
+	mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.Template.Spec.NodeSelector = map[string]string{"disktype": "ssd"}
+	mgr := client.NewManager(&mdb)
+	_ = reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"disktype": "ssd"}, sts.Spec.Template.Spec.NodeSelector)
+
+	arbiters, err := mgr.Client.GetStatefulSet(mdb.ArbitersNamespacedName())
+	assert.NoError(t, err)
+	assert.Empty(t, arbiters.Spec.Template.Spec.NodeSelector)
+}
+
+func TestArbiters_MustBeFewerThanMembers(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Arbiters = 3
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "the number of arbiters (3) must be lower than the number of members (3)")
+}
+
+func makeArbitersStatefulSetReady(t *testing.T, c k8sClient.Client, mdb mdbv1.MongoDBCommunity) {
+	makeComponentStatefulSetReady(t, c, mdb.ArbitersNamespacedName())
+}
+
+// makeComponentStatefulSetReady marks all the replicas of one of the additional StatefulSets as ready.
+func makeComponentStatefulSetReady(t *testing.T, c k8sClient.Client, nsName types.NamespacedName) {
+	sts := appsv1.StatefulSet{}
+	assert.NoError(t, c.Get(context.TODO(), nsName, &sts))
+	sts.Status.ReadyReplicas = *sts.Spec.Replicas
+	sts.Status.UpdatedReplicas = *sts.Spec.Replicas
+	assert.NoError(t, c.Update(context.TODO(), &sts))
+}
+
+func reconcileArbitersPending(t *testing.T, r *ReplicaSetReconciler, mgr *client.MockedManager, mdb mdbv1.MongoDBCommunity, expectedArbiters int) mdbv1.MongoDBCommunity {
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+	assert.Equal(t, expectedArbiters, mdb.Status.CurrentMongoDBArbiters)
+	return mdb
+}
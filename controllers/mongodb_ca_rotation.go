@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/statefulset"
+)
+
+const (
+	// caHashAnnotation is set on the pod template so that a change of the CA restarts the members,
+	// as mongod only reads the CA file on startup.
+	caHashAnnotation = "mongodb.com/v1.caHash"
+)
+
+// readCA returns the CA certificates configured for the resource, or an empty string if TLS is not in use.
+func readCA(getter caGetter, mdb mdbv1.MongoDBCommunity) (string, error) {
+	if !isTLSInUse(mdb) {
+		return "", nil
+	}
+	caData, err := readCAData(getter, mdb)
+	if err != nil {
+		return "", err
+	}
+	return caData[mdb.Spec.Security.TLS.CAKey()], nil
+}
+
+// caHash returns the hash of the given CA, or an empty string if there is no CA.
+func caHash(ca string) string {
+	if ca == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(ca))
+	return fmt.Sprintf("%x", hash)
+}
+
+// countCertificates returns the number of PEM encoded certificates in the given bundle.
+// Content which is not PEM encoded is considered a single certificate.
+func countCertificates(bundle string) int {
+	count := 0
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			count++
+		}
+	}
+	if count == 0 && bundle != "" {
+		return 1
+	}
+	return count
+}
+
+// getCARotationStage returns the stage of the CA rotation while the members are rolled out with the given CA.
+func getCARotationStage(mdb mdbv1.MongoDBCommunity, ca string) mdbv1.CARotationStage {
+	rolledOut := mdb.Status.TLS.CAHash == caHash(ca)
+	if countCertificates(ca) > 1 {
+		if rolledOut {
+			return mdbv1.CARotationStageServerCertificate
+		}
+		return mdbv1.CARotationStageBundleRollout
+	}
+	previousStage := mdb.Status.TLS.CARotationStage
+	if !rolledOut && (previousStage == mdbv1.CARotationStageServerCertificate || previousStage == mdbv1.CARotationStageOldCARemoval) {
+		return mdbv1.CARotationStageOldCARemoval
+	}
+	return ""
+}
+
+// completedCARotationStage returns the stage of the CA rotation once the members have been rolled out with the given CA.
+func completedCARotationStage(ca string) mdbv1.CARotationStage {
+	if countCertificates(ca) > 1 {
+		return mdbv1.CARotationStageServerCertificate
+	}
+	return ""
+}
+
+// verifyServerCertificate checks that the server certificate is signed by one of the CAs in the bundle.
+// Certificates which can't be parsed are not verified, mongod will reject them on startup.
+func verifyServerCertificate(ca, cert string) error {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(ca)) {
+		return nil
+	}
+	block, _ := pem.Decode([]byte(cert))
+	if block == nil {
+		return nil
+	}
+	serverCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil
+	}
+
+	if _, err := serverCert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+		return errors.Errorf("server certificate is not signed by the CA: %s", err)
+	}
+	return nil
+}
+
+// withCAHashAnnotation sets the hash of the CA on the pod template, which restarts the members when the CA changes.
+// A StatefulSet created by a previous version of the operator, which didn't set the annotation, only gets it once the
+// CA differs from the one recorded in the status, so that upgrading the operator doesn't restart the members.
+func withCAHashAnnotation(mdb mdbv1.MongoDBCommunity, ca string, exists bool) statefulset.Modification {
+	hash := caHash(ca)
+	return statefulset.WithPodSpecTemplate(func(podTemplateSpec *corev1.PodTemplateSpec) {
+		if hash == "" {
+			delete(podTemplateSpec.Annotations, caHashAnnotation)
+			return
+		}
+		if _, ok := podTemplateSpec.Annotations[caHashAnnotation]; exists && !ok && !isCAChanged(mdb, hash) {
+			return
+		}
+		if podTemplateSpec.Annotations == nil {
+			podTemplateSpec.Annotations = map[string]string{}
+		}
+		podTemplateSpec.Annotations[caHashAnnotation] = hash
+	})
+}
+
+// isCAChanged returns true if the members were last rolled out with a CA other than the one of the given hash.
+func isCAChanged(mdb mdbv1.MongoDBCommunity, hash string) bool {
+	return mdb.Status.TLS.CAHash != "" && mdb.Status.TLS.CAHash != hash
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/configmap"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestCountCertificates(t *testing.T) {
+	ca, _ := generateCertificate(t, "ca", nil, nil)
+	otherCA, _ := generateCertificate(t, "other-ca", nil, nil)
+
+	assert.Equal(t, 0, countCertificates(""))
+	assert.Equal(t, 1, countCertificates("CERT"))
+	assert.Equal(t, 1, countCertificates(ca))
+	assert.Equal(t, 2, countCertificates(ca+otherCA))
+}
+
+func TestGetCARotationStage(t *testing.T) {
+	oldCA, _ := generateCertificate(t, "old-ca", nil, nil)
+	newCA, _ := generateCertificate(t, "new-ca", nil, nil)
+	bundle := oldCA + newCA
+
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Status.TLS.CAHash = caHash(oldCA)
+	assert.Equal(t, mdbv1.CARotationStage(""), getCARotationStage(mdb, oldCA))
+
+	// the bundle with both CAs is rolled out
+	assert.Equal(t, mdbv1.CARotationStageBundleRollout, getCARotationStage(mdb, bundle))
+	mdb.Status.TLS.CAHash = caHash(bundle)
+	mdb.Status.TLS.CARotationStage = completedCARotationStage(bundle)
+	assert.Equal(t, mdbv1.CARotationStageServerCertificate, getCARotationStage(mdb, bundle))
+
+	// the old CA is dropped from the bundle
+	assert.Equal(t, mdbv1.CARotationStageOldCARemoval, getCARotationStage(mdb, newCA))
+	mdb.Status.TLS.CARotationStage = mdbv1.CARotationStageOldCARemoval
+	assert.Equal(t, mdbv1.CARotationStageOldCARemoval, getCARotationStage(mdb, newCA))
+
+	mdb.Status.TLS.CAHash = caHash(newCA)
+	mdb.Status.TLS.CARotationStage = completedCARotationStage(newCA)
+	assert.Equal(t, mdbv1.CARotationStage(""), getCARotationStage(mdb, newCA))
+}
+
+func TestVerifyServerCertificate(t *testing.T) {
+	oldCA, oldCAKey := generateCertificate(t, "old-ca", nil, nil)
+	newCA, _ := generateCertificate(t, "new-ca", nil, nil)
+	serverCert, _ := generateCertificate(t, "server", parseCertificate(t, oldCA), oldCAKey)
+
+	assert.NoError(t, verifyServerCertificate(oldCA, serverCert))
+	assert.NoError(t, verifyServerCertificate(oldCA+newCA, serverCert))
+	assert.Error(t, verifyServerCertificate(newCA, serverCert))
+
+	// certificates which can't be parsed are left to mongod
+	assert.NoError(t, verifyServerCertificate("CERT", serverCert))
+	assert.NoError(t, verifyServerCertificate(newCA, "CERT"))
+}
+
+func TestReplicaSet_IsRolledOutWithCAHash(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, caHash("CERT"), sts.Spec.Template.Annotations[caHashAnnotation])
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, caHash("CERT"), mdb.Status.TLS.CAHash)
+	assert.Equal(t, mdbv1.CARotationStage(""), mdb.Status.TLS.CARotationStage)
+
+	// replacing the CA changes the pod template, which restarts the members
+	assert.NoError(t, configmap.UpdateField(mgr.Client, mdb.TLSConfigMapNamespacedName(), tlsCACertName, "CERT\nOTHER-CERT"))
+	assert.NoError(t, r.createOrUpdateStatefulSet(mdb))
+
+	sts, err = mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, caHash("CERT\nOTHER-CERT"), sts.Spec.Template.Annotations[caHashAnnotation])
+}
+
+func TestReplicaSet_OfPreviousOperatorIsNotRestartedUntilTheCAChanges(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+
+	// previous versions of the operator didn't set the annotation, nor the hash in the status
+	r := NewReconciler(mgr)
+	assert.NoError(t, r.createOrUpdateStatefulSet(mdb))
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	delete(sts.Spec.Template.Annotations, caHashAnnotation)
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &sts))
+
+	for i := 0; i < 2; i++ {
+		makeStatefulSetReady(t, mgr.GetClient(), mdb)
+		res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assertReconciliationSuccessful(t, res, err)
+
+		sts, err = mgr.Client.GetStatefulSet(mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.NotContains(t, sts.Spec.Template.Annotations, caHashAnnotation)
+	}
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, caHash("CERT"), mdb.Status.TLS.CAHash)
+
+	assert.NoError(t, configmap.UpdateField(mgr.Client, mdb.TLSConfigMapNamespacedName(), tlsCACertName, "CERT\nOTHER-CERT"))
+	assert.NoError(t, r.createOrUpdateStatefulSet(mdb))
+
+	sts, err = mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, caHash("CERT\nOTHER-CERT"), sts.Spec.Template.Annotations[caHashAnnotation])
+}
+
+func TestReplicaSet_OldCAIsNotRemovedBeforeServerCertificateIsReplaced(t *testing.T) {
+	oldCA, oldCAKey := generateCertificate(t, "old-ca", nil, nil)
+	newCA, newCAKey := generateCertificate(t, "new-ca", nil, nil)
+	serverCert, _ := generateCertificate(t, "server", parseCertificate(t, oldCA), oldCAKey)
+
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Status.TLS.CAHash = caHash(oldCA + newCA)
+	mdb.Status.TLS.CARotationStage = mdbv1.CARotationStageServerCertificate
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+	assert.NoError(t, configmap.UpdateField(mgr.Client, mdb.TLSConfigMapNamespacedName(), tlsCACertName, newCA))
+	s, err := mgr.Client.GetSecret(mdb.TLSSecretNamespacedName())
+	assert.NoError(t, err)
+	s.Data[tlsSecretCertName] = []byte(serverCert)
+	assert.NoError(t, mgr.Client.UpdateSecret(s))
+
+	r := NewReconciler(mgr)
+	ok, err := r.validateTLSConfig(mdb)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// once the server certificate is signed by the new CA, the old one can be removed
+	serverCert, _ = generateCertificate(t, "server", parseCertificate(t, newCA), newCAKey)
+	s.Data[tlsSecretCertName] = []byte(serverCert)
+	assert.NoError(t, mgr.Client.UpdateSecret(s))
+
+	ok, err = r.validateTLSConfig(mdb)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+// generateCertificate creates a PEM encoded certificate for the given DNS names, signed by the given parent or self-signed if there is none.
+func generateCertificate(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, dnsNames ...string) (string, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+		DNSNames:              dnsNames,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), key
+}
+
+func parseCertificate(t *testing.T, certificate string) *x509.Certificate {
+	block, _ := pem.Decode([]byte(certificate))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.NoError(t, err)
+	return cert
+}
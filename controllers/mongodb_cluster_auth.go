@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"github.com/pkg/errors"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/statefulset"
+)
+
+const (
+	clusterAuthModeArg             = "security.clusterAuthMode"
+	clusterFileArg                 = "net.tls.clusterFile"
+	clusterOperatorSecretMountPath = "/var/lib/tls/cluster/" //nolint
+)
+
+// validateClusterAuthConfig will check that the Secret holding the member certificate exists
+// and has the expected fields when x509 cluster authentication is configured.
+func (r *ReplicaSetReconciler) validateClusterAuthConfig(mdb mdbv1.MongoDBCommunity) (bool, error) {
+	if !mdb.HasClusterCertificate() {
+		return true, nil
+	}
+
+	r.log.Info("Ensuring x509 cluster authentication is correctly configured")
+
+	secretData, err := secret.ReadStringData(r.client, mdb.ClusterCertificateSecretNamespacedName())
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			r.log.Warnf(`Secret "%s" not found`, mdb.ClusterCertificateSecretNamespacedName())
+			return false, nil
+		}
+		return false, err
+	}
+
+	tls := mdb.Spec.Security.TLS
+	if key, ok := secretData[tls.PrivateKeyKey()]; !ok || key == "" {
+		r.log.Warnf(`Secret "%s" should have a key in field "%s"`, mdb.ClusterCertificateSecretNamespacedName(), tls.PrivateKeyKey())
+		return false, nil
+	}
+	if cert, ok := secretData[tls.CertificateKey()]; !ok || cert == "" {
+		r.log.Warnf(`Secret "%s" should have a certificate in field "%s"`, mdb.ClusterCertificateSecretNamespacedName(), tls.CertificateKey())
+		return false, nil
+	}
+
+	// Watch the member certificate secret to handle rotations
+	r.secretWatcher.Watch(mdb.ClusterCertificateSecretNamespacedName(), mdb.NamespacedName())
+
+	r.log.Infof("Successfully validated x509 cluster authentication config")
+	return true, nil
+}
+
+// ensureClusterAuthSecret will create or update the operator-managed Secret containing
+// the concatenated member certificate and key from the user-provided Secret.
+func ensureClusterAuthSecret(getUpdateCreator secret.GetUpdateCreator, mdb mdbv1.MongoDBCommunity) error {
+	certKey, err := getClusterCertAndKey(getUpdateCreator, mdb)
+	if err != nil {
+		return errors.Errorf("could not get cluster cert and key: %s", err)
+	}
+
+	operatorSecret := secret.Builder().
+		SetName(mdb.ClusterOperatorSecretNamespacedName().Name).
+		SetNamespace(mdb.ClusterOperatorSecretNamespacedName().Namespace).
+		SetField(tlsOperatorSecretFileName(certKey), certKey).
+		SetOwnerReferences([]metav1.OwnerReference{getOwnerReference(mdb)}).
+		Build()
+
+	return secret.CreateOrUpdate(getUpdateCreator, operatorSecret)
+}
+
+// getClusterCertAndKey will fetch the member certificate and key from the user-provided Secret. They are stored under
+// the same keys as the server certificate.
+func getClusterCertAndKey(getter secret.Getter, mdb mdbv1.MongoDBCommunity) (string, error) {
+	cert, err := secret.ReadKey(getter, mdb.Spec.Security.TLS.CertificateKey(), mdb.ClusterCertificateSecretNamespacedName())
+	if err != nil {
+		return "", err
+	}
+
+	key, err := secret.ReadKey(getter, mdb.Spec.Security.TLS.PrivateKeyKey(), mdb.ClusterCertificateSecretNamespacedName())
+	if err != nil {
+		return "", err
+	}
+
+	return combineCertificateAndKey(cert, key), nil
+}
+
+// currentClusterAuthMode returns the cluster authentication mode the given automation config is configured with.
+// Processes without an explicit mode use keyFile, which is the mongod default.
+func currentClusterAuthMode(ac automationconfig.AutomationConfig) mdbv1.ClusterAuthMode {
+	if len(ac.Processes) == 0 {
+		return mdbv1.ClusterAuthModeKeyFile
+	}
+	mode := ac.Processes[0].Args26.Get(clusterAuthModeArg).Str()
+	if mode == "" {
+		return mdbv1.ClusterAuthModeKeyFile
+	}
+	return mdbv1.ClusterAuthMode(mode)
+}
+
+// nextClusterAuthMode returns the cluster authentication mode that should be configured in this
+// reconciliation in order to move from the current to the desired mode.
+func nextClusterAuthMode(current, desired mdbv1.ClusterAuthMode) mdbv1.ClusterAuthMode {
+	order := make([]string, len(mdbv1.ClusterAuthModeTransition))
+	for i, mode := range mdbv1.ClusterAuthModeTransition {
+		order[i] = string(mode)
+	}
+	return mdbv1.ClusterAuthMode(nextStep(order, string(current), string(desired)))
+}
+
+// getClusterAuthModification creates a modification function which configures the cluster authentication
+// mode for the next step of the transition towards the desired mode.
+func getClusterAuthModification(getter secret.Getter, mdb mdbv1.MongoDBCommunity, currentAC automationconfig.AutomationConfig) (automationconfig.Modification, error) {
+	current := currentClusterAuthMode(currentAC)
+	next := nextClusterAuthMode(current, mdb.GetClusterAuthMode())
+
+	// a new deployment has no members which need to keep talking to each other during the
+	// transition, so it can use the desired mode straight away.
+	if len(currentAC.Processes) == 0 {
+		next = mdb.GetClusterAuthMode()
+	}
+
+	// keyFile is the default, so a deployment which has never been configured otherwise is left untouched.
+	if next == mdbv1.ClusterAuthModeKeyFile && current == mdbv1.ClusterAuthModeKeyFile {
+		return automationconfig.NOOP(), nil
+	}
+
+	clusterFilePath := ""
+	if next.UsesX509() {
+		certKey, err := getClusterCertAndKey(getter, mdb)
+		if err != nil {
+			return automationconfig.NOOP(), err
+		}
+		clusterFilePath = clusterOperatorSecretMountPath + tlsOperatorSecretFileName(certKey)
+	}
+
+	return clusterAuthModification(next, clusterFilePath), nil
+}
+
+// clusterAuthModification sets the cluster authentication mode, and the member certificate if required, on every process.
+func clusterAuthModification(mode mdbv1.ClusterAuthMode, clusterFilePath string) automationconfig.Modification {
+	return func(config *automationconfig.AutomationConfig) {
+		for i := range config.Processes {
+			config.Processes[i].SetArgs26Field(clusterAuthModeArg, string(mode))
+			if clusterFilePath != "" {
+				config.Processes[i].SetArgs26Field(clusterFileArg, clusterFilePath)
+			}
+		}
+	}
+}
+
+// isClusterAuthTransitionComplete returns true if the given automation config has the desired cluster authentication mode.
+func isClusterAuthTransitionComplete(mdb mdbv1.MongoDBCommunity, ac automationconfig.AutomationConfig) bool {
+	return currentClusterAuthMode(ac) == mdb.GetClusterAuthMode()
+}
+
+// buildClusterAuthPodSpecModification will add the member certificate volume to the pod template
+// if a member certificate is configured. The volume is kept while transitioning back to keyFile,
+// as the processes keep referencing the certificate until the transition is complete.
+func buildClusterAuthPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
+	if !mdb.HasClusterCertificate() {
+		return podtemplatespec.NOOP()
+	}
+
+	clusterSecretVolume := statefulset.CreateVolumeFromSecret("cluster-secret", mdb.ClusterOperatorSecretNamespacedName().Name)
+	clusterSecretVolumeMount := statefulset.CreateVolumeMount(clusterSecretVolume.Name, clusterOperatorSecretMountPath, statefulset.WithReadOnly(true))
+
+	return podtemplatespec.Apply(
+		podtemplatespec.WithVolume(clusterSecretVolume),
+		podtemplatespec.WithVolumeMounts(construct.AgentName, clusterSecretVolumeMount),
+		podtemplatespec.WithVolumeMounts(construct.MongodbName, clusterSecretVolumeMount),
+	)
+}
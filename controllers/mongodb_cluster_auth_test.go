@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	mdbClient "github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/configmap"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	"github.com/stretchr/testify/assert"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestReplicaSetWithX509ClusterAuth() mdbv1.MongoDBCommunity {
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Spec.Security.Authentication.InternalCluster = mdbv1.InternalClusterAuthentication{
+		Mode: mdbv1.ClusterAuthModeX509,
+		CertificateKeySecret: mdbv1.LocalObjectReference{
+			Name: "clusterCertificateKeySecret",
+		},
+	}
+	return mdb
+}
+
+func TestNextClusterAuthMode(t *testing.T) {
+	tests := []struct {
+		current  mdbv1.ClusterAuthMode
+		desired  mdbv1.ClusterAuthMode
+		expected mdbv1.ClusterAuthMode
+	}{
+		{mdbv1.ClusterAuthModeKeyFile, mdbv1.ClusterAuthModeKeyFile, mdbv1.ClusterAuthModeKeyFile},
+		{mdbv1.ClusterAuthModeKeyFile, mdbv1.ClusterAuthModeX509, mdbv1.ClusterAuthModeSendKeyFile},
+		{mdbv1.ClusterAuthModeSendKeyFile, mdbv1.ClusterAuthModeX509, mdbv1.ClusterAuthModeSendX509},
+		{mdbv1.ClusterAuthModeSendX509, mdbv1.ClusterAuthModeX509, mdbv1.ClusterAuthModeX509},
+		{mdbv1.ClusterAuthModeX509, mdbv1.ClusterAuthModeKeyFile, mdbv1.ClusterAuthModeSendX509},
+		{mdbv1.ClusterAuthModeSendKeyFile, mdbv1.ClusterAuthModeKeyFile, mdbv1.ClusterAuthModeKeyFile},
+		{"unknown", mdbv1.ClusterAuthModeX509, mdbv1.ClusterAuthModeX509},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, nextClusterAuthMode(test.current, test.desired))
+	}
+}
+
+func TestClusterAuthModification_WalksThroughTransition(t *testing.T) {
+	mdb := newTestReplicaSetWithX509ClusterAuth()
+	c := mdbClient.NewClient(client.NewManager(&mdb).GetClient())
+	assert.NoError(t, createTLSSecretAndConfigMap(c, mdb))
+	assert.NoError(t, createClusterCertificateSecret(c, mdb))
+
+	// start from an existing deployment which uses the default keyFile mode
+	previousAC, err := buildAutomationConfig(newTestReplicaSetWithTLS(), automationconfig.Auth{}, automationconfig.AutomationConfig{})
+	assert.NoError(t, err)
+
+	expectedModes := []mdbv1.ClusterAuthMode{
+		mdbv1.ClusterAuthModeSendKeyFile,
+		mdbv1.ClusterAuthModeSendX509,
+		mdbv1.ClusterAuthModeX509,
+		mdbv1.ClusterAuthModeX509,
+	}
+	for _, expectedMode := range expectedModes {
+		modification, err := getClusterAuthModification(c, mdb, previousAC)
+		assert.NoError(t, err)
+
+		ac, err := buildAutomationConfig(mdb, automationconfig.Auth{}, previousAC, modification)
+		assert.NoError(t, err)
+
+		for _, process := range ac.Processes {
+			assert.Equal(t, string(expectedMode), process.Args26.Get(clusterAuthModeArg).Str())
+			assert.Equal(t, clusterOperatorSecretMountPath+tlsOperatorSecretFileName("CLUSTER-CERT\nCLUSTER-KEY"), process.Args26.Get(clusterFileArg).Str())
+		}
+		previousAC = ac
+	}
+	assert.True(t, isClusterAuthTransitionComplete(mdb, previousAC))
+}
+
+func TestClusterAuthModification_NewDeploymentUsesDesiredMode(t *testing.T) {
+	mdb := newTestReplicaSetWithX509ClusterAuth()
+	c := mdbClient.NewClient(client.NewManager(&mdb).GetClient())
+	assert.NoError(t, createClusterCertificateSecret(c, mdb))
+
+	modification, err := getClusterAuthModification(c, mdb, automationconfig.AutomationConfig{})
+	assert.NoError(t, err)
+
+	ac, err := buildAutomationConfig(mdb, automationconfig.Auth{}, automationconfig.AutomationConfig{}, modification)
+	assert.NoError(t, err)
+	for _, process := range ac.Processes {
+		assert.Equal(t, string(mdbv1.ClusterAuthModeX509), process.Args26.Get(clusterAuthModeArg).Str())
+	}
+}
+
+func TestClusterAuthModification_KeyFileIsLeftUntouched(t *testing.T) {
+	mdb := newTestReplicaSet()
+	c := mdbClient.NewClient(client.NewManager(&mdb).GetClient())
+
+	modification, err := getClusterAuthModification(c, mdb, automationconfig.AutomationConfig{})
+	assert.NoError(t, err)
+
+	ac, err := buildAutomationConfig(mdb, automationconfig.Auth{}, automationconfig.AutomationConfig{}, modification)
+	assert.NoError(t, err)
+	for _, process := range ac.Processes {
+		assert.False(t, process.Args26.Has(clusterAuthModeArg))
+	}
+}
+
+func TestReplicaSet_IsConfiguredWithX509ClusterAuth(t *testing.T) {
+	mdb := newTestReplicaSetWithX509ClusterAuth()
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+	assert.NoError(t, createClusterCertificateSecret(mgr.GetClient(), mdb))
+
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	certificateKey, err := secret.ReadKey(mgr.Client, tlsOperatorSecretFileName("CLUSTER-CERT\nCLUSTER-KEY"), mdb.ClusterOperatorSecretNamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, "CLUSTER-CERT\nCLUSTER-KEY", certificateKey)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	_, err = getVolumeByName(sts, "cluster-secret")
+	assert.NoError(t, err)
+}
+
+func TestReplicaSet_ClusterAuthUsesConfiguredKeyNames(t *testing.T) {
+	mdb := newTestReplicaSetWithX509ClusterAuth()
+	mdb.Spec.Security.TLS.Keys = mdbv1.TLSKeys{Certificate: "cert.pem", PrivateKey: "key.pem"}
+	mgr := client.NewManager(&mdb)
+	s := secret.Builder().
+		SetName(mdb.Spec.Security.TLS.CertificateKeySecret.Name).
+		SetNamespace(mdb.Namespace).
+		SetField("cert.pem", "CERT").
+		SetField("key.pem", "KEY").
+		Build()
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &s))
+	configMap := configmap.Builder().
+		SetName(mdb.Spec.Security.TLS.CaConfigMap.Name).
+		SetNamespace(mdb.Namespace).
+		SetField("ca.crt", "CA").
+		Build()
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &configMap))
+	assert.NoError(t, createClusterCertificateSecret(mgr.GetClient(), mdb))
+
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	certificateKey, err := secret.ReadKey(mgr.Client, tlsOperatorSecretFileName("CLUSTER-CERT\nCLUSTER-KEY"), mdb.ClusterOperatorSecretNamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, "CLUSTER-CERT\nCLUSTER-KEY", certificateKey)
+}
+
+func TestReplicaSet_ClusterAuthIsPendingWithoutCertificate(t *testing.T) {
+	mdb := newTestReplicaSetWithX509ClusterAuth()
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.True(t, res.RequeueAfter > 0)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+}
+
+func createClusterCertificateSecret(c k8sClient.Client, mdb mdbv1.MongoDBCommunity) error {
+	s := secret.Builder().
+		SetName(mdb.Spec.Security.Authentication.InternalCluster.CertificateKeySecret.Name).
+		SetNamespace(mdb.Namespace).
+		SetField(mdb.Spec.Security.TLS.CertificateKey(), "CLUSTER-CERT").
+		SetField(mdb.Spec.Security.TLS.PrivateKeyKey(), "CLUSTER-KEY").
+		Build()
+	return c.Create(context.TODO(), &s)
+}
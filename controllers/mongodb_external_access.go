@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"fmt"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/service"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// podNameLabel is set by Kubernetes on the pods of a StatefulSet, and selects a single member.
+const podNameLabel = "statefulset.kubernetes.io/pod-name"
+
+// externalServiceNamespacedName returns the name of the Service which exposes the member with the given index.
+func externalServiceNamespacedName(mdb mdbv1.MongoDBCommunity, i int) types.NamespacedName {
+	return types.NamespacedName{Name: fmt.Sprintf("%s-%d-svc-external", mdb.Name, i), Namespace: mdb.Namespace}
+}
+
+// buildExternalService creates the Service which makes the member with the given index reachable from outside of the cluster.
+func buildExternalService(mdb mdbv1.MongoDBCommunity, i, port int) corev1.Service {
+	externalAccess := mdb.Spec.ExternalAccess
+	annotations := map[string]string{}
+	for k, v := range externalAccess.Annotations {
+		annotations[k] = v
+	}
+	nsName := externalServiceNamespacedName(mdb, i)
+	return service.Builder().
+		SetName(nsName.Name).
+		SetNamespace(nsName.Namespace).
+		SetSelector(map[string]string{podNameLabel: fmt.Sprintf("%s-%d", mdb.Name, i)}).
+		SetServiceType(externalAccess.ServiceType()).
+		SetAnnotations(annotations).
+		SetOwnerReferences([]metav1.OwnerReference{getOwnerReference(mdb)}).
+		SetPortName("mongodb").
+		SetPort(int32(port)).
+		SetPublishNotReadyAddresses(true).
+		Build()
+}
+
+// externalAccessMembers returns the number of members which need an external address. The members being removed
+// keep theirs until the scale down is complete, as all the members of the replica set must have the same horizons.
+func externalAccessMembers(mdb mdbv1.MongoDBCommunity) int {
+	if mdb.Status.CurrentMongoDBMembers > mdb.Spec.Members {
+		return mdb.Status.CurrentMongoDBMembers
+	}
+	return mdb.Spec.Members
+}
+
+// ensureExternalServices creates a Service for each member when the external access is enabled, and removes the
+// Services of the members which no longer exist otherwise.
+func (r *ReplicaSetReconciler) ensureExternalServices(mdb mdbv1.MongoDBCommunity, port int) error {
+	members := 0
+	if mdb.Spec.ExternalAccess.Enabled {
+		members = externalAccessMembers(mdb)
+	}
+	for i := 0; i < members; i++ {
+		if err := service.CreateOrUpdate(r.client, buildExternalService(mdb, i, port)); err != nil {
+			return errors.Errorf("error creating/updating the external service of member %d: %s", i, err)
+		}
+	}
+
+	previousMembers := mdb.Status.CurrentStatefulSetReplicas
+	if externalAccessMembers(mdb) > previousMembers {
+		previousMembers = externalAccessMembers(mdb)
+	}
+	for i := members; i < previousMembers; i++ {
+		svc := buildExternalService(mdb, i, port)
+		if err := r.deleteIfExists(&svc); err != nil {
+			return errors.Errorf("error deleting the external service of member %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// getExternalAddresses returns the external address of each member of the replica set. The returned boolean
+// is false if an address has not been allocated yet.
+func (r *ReplicaSetReconciler) getExternalAddresses(mdb mdbv1.MongoDBCommunity) ([]string, bool, error) {
+	addresses := make([]string, externalAccessMembers(mdb))
+	for i := range addresses {
+		svc, err := r.client.GetService(externalServiceNamespacedName(mdb, i))
+		if err != nil {
+			return nil, false, errors.Errorf("error getting the external service of member %d: %s", i, err)
+		}
+		address, ok, err := externalAddress(mdb.Spec.ExternalAccess, svc)
+		if err != nil {
+			return nil, false, errors.Errorf("member %d: %s", i, err)
+		}
+		if !ok {
+			r.log.Debugf("The external address of member %d has not been allocated yet", i)
+			return nil, false, nil
+		}
+		addresses[i] = address
+	}
+	return addresses, true, nil
+}
+
+// externalAddress returns the address the clients reach the member exposed by the Service through.
+// mongod selects the horizon of a client with the SNI of its TLS connection, which can't be an IP address,
+// so a load balancer which only has an IP address is rejected.
+func externalAddress(externalAccess mdbv1.ExternalAccess, svc corev1.Service) (string, bool, error) {
+	if externalAccess.ServiceType() == corev1.ServiceTypeNodePort {
+		if len(svc.Spec.Ports) == 0 || svc.Spec.Ports[0].NodePort == 0 {
+			return "", false, nil
+		}
+		return fmt.Sprintf("%s:%d", externalAccess.NodeHostname, svc.Spec.Ports[0].NodePort), true, nil
+	}
+
+	if len(svc.Spec.Ports) == 0 || len(svc.Status.LoadBalancer.Ingress) == 0 {
+		return "", false, nil
+	}
+	ingress := svc.Status.LoadBalancer.Ingress[0]
+	if ingress.Hostname == "" && ingress.IP != "" {
+		return "", false, errors.Errorf("the load balancer only has the IP address %s, split horizons require a hostname", ingress.IP)
+	}
+	if ingress.Hostname == "" {
+		return "", false, nil
+	}
+	return fmt.Sprintf("%s:%d", ingress.Hostname, svc.Spec.Ports[0].Port), true, nil
+}
+
+// getExternalAccessModification adds the external addresses of the members to their horizons.
+func (r *ReplicaSetReconciler) getExternalAccessModification(mdb mdbv1.MongoDBCommunity) (automationconfig.Modification, error) {
+	if !mdb.Spec.ExternalAccess.Enabled {
+		return automationconfig.NOOP(), nil
+	}
+	addresses, allocated, err := r.getExternalAddresses(mdb)
+	if err != nil {
+		return automationconfig.NOOP(), err
+	}
+	if !allocated {
+		return automationconfig.NOOP(), errors.New("the external addresses of the members have not been allocated yet")
+	}
+	return externalAccessModification(mdb.Spec.ExternalAccess.GetHorizonName(), addresses), nil
+}
+
+// externalAccessModification returns a modification function which adds the external address of each
+// data-bearing member to its horizons, next to the horizons set in the resource.
+func externalAccessModification(horizonName string, addresses []string) automationconfig.Modification {
+	return func(config *automationconfig.AutomationConfig) {
+		for i := range config.ReplicaSets {
+			members := config.ReplicaSets[i].Members
+			for j := range members {
+				if members[j].ArbiterOnly || j >= len(addresses) {
+					continue
+				}
+				horizons := automationconfig.ReplicaSetHorizons{}
+				for name, address := range members[j].Horizons {
+					horizons[name] = address
+				}
+				horizons[horizonName] = addresses[j]
+				members[j].Horizons = horizons
+			}
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newExternalAccessReplicaSet() mdbv1.MongoDBCommunity {
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Spec.ExternalAccess = mdbv1.ExternalAccess{
+		Enabled:     true,
+		Annotations: map[string]string{"service.beta.kubernetes.io/aws-load-balancer-type": "nlb"},
+	}
+	return mdb
+}
+
+func TestExternalAccess_ServicesAreCreated(t *testing.T) {
+	mdb := newExternalAccessReplicaSet()
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	for i := 0; i < mdb.Spec.Members; i++ {
+		svc, err := mgr.Client.GetService(externalServiceNamespacedName(mdb, i))
+		assert.NoError(t, err)
+		assert.Equal(t, corev1.ServiceTypeLoadBalancer, svc.Spec.Type)
+		assert.Equal(t, "nlb", svc.Annotations["service.beta.kubernetes.io/aws-load-balancer-type"])
+		assert.Equal(t, map[string]string{podNameLabel: fmt.Sprintf("%s-%d", mdb.Name, i)}, svc.Spec.Selector)
+		assert.Equal(t, int32(27017), svc.Spec.Ports[0].Port)
+	}
+
+	// the automation config is not created until the addresses are allocated
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "Waiting for the external addresses of the members")
+	_, err = mgr.Client.GetSecret(types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.True(t, apiErrors.IsNotFound(err))
+}
+
+func TestExternalAccess_HorizonsArePopulated(t *testing.T) {
+	mdb := newExternalAccessReplicaSet()
+	mdb.Spec.ReplicaSetHorizons = mdbv1.ReplicaSetHorizonConfiguration{
+		{"internal": "my-rs-0.internal:27017"},
+		{"internal": "my-rs-1.internal:27017"},
+		{"internal": "my-rs-2.internal:27017"},
+	}
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	allocateLoadBalancers(t, mgr, mdb)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	ac := readAutomationConfig(t, mgr, mdb)
+	assert.Equal(t, automationconfig.ReplicaSetHorizons{
+		"internal": "my-rs-1.internal:27017",
+		"external": "lb-1.example.com:27017",
+	}, ac.ReplicaSets[0].Members[1].Horizons)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+}
+
+func TestExternalAccess_NodePort(t *testing.T) {
+	externalAccess := mdbv1.ExternalAccess{Enabled: true, Type: corev1.ServiceTypeNodePort, NodeHostname: "node.example.com"}
+
+	svc := corev1.Service{Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 27017}}}}
+	_, ok, err := externalAddress(externalAccess, svc)
+	assert.NoError(t, err)
+	assert.False(t, ok, "the node port has not been allocated yet")
+
+	svc.Spec.Ports[0].NodePort = 30017
+	address, ok, err := externalAddress(externalAccess, svc)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "node.example.com:30017", address)
+}
+
+func TestExternalAccess_LoadBalancerRequiresAHostname(t *testing.T) {
+	externalAccess := mdbv1.ExternalAccess{Enabled: true}
+	svc := corev1.Service{Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 27017}}}}
+
+	_, ok, err := externalAddress(externalAccess, svc)
+	assert.NoError(t, err)
+	assert.False(t, ok, "the load balancer has not been allocated yet")
+
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "203.0.113.10"}}
+	_, _, err = externalAddress(externalAccess, svc)
+	assert.EqualError(t, err, "the load balancer only has the IP address 203.0.113.10, split horizons require a hostname")
+
+	svc.Status.LoadBalancer.Ingress[0].Hostname = "lb-0.example.com"
+	address, ok, err := externalAddress(externalAccess, svc)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "lb-0.example.com:27017", address)
+}
+
+func TestExternalAccess_FailsWithIPAddresses(t *testing.T) {
+	mdb := newExternalAccessReplicaSet()
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	for i := 0; i < mdb.Spec.Members; i++ {
+		svc, err := mgr.Client.GetService(externalServiceNamespacedName(mdb, i))
+		assert.NoError(t, err)
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: fmt.Sprintf("203.0.113.%d", i)}}
+		assert.NoError(t, mgr.GetClient().Update(context.TODO(), &svc))
+	}
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "member 0: the load balancer only has the IP address 203.0.113.0")
+}
+
+func TestExternalAccess_ServicesAreDeletedWhenDisabled(t *testing.T) {
+	mdb := newExternalAccessReplicaSet()
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.ExternalAccess.Enabled = false
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	for i := 0; i < mdb.Spec.Members; i++ {
+		_, err := mgr.Client.GetService(externalServiceNamespacedName(mdb, i))
+		assert.True(t, apiErrors.IsNotFound(err))
+	}
+}
+
+// allocateLoadBalancers sets the address the cloud provider would allocate to the external service of each member.
+func allocateLoadBalancers(t *testing.T, mgr *client.MockedManager, mdb mdbv1.MongoDBCommunity) {
+	for i := 0; i < mdb.Spec.Members; i++ {
+		svc, err := mgr.Client.GetService(externalServiceNamespacedName(mdb, i))
+		assert.NoError(t, err)
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: fmt.Sprintf("lb-%d.example.com", i)}}
+		assert.NoError(t, mgr.GetClient().Update(context.TODO(), &svc))
+	}
+}
+
+func TestExternalAccess_MustBeCoveredByTheCertificate(t *testing.T) {
+	mdb := newExternalAccessReplicaSet()
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	allocateLoadBalancers(t, mgr, mdb)
+
+	ca, caKey := generateCertificate(t, "ca", nil, nil)
+	serverCert, _ := generateCertificate(t, "server", parseCertificate(t, ca), caKey, "*.my-rs-svc.my-ns.svc.cluster.local", "lb-0.example.com", "lb-1.example.com")
+	s, err := mgr.Client.GetSecret(mdb.TLSSecretNamespacedName())
+	assert.NoError(t, err)
+	s.Data[tlsSecretCertName] = []byte(serverCert)
+	assert.NoError(t, mgr.Client.UpdateSecret(s))
+
+	_, err = r.validateTLSConfig(mdb)
+	assert.EqualError(t, err, `the certificate in Secret "my-ns/certificateKeySecret" can't be used with externalAccess: the certificate doesn't cover the hostnames lb-2.example.com`)
+
+	// a wildcard name covers all the load balancers
+	serverCert, _ = generateCertificate(t, "server", parseCertificate(t, ca), caKey, "*.example.com")
+	s.Data[tlsSecretCertName] = []byte(serverCert)
+	assert.NoError(t, mgr.Client.UpdateSecret(s))
+
+	ok, err := r.validateTLSConfig(mdb)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/generate"
+)
+
+const (
+	// the keyfile and the agent password replacing the current ones are stored next to them
+	// while a rotation is in progress.
+	nextAgentPasswordKey = "nextPassword"
+	nextAgentKeyfileKey  = "nextKeyfile"
+)
+
+// getKeyRotationStage returns the stage of the rotation of the keyfile and of the agent password.
+// A new rotation starts when the rotationId changes, or when the interval has passed since the last rotation.
+func getKeyRotationStage(mdb mdbv1.MongoDBCommunity, now time.Time) mdbv1.KeyRotationStage {
+	if mdb.Status.KeyRotation.Stage != "" {
+		return mdb.Status.KeyRotation.Stage
+	}
+	rotation := mdb.Spec.Security.Authentication.KeyRotation
+	if rotation.RotationID != "" && rotation.RotationID != mdb.Status.KeyRotation.RotationID {
+		return mdbv1.KeyRotationStageKeyfileBundle
+	}
+	if rotation.Interval != nil && !now.Before(lastKeyRotationTime(mdb).Add(rotation.Interval.Duration)) {
+		return mdbv1.KeyRotationStageKeyfileBundle
+	}
+	return ""
+}
+
+// nextScheduledKeyRotation returns the duration until the next scheduled rotation, or 0 if no rotation is scheduled.
+func nextScheduledKeyRotation(mdb mdbv1.MongoDBCommunity, now time.Time) time.Duration {
+	interval := mdb.Spec.Security.Authentication.KeyRotation.Interval
+	if interval == nil {
+		return 0
+	}
+	next := lastKeyRotationTime(mdb).Add(interval.Duration).Sub(now)
+	if next <= 0 {
+		return time.Second
+	}
+	return next
+}
+
+// lastKeyRotationTime returns the time the last rotation completed, or the creation time of the resource
+// if the keys have never been rotated.
+func lastKeyRotationTime(mdb mdbv1.MongoDBCommunity) time.Time {
+	if mdb.Status.KeyRotation.LastRotationTime != nil {
+		return mdb.Status.KeyRotation.LastRotationTime.Time
+	}
+	return mdb.CreationTimestamp.Time
+}
+
+// completedKeyRotation returns the status of the rotation once it has completed.
+func completedKeyRotation(mdb mdbv1.MongoDBCommunity, now time.Time) mdbv1.KeyRotationStatus {
+	completedTime := metav1.NewTime(now)
+	return mdbv1.KeyRotationStatus{
+		RotationID:       mdb.Spec.Security.Authentication.KeyRotation.RotationID,
+		LastRotationTime: &completedTime,
+	}
+}
+
+// getKeyRotationModification returns the modification which rolls out the keyfile and the agent password of the given stage.
+// The next keyfile and agent password are generated when the rotation starts.
+func getKeyRotationModification(secretGetUpdater secret.GetUpdater, mdb mdbv1.MongoDBCommunity, stage mdbv1.KeyRotationStage) (automationconfig.Modification, error) {
+	if stage == "" {
+		return automationconfig.NOOP(), nil
+	}
+
+	keyfileNsName := mdb.GetAgentKeyfileSecretNamespacedName()
+	keyfile, err := secret.ReadKey(secretGetUpdater, scram.AgentKeyfileKey, keyfileNsName)
+	if err != nil {
+		return nil, errors.Errorf("could not read the keyfile: %s", err)
+	}
+	nextKeyfile, err := ensureSecretField(secretGetUpdater, keyfileNsName, nextAgentKeyfileKey, stage, keyfile, generate.KeyFileContents)
+	if err != nil {
+		return nil, errors.Errorf("could not generate the next keyfile: %s", err)
+	}
+
+	passwordNsName := mdb.GetAgentPasswordSecretNamespacedName()
+	password, err := secret.ReadKey(secretGetUpdater, scram.AgentPasswordKey, passwordNsName)
+	if err != nil {
+		return nil, errors.Errorf("could not read the agent password: %s", err)
+	}
+	nextPassword, err := ensureSecretField(secretGetUpdater, passwordNsName, nextAgentPasswordKey, stage, password, func() (string, error) {
+		return generate.RandomFixedLengthStringOfSize(20)
+	})
+	if err != nil {
+		return nil, errors.Errorf("could not generate the next agent password: %s", err)
+	}
+
+	return keyRotationModification(stage, keyfile, nextKeyfile, nextPassword), nil
+}
+
+// keyRotationModification sets the keyfile and the agent password of the given stage of the rotation.
+func keyRotationModification(stage mdbv1.KeyRotationStage, keyfile, nextKeyfile, nextPassword string) automationconfig.Modification {
+	return func(ac *automationconfig.AutomationConfig) {
+		switch stage {
+		case mdbv1.KeyRotationStageKeyfileBundle:
+			ac.Auth.Key = keyfileBundle(keyfile, nextKeyfile)
+		case mdbv1.KeyRotationStageNewCredentials:
+			ac.Auth.Key = nextKeyfile
+			ac.Auth.AutoPwd = nextPassword
+		}
+	}
+}
+
+// keyfileBundle returns a keyfile containing all the given keys, in the YAML format accepted by mongod.
+// The members accept any of the keys when authenticating each other.
+func keyfileBundle(keys ...string) string {
+	bundle := strings.Builder{}
+	for _, key := range keys {
+		bundle.WriteString(fmt.Sprintf("- %s\n", key))
+	}
+	return bundle.String()
+}
+
+// ensureSecretField returns the value of the key in the secret, setting it to a generated value if the key is not present.
+// Once the new credentials are rolled out, a missing key means that the value has already been promoted by completeKeyRotation,
+// and that the status update which followed failed. The current value, which is the rolled out one, is returned then.
+func ensureSecretField(secretGetUpdater secret.GetUpdater, nsName types.NamespacedName, key string, stage mdbv1.KeyRotationStage, current string, generate func() (string, error)) (string, error) {
+	s, err := secretGetUpdater.GetSecret(nsName)
+	if err != nil {
+		return "", err
+	}
+	if value, ok := s.Data[key]; ok {
+		return string(value), nil
+	}
+	if stage == mdbv1.KeyRotationStageNewCredentials {
+		return current, nil
+	}
+	value, err := generate()
+	if err != nil {
+		return "", err
+	}
+	if s.Data == nil {
+		s.Data = map[string][]byte{}
+	}
+	s.Data[key] = []byte(value)
+	if err := secretGetUpdater.UpdateSecret(s); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// completeKeyRotation replaces the keyfile and the agent password by the ones which have been rolled out.
+func completeKeyRotation(secretGetUpdater secret.GetUpdater, mdb mdbv1.MongoDBCommunity) error {
+	if err := promoteSecretField(secretGetUpdater, mdb.GetAgentKeyfileSecretNamespacedName(), nextAgentKeyfileKey, scram.AgentKeyfileKey); err != nil {
+		return errors.Errorf("could not replace the keyfile: %s", err)
+	}
+	if err := promoteSecretField(secretGetUpdater, mdb.GetAgentPasswordSecretNamespacedName(), nextAgentPasswordKey, scram.AgentPasswordKey); err != nil {
+		return errors.Errorf("could not replace the agent password: %s", err)
+	}
+	return nil
+}
+
+// promoteSecretField moves the value of the from key to the to key of the secret, if the from key is present.
+func promoteSecretField(secretGetUpdater secret.GetUpdater, nsName types.NamespacedName, from, to string) error {
+	s, err := secretGetUpdater.GetSecret(nsName)
+	if err != nil {
+		return err
+	}
+	value, ok := s.Data[from]
+	if !ok {
+		return nil
+	}
+	s.Data[to] = value
+	delete(s.Data, from)
+	return secretGetUpdater.UpdateSecret(s)
+}
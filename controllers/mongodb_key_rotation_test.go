@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestGetKeyRotationStage(t *testing.T) {
+	now := time.Now()
+	mdb := newScramReplicaSet()
+	mdb.CreationTimestamp = metav1.NewTime(now.Add(-2 * time.Hour))
+	assert.Equal(t, mdbv1.KeyRotationStage(""), getKeyRotationStage(mdb, now))
+
+	t.Run("A new rotation id starts a rotation", func(t *testing.T) {
+		mdb := mdb
+		mdb.Spec.Security.Authentication.KeyRotation.RotationID = "2024-01"
+		assert.Equal(t, mdbv1.KeyRotationStageKeyfileBundle, getKeyRotationStage(mdb, now))
+
+		mdb.Status.KeyRotation = completedKeyRotation(mdb, now)
+		assert.Equal(t, mdbv1.KeyRotationStage(""), getKeyRotationStage(mdb, now))
+	})
+
+	t.Run("A rotation starts when the interval has passed", func(t *testing.T) {
+		mdb := mdb
+		mdb.Spec.Security.Authentication.KeyRotation.Interval = &metav1.Duration{Duration: time.Hour}
+		assert.Equal(t, mdbv1.KeyRotationStageKeyfileBundle, getKeyRotationStage(mdb, now))
+
+		mdb.Status.KeyRotation = completedKeyRotation(mdb, now.Add(-30*time.Minute))
+		assert.Equal(t, mdbv1.KeyRotationStage(""), getKeyRotationStage(mdb, now))
+		assert.Equal(t, 30*time.Minute, nextScheduledKeyRotation(mdb, now))
+	})
+
+	t.Run("A rotation in progress is continued", func(t *testing.T) {
+		mdb := mdb
+		mdb.Status.KeyRotation.Stage = mdbv1.KeyRotationStageNewCredentials
+		assert.Equal(t, mdbv1.KeyRotationStageNewCredentials, getKeyRotationStage(mdb, now))
+	})
+}
+
+func TestKeyfileBundle(t *testing.T) {
+	assert.Equal(t, "- old-key\n- new-key\n", keyfileBundle("old-key", "new-key"))
+}
+
+func TestReplicaSet_KeyfileAndAgentPasswordAreRotated(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	oldKeyfile, oldPassword := ac.Auth.Key, ac.Auth.AutoPwd
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.Security.Authentication.KeyRotation.RotationID = "2024-01"
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+	// the members first accept both the old and the new keyfile
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Second, res.RequeueAfter)
+
+	newKeyfile, err := secret.ReadKey(mgr.Client, nextAgentKeyfileKey, mdb.GetAgentKeyfileSecretNamespacedName())
+	assert.NoError(t, err)
+	newPassword, err := secret.ReadKey(mgr.Client, nextAgentPasswordKey, mdb.GetAgentPasswordSecretNamespacedName())
+	assert.NoError(t, err)
+
+	ac = readAutomationConfig(t, mgr, mdb)
+	assert.Equal(t, keyfileBundle(oldKeyfile, newKeyfile), ac.Auth.Key)
+	assert.Equal(t, oldPassword, ac.Auth.AutoPwd)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.KeyRotationStageNewCredentials, mdb.Status.KeyRotation.Stage)
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+
+	// once the agents reached goal state, the old keyfile and password are replaced
+	ac = reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Equal(t, newKeyfile, ac.Auth.Key)
+	assert.Equal(t, newPassword, ac.Auth.AutoPwd)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+	assert.Equal(t, mdbv1.KeyRotationStage(""), mdb.Status.KeyRotation.Stage)
+	assert.Equal(t, "2024-01", mdb.Status.KeyRotation.RotationID)
+	assert.NotNil(t, mdb.Status.KeyRotation.LastRotationTime)
+
+	keyfileSecret, err := mgr.Client.GetSecret(mdb.GetAgentKeyfileSecretNamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, newKeyfile, string(keyfileSecret.Data[scram.AgentKeyfileKey]))
+	assert.NotContains(t, keyfileSecret.Data, nextAgentKeyfileKey)
+	passwordSecret, err := mgr.Client.GetSecret(mdb.GetAgentPasswordSecretNamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, newPassword, string(passwordSecret.Data[scram.AgentPasswordKey]))
+	assert.NotContains(t, passwordSecret.Data, nextAgentPasswordKey)
+
+	// the rotated keys are kept by the following reconciliations
+	ac = reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Equal(t, newKeyfile, ac.Auth.Key)
+	assert.Equal(t, newPassword, ac.Auth.AutoPwd)
+}
+
+func TestReplicaSet_KeyRotationIsCompletedAfterAFailedStatusUpdate(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	_ = reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.Security.Authentication.KeyRotation.RotationID = "2024-01"
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	newKeyfile, err := secret.ReadKey(mgr.Client, nextAgentKeyfileKey, mdb.GetAgentKeyfileSecretNamespacedName())
+	assert.NoError(t, err)
+	newPassword, err := secret.ReadKey(mgr.Client, nextAgentPasswordKey, mdb.GetAgentPasswordSecretNamespacedName())
+	assert.NoError(t, err)
+
+	// the keys are promoted, but the resource still reports the NewCredentialsRollout stage,
+	// as if the status update following the promotion had failed.
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.KeyRotationStageNewCredentials, mdb.Status.KeyRotation.Stage)
+	assert.NoError(t, completeKeyRotation(mgr.Client, mdb))
+
+	// the promoted keys are kept, no other keys are generated
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Equal(t, newKeyfile, ac.Auth.Key)
+	assert.Equal(t, newPassword, ac.Auth.AutoPwd)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.KeyRotationStage(""), mdb.Status.KeyRotation.Stage)
+	assert.Equal(t, "2024-01", mdb.Status.KeyRotation.RotationID)
+
+	keyfileSecret, err := mgr.Client.GetSecret(mdb.GetAgentKeyfileSecretNamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, newKeyfile, string(keyfileSecret.Data[scram.AgentKeyfileKey]))
+	assert.NotContains(t, keyfileSecret.Data, nextAgentKeyfileKey)
+}
+
+func TestReplicaSet_IsRequeuedForScheduledKeyRotation(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Security.Authentication.KeyRotation.Interval = &metav1.Duration{Duration: 24 * time.Hour}
+	mdb.CreationTimestamp = metav1.NewTime(time.Now())
+	mgr := client.NewManager(&mdb)
+
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.True(t, res.RequeueAfter > 23*time.Hour && res.RequeueAfter <= 24*time.Hour)
+}
+
+func TestKeyRotationInterval_MustBeAtLeastAnHour(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Security.Authentication.KeyRotation.Interval = &metav1.Duration{Duration: time.Minute}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "keyRotation.interval must be at least 1h0m0s")
+}
+
+func readAutomationConfig(t *testing.T, mgr *client.MockedManager, mdb mdbv1.MongoDBCommunity) automationconfig.AutomationConfig {
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	return ac
+}
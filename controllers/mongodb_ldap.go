@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/contains"
+)
+
+const (
+	// ldapMechanism is the authentication mechanism used by clients authenticating against LDAP.
+	ldapMechanism = "PLAIN"
+
+	ldapServersArg                  = "security.ldap.servers"
+	ldapTransportSecurityArg        = "security.ldap.transportSecurity"
+	ldapBindMethodArg               = "security.ldap.bind.method"
+	ldapBindQueryUserArg            = "security.ldap.bind.queryUser"
+	ldapBindQueryPasswordArg        = "security.ldap.bind.queryPassword" //nolint
+	ldapUserToDNMappingArg          = "security.ldap.userToDNMapping"
+	ldapAuthzQueryTemplateArg       = "security.ldap.authz.queryTemplate"
+	ldapTimeoutMSArg                = "security.ldap.timeoutMS"
+	ldapValidateLDAPServerConfigArg = "security.ldap.validateLDAPServerConfig"
+)
+
+// validateLDAPConfig will check that the Secret holding the LDAP bind password exists
+// and has the expected field when LDAP is enabled.
+func (r *ReplicaSetReconciler) validateLDAPConfig(mdb mdbv1.MongoDBCommunity) (bool, error) {
+	ldap := mdb.Spec.Security.LDAP
+	if !ldap.Enabled || !ldap.HasBindQueryPassword() {
+		return true, nil
+	}
+
+	r.log.Info("Ensuring LDAP is correctly configured")
+
+	secretData, err := secret.ReadStringData(r.client, mdb.LDAPBindQueryPasswordSecretNamespacedName())
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			r.log.Warnf(`Secret "%s" not found`, mdb.LDAPBindQueryPasswordSecretNamespacedName())
+			return false, nil
+		}
+		return false, err
+	}
+
+	if password, ok := secretData[ldap.GetBindQueryPasswordSecretKey()]; !ok || password == "" {
+		r.log.Warnf(`Secret "%s" should have a password in field "%s"`, mdb.LDAPBindQueryPasswordSecretNamespacedName(), ldap.GetBindQueryPasswordSecretKey())
+		return false, nil
+	}
+
+	// Watch the bind password secret to handle password changes
+	r.secretWatcher.Watch(mdb.LDAPBindQueryPasswordSecretNamespacedName(), mdb.NamespacedName())
+
+	r.log.Infof("Successfully validated LDAP config")
+	return true, nil
+}
+
+// getLDAPModification creates a modification function which configures the processes to proxy
+// PLAIN authentication, and optionally authorization, to the LDAP servers.
+func getLDAPModification(getter secret.Getter, mdb mdbv1.MongoDBCommunity) (automationconfig.Modification, error) {
+	ldap := mdb.Spec.Security.LDAP
+	if !ldap.Enabled {
+		return automationconfig.NOOP(), nil
+	}
+
+	bindQueryPassword := ""
+	if ldap.HasBindQueryPassword() {
+		password, err := secret.ReadKey(getter, ldap.GetBindQueryPasswordSecretKey(), mdb.LDAPBindQueryPasswordSecretNamespacedName())
+		if err != nil {
+			return automationconfig.NOOP(), errors.Errorf("could not read LDAP bind password: %s", err)
+		}
+		bindQueryPassword = password
+	}
+
+	return ldapModification(ldap, bindQueryPassword), nil
+}
+
+// ldapModification returns a modification function which renders the LDAP settings into the processes
+// and enables the PLAIN mechanism in the deployment.
+func ldapModification(ldap mdbv1.LDAP, bindQueryPassword string) automationconfig.Modification {
+	return func(config *automationconfig.AutomationConfig) {
+		if !contains.String(config.Auth.DeploymentAuthMechanisms, ldapMechanism) {
+			config.Auth.DeploymentAuthMechanisms = append(config.Auth.DeploymentAuthMechanisms, ldapMechanism)
+		}
+
+		validateLDAPServerConfig := true
+		if ldap.ValidateLDAPServerConfig != nil {
+			validateLDAPServerConfig = *ldap.ValidateLDAPServerConfig
+		}
+
+		for i := range config.Processes {
+			args := config.Processes[i].Args26
+
+			args.Set(ldapServersArg, strings.Join(ldap.Servers, ","))
+			args.Set(ldapTransportSecurityArg, string(ldap.GetTransportSecurity()))
+			args.Set(ldapValidateLDAPServerConfigArg, validateLDAPServerConfig)
+
+			// mongod only reads the bind password from its configuration, so it is stored in the automation config Secret.
+			if ldap.BindQueryUser != "" {
+				args.Set(ldapBindMethodArg, "simple")
+				args.Set(ldapBindQueryUserArg, ldap.BindQueryUser)
+				args.Set(ldapBindQueryPasswordArg, bindQueryPassword)
+			}
+			if ldap.UserToDNMapping != "" {
+				args.Set(ldapUserToDNMappingArg, ldap.UserToDNMapping)
+			}
+			if ldap.AuthzQueryTemplate != "" {
+				args.Set(ldapAuthzQueryTemplateArg, ldap.AuthzQueryTemplate)
+			}
+			if ldap.TimeoutMS != 0 {
+				args.Set(ldapTimeoutMSArg, ldap.TimeoutMS)
+			}
+		}
+	}
+}
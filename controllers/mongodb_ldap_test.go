@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestReplicaSetWithLDAP() mdbv1.MongoDBCommunity {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Security.LDAP = mdbv1.LDAP{
+		Enabled:                    true,
+		Servers:                    []string{"ldap1.example.com:636", "ldap2.example.com"},
+		BindQueryUser:              "cn=admin,dc=example,dc=com",
+		BindQueryPasswordSecretRef: mdbv1.SecretKeyReference{Name: "ldap-bind-password"},
+		UserToDNMapping:            `[{"match": "(.+)", "substitution": "uid={0},ou=users,dc=example,dc=com"}]`,
+		AuthzQueryTemplate:         "ou=groups,dc=example,dc=com??one?(member={USER})",
+	}
+	return mdb
+}
+
+func createLDAPBindPasswordSecret(c secret.Creator, mdb mdbv1.MongoDBCommunity) error {
+	s := secret.Builder().
+		SetName(mdb.Spec.Security.LDAP.BindQueryPasswordSecretRef.Name).
+		SetNamespace(mdb.Namespace).
+		SetField("password", "bind-password").
+		Build()
+	return c.CreateSecret(s)
+}
+
+func TestLDAPModification(t *testing.T) {
+	mdb := newTestReplicaSetWithLDAP()
+	mgr := client.NewManager(&mdb)
+	c := client.NewClient(mgr.GetClient())
+	assert.NoError(t, createLDAPBindPasswordSecret(c, mdb))
+
+	modification, err := getLDAPModification(c, mdb)
+	assert.NoError(t, err)
+
+	ac, err := buildAutomationConfig(mdb, automationconfig.Auth{DeploymentAuthMechanisms: []string{"SCRAM-SHA-256"}}, automationconfig.AutomationConfig{}, modification)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"SCRAM-SHA-256", ldapMechanism}, ac.Auth.DeploymentAuthMechanisms)
+	for _, process := range ac.Processes {
+		assert.Equal(t, "ldap1.example.com:636,ldap2.example.com", process.Args26.Get(ldapServersArg).Str())
+		assert.Equal(t, "tls", process.Args26.Get(ldapTransportSecurityArg).Str())
+		assert.Equal(t, "simple", process.Args26.Get(ldapBindMethodArg).Str())
+		assert.Equal(t, "cn=admin,dc=example,dc=com", process.Args26.Get(ldapBindQueryUserArg).Str())
+		assert.Equal(t, "bind-password", process.Args26.Get(ldapBindQueryPasswordArg).Str())
+		assert.Equal(t, mdb.Spec.Security.LDAP.UserToDNMapping, process.Args26.Get(ldapUserToDNMappingArg).Str())
+		assert.Equal(t, mdb.Spec.Security.LDAP.AuthzQueryTemplate, process.Args26.Get(ldapAuthzQueryTemplateArg).Str())
+		assert.True(t, process.Args26.Get(ldapValidateLDAPServerConfigArg).MustBool())
+		assert.False(t, process.Args26.Has(ldapTimeoutMSArg))
+	}
+}
+
+func TestLDAPModification_Disabled(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+
+	modification, err := getLDAPModification(client.NewClient(mgr.GetClient()), mdb)
+	assert.NoError(t, err)
+
+	ac, err := buildAutomationConfig(mdb, automationconfig.Auth{}, automationconfig.AutomationConfig{}, modification)
+	assert.NoError(t, err)
+
+	assert.NotContains(t, ac.Auth.DeploymentAuthMechanisms, ldapMechanism)
+	for _, process := range ac.Processes {
+		assert.False(t, process.Args26.Has("security.ldap"))
+	}
+}
+
+func TestReplicaSet_LDAPIsConfigured(t *testing.T) {
+	mdb := newTestReplicaSetWithLDAP()
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createLDAPBindPasswordSecret(client.NewClient(mgr.GetClient()), mdb))
+
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Contains(t, ac.Auth.DeploymentAuthMechanisms, ldapMechanism)
+	assert.Contains(t, ac.Auth.DeploymentAuthMechanisms, "SCRAM-SHA-256")
+}
+
+func TestReplicaSet_LDAPIsPendingWithoutBindPasswordSecret(t *testing.T) {
+	mdb := newTestReplicaSetWithLDAP()
+	mgr := client.NewManager(&mdb)
+
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.True(t, res.Requeue || res.RequeueAfter > 0)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+}
+
+func TestReplicaSet_FailsWithInvalidLDAPServer(t *testing.T) {
+	mdb := newTestReplicaSetWithLDAP()
+	mdb.Spec.Security.LDAP.Servers = []string{"ldap.example.com:notaport"}
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createLDAPBindPasswordSecret(client.NewClient(mgr.GetClient()), mdb))
+
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestPort_IsConfigurable(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Metrics = mdbv1.Metrics{Enabled: true}
+	mdb.Spec.Backup = mdbv1.Backup{Enabled: true}
+	mdb.Spec.Port = 30000
+	mdb.Spec.Services = mdbv1.Services{Client: true, Primary: true, Secondary: true}
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	for _, p := range ac.Processes {
+		assert.Equal(t, float64(30000), p.Args26.Get("net.port").Data())
+	}
+	assertServicePorts(t, mgr, mdb, 30000)
+	for _, name := range []string{mdb.Name + "-primary", mdb.Name + "-secondary"} {
+		svc, err := mgr.Client.GetService(types.NamespacedName{Name: name, Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(30000), svc.Spec.Ports[0].Port, name)
+	}
+
+	for _, uriSecretName := range []string{metricsURISecretName(mdb), backupURISecretName(mdb)} {
+		uri, err := secret.ReadKey(mgr.Client, "mongodb-uri", types.NamespacedName{Name: uriSecretName, Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Contains(t, uri, "my-rs-0.my-rs-svc.my-ns.svc.cluster.local:30000", uriSecretName)
+		assert.NotContains(t, uri, ":27017", uriSecretName)
+	}
+
+	// the exporter reads the connection string from the secret of the metrics user, and none of the probes
+	// connects to the port of mongod.
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	exporter := podtemplatespec.FindContainerByName(construct.ExporterName, &sts.Spec.Template)
+	if assert.NotNil(t, exporter) {
+		assert.Equal(t, metricsURISecretName(mdb), exporter.Env[0].ValueFrom.SecretKeyRef.Name)
+	}
+	for _, c := range sts.Spec.Template.Spec.Containers {
+		for _, probe := range []*corev1.Probe{c.ReadinessProbe, c.LivenessProbe} {
+			if probe == nil {
+				continue
+			}
+			assert.Nil(t, probe.TCPSocket, c.Name)
+			if probe.HTTPGet != nil {
+				assert.Equal(t, "metrics", probe.HTTPGet.Port.String(), c.Name)
+			}
+		}
+	}
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, "mongodb://my-rs-0.my-rs-svc.my-ns.svc.cluster.local:30000,my-rs-1.my-rs-svc.my-ns.svc.cluster.local:30000,my-rs-2.my-rs-svc.my-ns.svc.cluster.local:30000", mdb.Status.MongoURI)
+}
+
+func TestPort_ServicesAreUpdatedAfterTheAutomationConfig(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Services.Client = true
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	assertServicePorts(t, mgr, mdb, 27017)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.Port = 30000
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+	// the agents haven't reached goal state with the new port yet
+	setStatefulSetReadyReplicas(t, mgr.GetClient(), mdb, 0)
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	ac := readAutomationConfig(t, mgr, mdb)
+	assert.Equal(t, float64(30000), ac.Processes[0].Args26.Get("net.port").Data())
+	assertServicePorts(t, mgr, mdb, 27017)
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.MongoURI, ":27017")
+
+	makeStatefulSetReady(t, mgr.GetClient(), mdb)
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	assertServicePorts(t, mgr, mdb, 30000)
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Contains(t, mdb.Status.MongoURI, ":30000")
+	assert.NotContains(t, mdb.Status.MongoURI, ":27017")
+}
+
+func TestPort_DefaultsToTheAdditionalMongodConfig(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.AdditionalMongodConfig.Object = map[string]interface{}{"net": map[string]interface{}{"port": float64(30000)}}
+	assert.Equal(t, 30000, mdb.GetPort())
+
+	mdb.Spec.Port = 40000
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "spec.port 40000 and net.port 30000 of additionalMongodConfig must be the same")
+}
+
+// assertServicePorts checks the port of the headless Service and of the client Service.
+func assertServicePorts(t *testing.T, mgr *client.MockedManager, mdb mdbv1.MongoDBCommunity, port int32) {
+	for _, name := range []string{mdb.ServiceName(), mdb.Name + "-client"} {
+		svc, err := mgr.Client.GetService(types.NamespacedName{Name: name, Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, port, svc.Spec.Ports[0].Port, name)
+	}
+}
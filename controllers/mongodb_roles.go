@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/validation"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+)
+
+// rejectedRole is a MongoDBCommunityRole which can't be configured in the deployment it references.
+type rejectedRole struct {
+	role   mdbv1.MongoDBCommunityRole
+	reason string
+}
+
+// getCommunityRoles returns the MongoDBCommunityRole resources referencing the MongoDBCommunity resource.
+// The roles which can be configured in the deployment are returned first, followed by the rejected ones.
+func (r *ReplicaSetReconciler) getCommunityRoles(mdb mdbv1.MongoDBCommunity) ([]mdbv1.MongoDBCommunityRole, []rejectedRole, error) {
+	roleList := mdbv1.MongoDBCommunityRoleList{}
+	if err := r.client.List(context.TODO(), &roleList); err != nil {
+		return nil, nil, errors.Errorf("could not list MongoDBCommunityRole resources: %s", err)
+	}
+
+	// roles from the spec always take precedence over the ones declared in MongoDBCommunityRole resources.
+	roleKeys := map[string]bool{}
+	for _, role := range mdb.Spec.Security.Roles {
+		roleKeys[automationconfig.RoleKey(role.DB, role.Role)] = true
+	}
+
+	var candidates []mdbv1.MongoDBCommunityRole
+	var rejected []rejectedRole
+	for _, role := range roleList.Items {
+		if role.MongoDBResourceNamespacedName() != mdb.NamespacedName() {
+			continue
+		}
+
+		if !mdb.IsNamespaceAllowed(role.Namespace) {
+			rejected = append(rejected, rejectedRole{role: role, reason: fmt.Sprintf("namespace %s is not allowed by MongoDBCommunity %s", role.Namespace, mdb.NamespacedName())})
+			continue
+		}
+		if roleKeys[automationconfig.RoleKey(role.Spec.DB, role.Spec.Role)] {
+			rejected = append(rejected, rejectedRole{role: role, reason: fmt.Sprintf("role %s already exists in MongoDBCommunity %s", automationconfig.RoleKey(role.Spec.DB, role.Spec.Role), mdb.NamespacedName())})
+			continue
+		}
+		roleKeys[automationconfig.RoleKey(role.Spec.DB, role.Spec.Role)] = true
+		candidates = append(candidates, role)
+	}
+
+	// rejecting a role can make the roles inheriting from it invalid, so the roles
+	// are validated again until all the remaining ones are valid.
+	for {
+		customRoles := append([]mdbv1.CustomRole{}, mdb.Spec.Security.Roles...)
+		for _, role := range candidates {
+			customRoles = append(customRoles, role.Spec.CustomRole)
+		}
+
+		var accepted []mdbv1.MongoDBCommunityRole
+		for _, role := range candidates {
+			if err := validation.ValidateCustomRole(role.Spec.CustomRole, customRoles, mdb.Spec.Version); err != nil {
+				rejected = append(rejected, rejectedRole{role: role, reason: err.Error()})
+				continue
+			}
+			accepted = append(accepted, role)
+		}
+
+		if len(accepted) == len(candidates) {
+			return accepted, rejected, nil
+		}
+		candidates = accepted
+	}
+}
+
+// updateCommunityRolesStatus sets the status of the MongoDBCommunityRole resources referencing the MongoDBCommunity resource.
+// Accepted roles get the given phase, and rejected roles are marked as failed with the reason. When the phase is Pending, the accepted roles which are already Running keep their status,
+// so that it doesn't change back and forth on every reconciliation.
+func (r *ReplicaSetReconciler) updateCommunityRolesStatus(mdb mdbv1.MongoDBCommunity, phase mdbv1.Phase) error {
+	accepted, rejected, err := r.getCommunityRoles(mdb)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range accepted {
+		if phase == mdbv1.Pending && role.Status.Phase == mdbv1.Running {
+			continue
+		}
+		if err := r.updateCommunityRoleStatus(role, mdbv1.MongoDBCommunityRoleStatus{Phase: phase}); err != nil {
+			return err
+		}
+	}
+	for _, rejected := range rejected {
+		if err := r.updateCommunityRoleStatus(rejected.role, mdbv1.MongoDBCommunityRoleStatus{Phase: mdbv1.Failed, Message: rejected.reason}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warnInvalidDeployedRoles logs the roles of the spec which are unchanged since the last successful deployment,
+// but would be rejected if they were new. They were deployed before the roles were validated.
+func (r *ReplicaSetReconciler) warnInvalidDeployedRoles(mdb mdbv1.MongoDBCommunity) {
+	prevSpec, ok, err := getLastSuccessfulSpec(mdb)
+	if err != nil || !ok {
+		return
+	}
+	for _, reason := range validation.InvalidDeployedCustomRoles(prevSpec, mdb.Spec) {
+		r.log.Warnf("%s, fix the role before changing it", reason)
+	}
+}
+
+// updateCommunityRoleStatus updates the status of the role if it has changed.
+func (r *ReplicaSetReconciler) updateCommunityRoleStatus(role mdbv1.MongoDBCommunityRole, status mdbv1.MongoDBCommunityRoleStatus) error {
+	if role.Status == status {
+		return nil
+	}
+	role.Status = status
+	if err := r.client.Status().Update(context.TODO(), &role); err != nil {
+		return errors.Errorf("could not update the status of MongoDBCommunityRole %s: %s", role.NamespacedName(), err)
+	}
+	return nil
+}
+
+// communityRoleToMongoDB maps a MongoDBCommunityRole to a reconciliation of the MongoDBCommunity resource it references.
+func communityRoleToMongoDB(obj k8sClient.Object) []reconcile.Request {
+	role, ok := obj.(*mdbv1.MongoDBCommunityRole)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: role.MongoDBResourceNamespacedName()}}
+}
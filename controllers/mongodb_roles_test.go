@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestCustomRole(name string, actions []string, inherited ...mdbv1.Role) mdbv1.CustomRole {
+	db := "app"
+	collection := ""
+	return mdbv1.CustomRole{
+		Role: name,
+		DB:   "admin",
+		Privileges: []mdbv1.Privilege{{
+			Resource: mdbv1.Resource{DB: &db, Collection: &collection},
+			Actions:  actions,
+		}},
+		Roles: inherited,
+	}
+}
+
+func newTestCommunityRole(name, namespace string, mdb mdbv1.MongoDBCommunity, role mdbv1.CustomRole) mdbv1.MongoDBCommunityRole {
+	return mdbv1.MongoDBCommunityRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: mdbv1.MongoDBCommunityRoleSpec{
+			MongoDBResourceRef: mdbv1.MongoDBResourceReference{Name: mdb.Name},
+			CustomRole:         role,
+		},
+	}
+}
+
+func getCommunityRole(t *testing.T, mgr *client.MockedManager, nsName types.NamespacedName) mdbv1.MongoDBCommunityRole {
+	role := mdbv1.MongoDBCommunityRole{}
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), nsName, &role))
+	return role
+}
+
+func acRoleNames(ac automationconfig.AutomationConfig) []string {
+	var names []string
+	for _, r := range ac.Roles {
+		names = append(names, r.Role)
+	}
+	return names
+}
+
+func TestCommunityRole_IsAddedToTheDeployment(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Security.Roles = []mdbv1.CustomRole{newTestCustomRole("specRole", []string{"find"})}
+	mgr := client.NewManager(&mdb)
+
+	role := newTestCommunityRole("app-role", mdb.Namespace, mdb, newTestCustomRole("appRole", []string{"insert"}, mdbv1.Role{Name: "specRole", DB: "admin"}, mdbv1.Role{Name: "read", DB: "app"}))
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &role))
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	assert.Equal(t, []string{"specRole", "appRole"}, acRoleNames(ac))
+	assert.Equal(t, mdbv1.Running, getCommunityRole(t, mgr, role.NamespacedName()).Status.Phase)
+}
+
+func TestCommunityRole_WithUnknownAction_IsRejected(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+
+	role := newTestCommunityRole("typo-role", mdb.Namespace, mdb, newTestCustomRole("typoRole", []string{"find", "fnd"}))
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &role))
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	assert.Empty(t, acRoleNames(ac))
+	rejected := getCommunityRole(t, mgr, role.NamespacedName())
+	assert.Equal(t, mdbv1.Failed, rejected.Status.Phase)
+	assert.Equal(t, "role admin.typoRole uses unknown privilege action fnd", rejected.Status.Message)
+}
+
+func TestCommunityRole_WithoutActions_IsRejected(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+
+	invalid := newTestCommunityRole("invalid-role", mdb.Namespace, mdb, newTestCustomRole("invalidRole", []string{}))
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &invalid))
+	// a role inheriting from a rejected role is rejected as well
+	inheriting := newTestCommunityRole("inheriting-role", mdb.Namespace, mdb, newTestCustomRole("inheritingRole", []string{"find"}, mdbv1.Role{Name: "invalidRole", DB: "admin"}))
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &inheriting))
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	assert.Empty(t, acRoleNames(ac))
+	rejected := getCommunityRole(t, mgr, invalid.NamespacedName())
+	assert.Equal(t, mdbv1.Failed, rejected.Status.Phase)
+	assert.Equal(t, "role admin.invalidRole has a privilege without actions", rejected.Status.Message)
+
+	rejected = getCommunityRole(t, mgr, inheriting.NamespacedName())
+	assert.Equal(t, mdbv1.Failed, rejected.Status.Phase)
+	assert.Equal(t, "role admin.inheritingRole inherits role admin.invalidRole which does not exist", rejected.Status.Message)
+}
+
+func TestCommunityRole_StaysRunningWhileTheDeploymentIsPending(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+	role := newTestCommunityRole("app-role", mdb.Namespace, mdb, newTestCustomRole("appRole", []string{"find"}))
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &role))
+
+	reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Equal(t, mdbv1.Running, getCommunityRole(t, mgr, role.NamespacedName()).Status.Phase)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	setStatefulSetReadyReplicas(t, mgr.GetClient(), mdb, 0)
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+	assert.Equal(t, mdbv1.Running, getCommunityRole(t, mgr, role.NamespacedName()).Status.Phase)
+}
+
+func TestCommunityRole_WithCycle_IsRejected(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+
+	first := newTestCommunityRole("first-role", mdb.Namespace, mdb, newTestCustomRole("firstRole", []string{"find"}, mdbv1.Role{Name: "secondRole", DB: "admin"}))
+	second := newTestCommunityRole("second-role", mdb.Namespace, mdb, newTestCustomRole("secondRole", []string{"find"}, mdbv1.Role{Name: "firstRole", DB: "admin"}))
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &first))
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &second))
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	assert.Empty(t, acRoleNames(ac))
+	rejected := getCommunityRole(t, mgr, first.NamespacedName())
+	assert.Equal(t, mdbv1.Failed, rejected.Status.Phase)
+	assert.Equal(t, "role admin.firstRole inherits from itself through admin.secondRole", rejected.Status.Message)
+}
+
+func TestCustomRoles_InSpec_AreValidated(t *testing.T) {
+	tests := map[string]struct {
+		roles   []mdbv1.CustomRole
+		message string
+	}{
+		"privilege without actions": {
+			roles:   []mdbv1.CustomRole{newTestCustomRole("specRole", nil)},
+			message: "role admin.specRole has a privilege without actions",
+		},
+		"unknown action": {
+			roles:   []mdbv1.CustomRole{newTestCustomRole("specRole", []string{"fnd"})},
+			message: "role admin.specRole uses unknown privilege action fnd",
+		},
+		"action of a later version": {
+			roles:   []mdbv1.CustomRole{newTestCustomRole("specRole", []string{"analyzeShardKey"})},
+			message: "role admin.specRole uses privilege action analyzeShardKey, which requires MongoDB 7.0.0 or later",
+		},
+		"unknown inherited role": {
+			roles:   []mdbv1.CustomRole{newTestCustomRole("specRole", []string{"find"}, mdbv1.Role{Name: "missingRole", DB: "admin"})},
+			message: "role admin.specRole inherits role admin.missingRole which does not exist",
+		},
+		"admin only built-in role in another database": {
+			roles:   []mdbv1.CustomRole{newTestCustomRole("specRole", []string{"find"}, mdbv1.Role{Name: "clusterAdmin", DB: "app"})},
+			message: "role admin.specRole inherits role app.clusterAdmin which does not exist",
+		},
+		"role inheriting from itself": {
+			roles:   []mdbv1.CustomRole{newTestCustomRole("specRole", []string{"find"}, mdbv1.Role{Name: "specRole", DB: "admin"})},
+			message: "role admin.specRole inherits from itself through admin.specRole",
+		},
+		"duplicate role": {
+			roles:   []mdbv1.CustomRole{newTestCustomRole("specRole", []string{"find"}), newTestCustomRole("specRole", []string{"insert"})},
+			message: "role admin.specRole is defined more than once",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mdb := newScramReplicaSet()
+			mdb.Spec.Security.Roles = test.roles
+			mgr := client.NewManager(&mdb)
+
+			r := NewReconciler(mgr)
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+			assert.NoError(t, err)
+
+			assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+			assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+			assert.Contains(t, mdb.Status.Message, test.message)
+		})
+	}
+}
+
+func TestCustomRoles_InSpec_WithRecentActionsAreAccepted(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Version = "7.0.2"
+	mdb.Spec.Security.Roles = []mdbv1.CustomRole{
+		newTestCustomRole("statsRole", []string{"indexStats", "listSearchIndexes", "analyzeShardKey", "setClusterParameter"}, mdbv1.Role{Name: "enableSharding", DB: "admin"}),
+	}
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	assert.Equal(t, []string{"statsRole"}, acRoleNames(ac))
+}
+
+func TestCustomRoles_InSpec_DeployedBeforeTheValidationAreKept(t *testing.T) {
+	mdb := newScramReplicaSet()
+	// the role inherits from a role created by hand, which previous versions of the operator accepted.
+	mdb.Spec.Security.Roles = []mdbv1.CustomRole{newTestCustomRole("specRole", []string{"find"}, mdbv1.Role{Name: "handRole", DB: "admin"})}
+	deployedSpec, err := json.Marshal(mdb.Spec)
+	assert.NoError(t, err)
+	mdb.Annotations = map[string]string{lastSuccessfulConfiguration: string(deployedSpec)}
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Equal(t, []string{"specRole"}, acRoleNames(ac))
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.Security.Roles[0].Privileges[0].Actions = []string{"find", "insert"}
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+	r := NewReconciler(mgr)
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase, "a changed role is validated")
+	assert.Contains(t, mdb.Status.Message, "role admin.specRole inherits role admin.handRole which does not exist")
+}
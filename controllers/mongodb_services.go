@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"fmt"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/service"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/statefulset"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/readiness/health"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/readiness/pod"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// componentLabel tells the pods of the members which hold data apart from the pods of the arbiters and of the
+	// analytics members, which share the "app" label of the headless Service.
+	componentLabel = "mongodb.com/component"
+
+	componentMember    = "member"
+	componentArbiter   = "arbiter"
+	componentAnalytics = "analytics"
+)
+
+// additionalService is one of the ClusterIP Services which can be enabled in spec.services.
+type additionalService struct {
+	suffix  string
+	role    string
+	enabled func(services mdbv1.Services) bool
+}
+
+var additionalServices = []additionalService{
+	{suffix: "client", enabled: func(s mdbv1.Services) bool { return s.Client }},
+	{suffix: "primary", role: health.RolePrimary, enabled: func(s mdbv1.Services) bool { return s.Primary }},
+	{suffix: "secondary", role: health.RoleSecondary, enabled: func(s mdbv1.Services) bool { return s.Secondary }},
+}
+
+// buildAdditionalService creates a ClusterIP Service which selects the ready members with the given role,
+// or all the ready members which hold data if there is none. The role label is kept current on the pods by the
+// readiness probe, from the health status of the agent.
+func buildAdditionalService(mdb mdbv1.MongoDBCommunity, svc additionalService, port int) corev1.Service {
+	selector := map[string]string{"app": mdb.ServiceName(), componentLabel: componentMember}
+	if svc.role != "" {
+		selector[pod.RoleLabel] = svc.role
+	}
+	return service.Builder().
+		SetName(fmt.Sprintf("%s-%s", mdb.Name, svc.suffix)).
+		SetNamespace(mdb.Namespace).
+		SetSelector(selector).
+		SetServiceType(corev1.ServiceTypeClusterIP).
+		SetOwnerReferences([]metav1.OwnerReference{getOwnerReference(mdb)}).
+		SetPortName("mongodb").
+		SetPort(int32(port)).
+		Build()
+}
+
+// ensureAdditionalServices creates the Services enabled in spec.services, and removes the other ones.
+func (r *ReplicaSetReconciler) ensureAdditionalServices(mdb mdbv1.MongoDBCommunity, port int) error {
+	for _, svc := range additionalServices {
+		s := buildAdditionalService(mdb, svc, port)
+		if !svc.enabled(mdb.Spec.Services) {
+			if err := r.deleteIfExists(&s); err != nil {
+				return errors.Errorf("error deleting the %s service: %s", svc.suffix, err)
+			}
+			continue
+		}
+		if err := service.CreateOrUpdate(r.client, s); err != nil {
+			return errors.Errorf("error creating/updating the %s service: %s", svc.suffix, err)
+		}
+	}
+	return nil
+}
+
+// withComponentLabel applies the modification, then labels the StatefulSet and its pods with their component. The
+// selector of a StatefulSet can't be changed, so the label is only added to the selector of new StatefulSets.
+func withComponentLabel(component string, modification statefulset.Modification) statefulset.Modification {
+	return func(set *appsv1.StatefulSet) {
+		existingSelector := set.Spec.Selector.DeepCopy()
+		modification(set)
+		if set.Labels == nil {
+			set.Labels = map[string]string{}
+		}
+		set.Labels[componentLabel] = component
+		if set.Spec.Template.Labels == nil {
+			set.Spec.Template.Labels = map[string]string{}
+		}
+		set.Spec.Template.Labels[componentLabel] = component
+		if existingSelector != nil {
+			set.Spec.Selector = existingSelector
+			return
+		}
+		set.Spec.Selector.MatchLabels[componentLabel] = component
+	}
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestAdditionalServices_AreCreated(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Services = mdbv1.Services{Client: true, Primary: true, Secondary: true}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	expectedSelectors := map[string]map[string]string{
+		"my-rs-client":    {"app": "my-rs-svc", "mongodb.com/component": "member"},
+		"my-rs-primary":   {"app": "my-rs-svc", "mongodb.com/component": "member", "mongodb.com/role": "primary"},
+		"my-rs-secondary": {"app": "my-rs-svc", "mongodb.com/component": "member", "mongodb.com/role": "secondary"},
+	}
+	for name, selector := range expectedSelectors {
+		svc, err := mgr.Client.GetService(types.NamespacedName{Name: name, Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, corev1.ServiceTypeClusterIP, svc.Spec.Type)
+		assert.Equal(t, selector, svc.Spec.Selector, name)
+		assert.False(t, svc.Spec.PublishNotReadyAddresses, "only the ready members receive traffic")
+		assert.Equal(t, int32(27017), svc.Spec.Ports[0].Port)
+	}
+}
+
+func TestAdditionalServices_AreDeletedWhenDisabled(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Services = mdbv1.Services{Primary: true, Secondary: true}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	_, err = mgr.Client.GetService(types.NamespacedName{Name: "my-rs-client", Namespace: mdb.Namespace})
+	assert.True(t, apiErrors.IsNotFound(err))
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.Services.Secondary = false
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	_, err = mgr.Client.GetService(types.NamespacedName{Name: "my-rs-primary", Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	_, err = mgr.Client.GetService(types.NamespacedName{Name: "my-rs-secondary", Namespace: mdb.Namespace})
+	assert.True(t, apiErrors.IsNotFound(err))
+}
+
+func TestAdditionalServices_RequireAReplicaSet(t *testing.T) {
+	mdb := newTestShardedCluster()
+	mdb.Spec.Services.Primary = true
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "services are not supported with a ShardedCluster")
+}
+
+func TestAdditionalServices_OnlySelectTheMembersWhichHoldData(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Arbiters = 1
+	mdb.Spec.AnalyticsMembers.Members = 1
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	expectedComponents := map[types.NamespacedName]string{
+		mdb.NamespacedName():          "member",
+		mdb.ArbitersNamespacedName():  "arbiter",
+		mdb.AnalyticsNamespacedName(): "analytics",
+	}
+	for nsName, component := range expectedComponents {
+		sts, err := mgr.Client.GetStatefulSet(nsName)
+		assert.NoError(t, err)
+		assert.Equal(t, component, sts.Spec.Template.Labels["mongodb.com/component"], nsName.Name)
+		assert.Equal(t, map[string]string{"app": "my-rs-svc", "mongodb.com/component": component}, sts.Spec.Selector.MatchLabels, nsName.Name)
+	}
+}
+
+func TestAdditionalServices_TheSelectorOfExistingStatefulSetsIsKept(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	// the StatefulSets of previous operators only select the "app" label
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	sts.Spec.Selector.MatchLabels = map[string]string{"app": "my-rs-svc"}
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &sts))
+
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	sts, err = mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": "my-rs-svc"}, sts.Spec.Selector.MatchLabels)
+	assert.Equal(t, "member", sts.Spec.Template.Labels["mongodb.com/component"])
+}
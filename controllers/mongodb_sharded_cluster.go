@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/agent"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/statefulset"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/functions"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// shardedClusterComponent is one of the StatefulSets of a sharded cluster: the config servers, a shard or the mongos routers.
+type shardedClusterComponent struct {
+	nsName   types.NamespacedName
+	replicas int
+	mongos   bool
+}
+
+// shardedClusterComponents returns the components of the sharded cluster, in the order they appear in the automation config.
+// The members of the config servers and of the shards are scaled one at a time, the mongos routers all at once.
+func shardedClusterComponents(mdb mdbv1.MongoDBCommunity) []shardedClusterComponent {
+	components := []shardedClusterComponent{
+		{nsName: mdb.ConfigServerNamespacedName(), replicas: mdb.ConfigServerMembersThisReconciliation()},
+	}
+	for i := 0; i < mdb.Spec.ShardedCluster.Shards; i++ {
+		components = append(components, shardedClusterComponent{nsName: mdb.ShardNamespacedName(i), replicas: mdb.ShardMembersThisReconciliation(i)})
+	}
+	return append(components, shardedClusterComponent{nsName: mdb.MongosNamespacedName(), replicas: mdb.Spec.ShardedCluster.MongosReplicas, mongos: true})
+}
+
+func (c shardedClusterComponent) owner(mdb mdbv1.MongoDBCommunity) *componentStatefulSetOwner {
+	return &componentStatefulSetOwner{MongoDBCommunity: mdb, nsName: c.nsName, singleVolume: c.mongos}
+}
+
+func buildShardedClusterStatefulSetModificationFunction(mdb mdbv1.MongoDBCommunity, component shardedClusterComponent) statefulset.Modification {
+	commonModification := construct.BuildMongoDBReplicaSetStatefulSetModificationFunction(component.owner(mdb), fixedReplicasScaler{replicas: component.replicas})
+
+	// the mongos routers hold no data, so their data volume is an emptyDir rather than a volume claim,
+	// and the clients connect to them, so they run the metrics exporter.
+	mongosModification := statefulset.NOOP()
+	mongosPodSpecModification := podtemplatespec.NOOP()
+	if component.mongos {
+		mongosModification = statefulset.WithoutVolumeClaim(mdb.DataVolumeName())
+		mongosPodSpecModification = podtemplatespec.Apply(
+			podtemplatespec.WithVolume(statefulset.CreateVolumeFromEmptyDir(mdb.DataVolumeName())),
+			construct.WithMongosCommand(),
+			buildExporterPodSpecModification(mdb),
+		)
+	}
+
+	// spec.statefulSet describes the StatefulSet of the members of a replica set, it isn't applied to the components
+	// of a sharded cluster.
+	return statefulset.Apply(
+		commonModification,
+		statefulset.WithOwnerReference([]metav1.OwnerReference{getOwnerReference(mdb)}),
+		statefulset.WithPodSpecTemplate(
+			podtemplatespec.Apply(
+				buildTLSPodSpecModification(mdb),
+				buildClusterAuthPodSpecModification(mdb),
+				mongosPodSpecModification,
+			),
+		),
+		mongosModification,
+	)
+}
+
+// deployShardedCluster deploys the automation config and the StatefulSets of the sharded cluster.
+// The returned boolean indicates that all the components are ready.
+func (r *ReplicaSetReconciler) deployShardedCluster(mdb mdbv1.MongoDBCommunity) (bool, error) {
+	return functions.RunSequentially(r.shouldDeployShardedClusterInOrder(mdb),
+		func() (bool, error) {
+			return r.deployShardedClusterAutomationConfig(mdb)
+		},
+		func() (bool, error) {
+			return r.deployShardedClusterStatefulSets(mdb)
+		})
+}
+
+// shouldDeployShardedClusterInOrder returns false when the StatefulSets of an existing sharded cluster must be updated
+// before the automation config: when TLS is enabled, when the version changes, or when a component is scaled up.
+func (r *ReplicaSetReconciler) shouldDeployShardedClusterInOrder(mdb mdbv1.MongoDBCommunity) bool {
+	if _, err := r.client.GetStatefulSet(mdb.ConfigServerNamespacedName()); err != nil {
+		return true
+	}
+	if mdb.Spec.Security.TLS.Enabled || mdb.IsChangingVersion() {
+		return false
+	}
+	current := map[string]int{}
+	for _, component := range mdb.Status.ShardedCluster {
+		current[component.Name] = component.Replicas
+	}
+	for _, component := range shardedClusterComponents(mdb) {
+		if replicas, ok := current[component.nsName.Name]; !ok || component.replicas > replicas {
+			r.log.Debugf("Scaling up %s, the StatefulSets must be updated first", component.nsName.Name)
+			return false
+		}
+	}
+	return true
+}
+
+func (r *ReplicaSetReconciler) deployShardedClusterAutomationConfig(mdb mdbv1.MongoDBCommunity) (bool, error) {
+	r.log.Infof("Creating/Updating AutomationConfig")
+	ac, err := r.ensureAutomationConfig(mdb)
+	if err != nil {
+		return false, fmt.Errorf("failed to ensure AutomationConfig: %s", err)
+	}
+
+	for _, component := range shardedClusterComponents(mdb) {
+		sts, err := r.client.GetStatefulSet(component.nsName)
+		if err != nil {
+			if apiErrors.IsNotFound(err) {
+				continue
+			}
+			return false, fmt.Errorf("failed to get StatefulSet %s: %s", component.nsName.Name, err)
+		}
+		ready, err := agent.AllReachedGoalState(sts, r.client, component.replicas, ac.Version, r.log)
+		if err != nil {
+			return false, fmt.Errorf("failed to ensure agents of %s have reached goal state: %s", component.nsName.Name, err)
+		}
+		if !ready {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *ReplicaSetReconciler) deployShardedClusterStatefulSets(mdb mdbv1.MongoDBCommunity) (bool, error) {
+	r.log.Info("Creating/Updating the StatefulSets of the sharded cluster")
+	ca, err := readCA(r.client, mdb)
+	if err != nil {
+		return false, errors.Errorf("error reading CA: %s", err)
+	}
+
+	allReady := true
+	for _, component := range shardedClusterComponents(mdb) {
+		set := appsv1.StatefulSet{}
+		err := r.client.Get(context.TODO(), component.nsName, &set)
+		exists := err == nil
+		if err := k8sClient.IgnoreNotFound(err); err != nil {
+			return false, errors.Errorf("error getting StatefulSet %s: %s", component.nsName.Name, err)
+		}
+		buildShardedClusterStatefulSetModificationFunction(mdb, component)(&set)
+		withCAHashAnnotation(mdb, ca, exists)(&set)
+		if _, err := statefulset.CreateOrUpdate(r.client, set); err != nil {
+			return false, errors.Errorf("error creating/updating StatefulSet %s: %s", component.nsName.Name, err)
+		}
+
+		currentSts, err := r.client.GetStatefulSet(component.nsName)
+		if err != nil {
+			return false, errors.Errorf("error getting StatefulSet %s: %s", component.nsName.Name, err)
+		}
+		isReady := statefulset.IsReady(currentSts, component.replicas) || currentSts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType
+		allReady = allReady && isReady
+	}
+	return allReady, nil
+}
+
+// shardedClusterStatus returns the number of ready replicas of each component of the sharded cluster,
+// or nil for a replica set.
+func (r *ReplicaSetReconciler) shardedClusterStatus(mdb mdbv1.MongoDBCommunity) []mdbv1.ComponentStatus {
+	if !mdb.IsShardedCluster() {
+		return nil
+	}
+	components := shardedClusterComponents(mdb)
+	statuses := make([]mdbv1.ComponentStatus, len(components))
+	for i, component := range components {
+		statuses[i] = mdbv1.ComponentStatus{Name: component.nsName.Name, Replicas: component.replicas}
+		if sts, err := r.client.GetStatefulSet(component.nsName); err == nil {
+			statuses[i].ReadyReplicas = int(sts.Status.ReadyReplicas)
+		}
+	}
+	return statuses
+}
+
+// withPreviousReplicas returns the given statuses with the replicas reported by the previous reconciliation, which
+// are the replicas the config servers and the shards are scaled from. A component is only scaled further once all of
+// its members are ready.
+func withPreviousReplicas(mdb mdbv1.MongoDBCommunity, components []mdbv1.ComponentStatus) []mdbv1.ComponentStatus {
+	previous := map[string]int{}
+	for _, component := range mdb.Status.ShardedCluster {
+		previous[component.Name] = component.Replicas
+	}
+	statuses := make([]mdbv1.ComponentStatus, len(components))
+	for i, component := range components {
+		statuses[i] = component
+		if replicas, ok := previous[component.Name]; ok {
+			statuses[i].Replicas = replicas
+		}
+	}
+	return statuses
+}
+
+// notReadyMessage lists the components of a sharded cluster which are not ready yet.
+func notReadyMessage(mdb mdbv1.MongoDBCommunity, components []mdbv1.ComponentStatus) string {
+	if !mdb.IsShardedCluster() {
+		return "ReplicaSet is not yet ready, retrying in 10 seconds"
+	}
+	var notReady []string
+	for _, component := range components {
+		if !component.IsReady() {
+			notReady = append(notReady, component.Name)
+		}
+	}
+	if len(notReady) == 0 {
+		return "Sharded cluster is not yet ready, retrying in 10 seconds"
+	}
+	return fmt.Sprintf("Sharded cluster is not yet ready, waiting for %s, retrying in 10 seconds", strings.Join(notReady, ", "))
+}
+
+// resetShardedClusterUpdateStrategy resets the UpdateStrategy of the StatefulSets of the sharded cluster
+// once the version change is complete.
+func (r *ReplicaSetReconciler) resetShardedClusterUpdateStrategy(mdb mdbv1.MongoDBCommunity) error {
+	for _, component := range shardedClusterComponents(mdb) {
+		if err := statefulset.ResetUpdateStrategy(component.owner(mdb), r.client); err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// automationConfigTopology returns the topology of the automation config of the resource.
+func automationConfigTopology(mdb mdbv1.MongoDBCommunity) automationconfig.Topology {
+	if mdb.IsShardedCluster() {
+		return automationconfig.ShardedClusterTopology
+	}
+	if mdb.IsStandalone() {
+		return automationconfig.StandaloneTopology
+	}
+	return automationconfig.ReplicaSetTopology
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/podtemplatespec"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestShardedCluster() mdbv1.MongoDBCommunity {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Type = mdbv1.ShardedCluster
+	mdb.Spec.ShardedCluster = mdbv1.ShardedClusterConfiguration{
+		Shards:          2,
+		MembersPerShard: 3,
+		MongosReplicas:  2,
+	}
+	return mdb
+}
+
+func TestShardedCluster_IsCreated(t *testing.T) {
+	mdb := newTestShardedCluster()
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Len(t, ac.Processes, 3+2*3+2)
+	assert.Len(t, ac.ReplicaSets, 3)
+	assert.Equal(t, "my-rs-configsrv", ac.ReplicaSets[0].Id)
+	assert.True(t, ac.ReplicaSets[0].ConfigServer)
+	assert.Equal(t, "my-rs-1", ac.ReplicaSets[2].Id)
+	assert.Equal(t, automationconfig.Mongos, ac.Processes[len(ac.Processes)-1].ProcessType)
+	assert.Equal(t, mdb.Name, ac.Processes[len(ac.Processes)-1].Cluster)
+
+	assert.Len(t, ac.Sharding, 1)
+	assert.Equal(t, "my-rs-configsrv", ac.Sharding[0].ConfigServerReplica)
+	assert.Len(t, ac.Sharding[0].Shards, 2)
+	assert.Equal(t, "my-rs-0", ac.Sharding[0].Shards[0].Rs)
+
+	_, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.Error(t, err, "no StatefulSet is created for a replica set")
+
+	for _, component := range shardedClusterComponents(mdb) {
+		sts, err := mgr.Client.GetStatefulSet(component.nsName)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(component.replicas), *sts.Spec.Replicas)
+		assert.Equal(t, mdb.ServiceName(), sts.Spec.ServiceName)
+
+		command := strings.Join(podtemplatespec.FindContainerByName(construct.MongodbName, &sts.Spec.Template).Command, " ")
+		assert.Equal(t, component.mongos, strings.Contains(command, "mongos"), component.nsName.Name)
+	}
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+	assert.Len(t, mdb.Status.ShardedCluster, 4)
+	for _, component := range mdb.Status.ShardedCluster {
+		assert.True(t, component.IsReady(), component.Name)
+	}
+	assert.Equal(t, "my-rs-mongos-0.my-rs-svc.my-ns.svc.cluster.local:27017", mdb.Hosts()[0])
+	assert.NotContains(t, mdb.MongoURI(), "replicaSet")
+}
+
+func TestShardedCluster_MongosHaveNoVolumeClaim(t *testing.T) {
+	mdb := newTestShardedCluster()
+	mgr := client.NewManager(&mdb)
+	_ = reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.MongosNamespacedName())
+	assert.NoError(t, err)
+	assert.Empty(t, sts.Spec.VolumeClaimTemplates)
+	volume := findVolumeByName(t, sts.Spec.Template.Spec.Volumes, mdb.DataVolumeName())
+	assert.NotNil(t, volume.EmptyDir)
+
+	configServer, err := mgr.Client.GetStatefulSet(mdb.ConfigServerNamespacedName())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, configServer.Spec.VolumeClaimTemplates)
+}
+
+func TestShardedCluster_DontUseTheStatefulSetOfTheMembers(t *testing.T) {
+	mdb := newTestShardedCluster()
+	mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec.Template.Spec.NodeSelector = map[string]string{"disktype": "ssd"}
+	mgr := client.NewManager(&mdb)
+	_ = reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	for _, component := range shardedClusterComponents(mdb) {
+		sts, err := mgr.Client.GetStatefulSet(component.nsName)
+		assert.NoError(t, err)
+		assert.Empty(t, sts.Spec.Template.Spec.NodeSelector, component.nsName.Name)
+	}
+}
+
+func findVolumeByName(t *testing.T, volumes []corev1.Volume, name string) corev1.Volume {
+	for _, v := range volumes {
+		if v.Name == name {
+			return v
+		}
+	}
+	t.Fatalf("volume %s not found", name)
+	return corev1.Volume{}
+}
+
+func TestShardedCluster_ShardsAreAdded(t *testing.T) {
+	mdb := newTestShardedCluster()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.ShardedCluster.Shards = 3
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.ShardNamespacedName(2))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), *sts.Spec.Replicas)
+
+	ac := readAutomationConfig(t, mgr, mdb)
+	assert.Len(t, ac.Sharding[0].Shards, 3)
+	assert.Equal(t, 2, ac.Version)
+}
+
+func TestShardedCluster_ShardsAreScaledOneMemberAtATime(t *testing.T) {
+	mdb := newTestShardedCluster()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.ShardedCluster.MembersPerShard = 5
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+	assertShardMembers := func(expectedStatefulSetReplicas, expectedAutomationConfigMembers int) {
+		for i := 0; i < mdb.Spec.ShardedCluster.Shards; i++ {
+			sts, err := mgr.Client.GetStatefulSet(mdb.ShardNamespacedName(i))
+			assert.NoError(t, err)
+			assert.Equal(t, int32(expectedStatefulSetReplicas), *sts.Spec.Replicas)
+		}
+		ac := readAutomationConfig(t, mgr, mdb)
+		assert.Len(t, ac.ReplicaSets[0].Members, 3, "the config servers are not scaled")
+		assert.Len(t, ac.ReplicaSets[1].Members, expectedAutomationConfigMembers)
+		assert.Len(t, ac.ReplicaSets[2].Members, expectedAutomationConfigMembers)
+	}
+	reconcileShardedClusterPending := func() {
+		_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+		assert.NoError(t, err)
+		assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+		assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+	}
+	makeShardsReady := func() {
+		for i := 0; i < mdb.Spec.ShardedCluster.Shards; i++ {
+			makeComponentStatefulSetReady(t, mgr.GetClient(), mdb.ShardNamespacedName(i))
+		}
+	}
+
+	// the StatefulSets are scaled up first, then the automation config once they are ready
+	reconcileShardedClusterPending()
+	assertShardMembers(4, 3)
+	reconcileShardedClusterPending()
+	assertShardMembers(4, 3)
+
+	makeShardsReady()
+	reconcileShardedClusterPending()
+	assertShardMembers(4, 4)
+
+	reconcileShardedClusterPending()
+	assertShardMembers(5, 4)
+	makeShardsReady()
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	assertShardMembers(5, 5)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+	assert.Equal(t, 5, mdb.Status.ShardedCluster[1].Replicas)
+}
+
+func TestShardedCluster_OnlyTheFirstSevenMembersVote(t *testing.T) {
+	mdb := newTestShardedCluster()
+	mdb.Spec.ShardedCluster.MembersPerShard = 9
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	members := ac.ReplicaSets[1].Members
+	assert.Len(t, members, 9)
+	totalVotes := 0
+	for _, member := range members {
+		totalVotes += member.Votes
+	}
+	assert.Equal(t, 7, totalVotes)
+	assert.Equal(t, 0, members[8].Votes)
+}
+
+func TestShardedCluster_ShardsCannotBeRemoved(t *testing.T) {
+	mdb := newTestShardedCluster()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.ShardedCluster.Shards = 1
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "shards can't be removed")
+}
@@ -112,6 +112,20 @@ func (o *optionBuilder) withStatefulSetReplicas(members int) *optionBuilder {
 	return o
 }
 
+func (o *optionBuilder) withMongoDBArbiters(arbiters int) *optionBuilder {
+	o.options = append(o.options, mongoDBArbitersOption{
+		mongoDBArbiters: arbiters,
+	})
+	return o
+}
+
+func (o *optionBuilder) withStatefulSetArbiters(arbiters int) *optionBuilder {
+	o.options = append(o.options, statefulSetArbitersOption{
+		arbiters: arbiters,
+	})
+	return o
+}
+
 func (o *optionBuilder) withMessage(severityLevel severity, msg string) *optionBuilder {
 	if apierrors.IsTransientMessage(msg) {
 		severityLevel = Debug
@@ -183,3 +197,103 @@ func (s statefulSetReplicasOption) ApplyOption(mdb *mdbv1.MongoDBCommunity) {
 func (s statefulSetReplicasOption) GetResult() (reconcile.Result, error) {
 	return result.OK()
 }
+
+type mongoDBArbitersOption struct {
+	mongoDBArbiters int
+}
+
+func (a mongoDBArbitersOption) ApplyOption(mdb *mdbv1.MongoDBCommunity) {
+	mdb.Status.CurrentMongoDBArbiters = a.mongoDBArbiters
+}
+
+func (a mongoDBArbitersOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+type statefulSetArbitersOption struct {
+	arbiters int
+}
+
+func (s statefulSetArbitersOption) ApplyOption(mdb *mdbv1.MongoDBCommunity) {
+	mdb.Status.CurrentStatefulSetArbitersReplicas = s.arbiters
+}
+
+func (s statefulSetArbitersOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+func (o *optionBuilder) withShardedCluster(components []mdbv1.ComponentStatus) *optionBuilder {
+	o.options = append(o.options, shardedClusterOption{
+		components: components,
+	})
+	return o
+}
+
+type shardedClusterOption struct {
+	components []mdbv1.ComponentStatus
+}
+
+func (s shardedClusterOption) ApplyOption(mdb *mdbv1.MongoDBCommunity) {
+	mdb.Status.ShardedCluster = s.components
+}
+
+func (s shardedClusterOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+func (o *optionBuilder) withCAHash(hash string) *optionBuilder {
+	o.options = append(o.options, caHashOption{
+		hash: hash,
+	})
+	return o
+}
+
+type caHashOption struct {
+	hash string
+}
+
+func (c caHashOption) ApplyOption(mdb *mdbv1.MongoDBCommunity) {
+	mdb.Status.TLS.CAHash = c.hash
+}
+
+func (c caHashOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+func (o *optionBuilder) withCARotationStage(stage mdbv1.CARotationStage) *optionBuilder {
+	o.options = append(o.options, caRotationStageOption{
+		stage: stage,
+	})
+	return o
+}
+
+type caRotationStageOption struct {
+	stage mdbv1.CARotationStage
+}
+
+func (c caRotationStageOption) ApplyOption(mdb *mdbv1.MongoDBCommunity) {
+	mdb.Status.TLS.CARotationStage = c.stage
+}
+
+func (c caRotationStageOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+func (o *optionBuilder) withKeyRotation(keyRotation mdbv1.KeyRotationStatus) *optionBuilder {
+	o.options = append(o.options, keyRotationOption{
+		keyRotation: keyRotation,
+	})
+	return o
+}
+
+type keyRotationOption struct {
+	keyRotation mdbv1.KeyRotationStatus
+}
+
+func (k keyRotationOption) ApplyOption(mdb *mdbv1.MongoDBCommunity) {
+	mdb.Status.KeyRotation = k.keyRotation
+}
+
+func (k keyRotationOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
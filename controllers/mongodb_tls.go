@@ -2,11 +2,16 @@ package controllers
 
 import (
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"net"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
@@ -30,30 +35,43 @@ const (
 	tlsOperatorSecretMountPath = "/var/lib/tls/server/" //nolint
 	tlsSecretCertName          = "tls.crt"              //nolint
 	tlsSecretKeyName           = "tls.key"
+	tlsModeArg                 = "net.tls.mode"
 )
 
-// validateTLSConfig will check that the configured ConfigMap and Secret exist and that they have the correct fields.
+// isTLSInUse returns true if TLS is enabled, or if it was enabled in the last successful configuration.
+// In the latter case TLS is being disabled, and the processes keep referencing the certificates
+// until the transition to disabled has completed.
+func isTLSInUse(mdb mdbv1.MongoDBCommunity) bool {
+	if mdb.Spec.Security.TLS.Enabled {
+		return true
+	}
+	prevSpec, ok, err := getLastSuccessfulSpec(mdb)
+	return err == nil && ok && prevSpec.Security.TLS.Enabled
+}
+
+// validateTLSConfig will check that the configured CA and Secret exist and that they have the correct fields.
 func (r *ReplicaSetReconciler) validateTLSConfig(mdb mdbv1.MongoDBCommunity) (bool, error) {
-	if !mdb.Spec.Security.TLS.Enabled {
+	if !isTLSInUse(mdb) {
 		return true, nil
 	}
 
 	r.log.Info("Ensuring TLS is correctly configured")
+	tls := mdb.Spec.Security.TLS
 
-	// Ensure CA ConfigMap exists
-	caData, err := configmap.ReadData(r.client, mdb.TLSConfigMapNamespacedName())
+	// Ensure CA ConfigMap or Secret exists
+	caData, err := readCAData(r.client, mdb)
 	if err != nil {
 		if apiErrors.IsNotFound(err) {
-			r.log.Warnf(`CA ConfigMap "%s" not found`, mdb.TLSConfigMapNamespacedName())
+			r.log.Warnf(`CA %s not found`, caResourceDescription(mdb))
 			return false, nil
 		}
 
 		return false, err
 	}
 
-	// Ensure ConfigMap has a "ca.crt" field
-	if cert, ok := caData[tlsCACertName]; !ok || cert == "" {
-		r.log.Warnf(`ConfigMap "%s" should have a CA certificate in field "%s"`, mdb.TLSConfigMapNamespacedName(), tlsCACertName)
+	// Ensure CA ConfigMap or Secret has the CA certificate field
+	if cert, ok := caData[tls.CAKey()]; !ok || cert == "" {
+		r.log.Warnf(`%s should have a CA certificate in field "%s"`, caResourceDescription(mdb), tls.CAKey())
 		return false, nil
 	}
 
@@ -68,46 +86,197 @@ func (r *ReplicaSetReconciler) validateTLSConfig(mdb mdbv1.MongoDBCommunity) (bo
 		return false, err
 	}
 
-	// Ensure Secret has "tls.crt" and "tls.key" fields
-	if key, ok := secretData[tlsSecretKeyName]; !ok || key == "" {
-		r.log.Warnf(`Secret "%s" should have a key in field "%s"`, mdb.TLSSecretNamespacedName(), tlsSecretKeyName)
+	// Ensure Secret has the certificate and key fields
+	if key, ok := secretData[tls.PrivateKeyKey()]; !ok || key == "" {
+		r.log.Warnf(`Secret "%s" should have a key in field "%s"`, mdb.TLSSecretNamespacedName(), tls.PrivateKeyKey())
 		return false, nil
 	}
-	if cert, ok := secretData[tlsSecretCertName]; !ok || cert == "" {
-		r.log.Warnf(`Secret "%s" should have a certificate in field "%s"`, mdb.TLSSecretNamespacedName(), tlsSecretKeyName)
+	if cert, ok := secretData[tls.CertificateKey()]; !ok || cert == "" {
+		r.log.Warnf(`Secret "%s" should have a certificate in field "%s"`, mdb.TLSSecretNamespacedName(), tls.CertificateKey())
 		return false, nil
 	}
 
+	// mongod presents its certificate to the clients connecting through the horizons
+	var horizonAddresses []string
+	for _, memberHorizons := range mdb.Spec.ReplicaSetHorizons {
+		for _, address := range memberHorizons {
+			horizonAddresses = append(horizonAddresses, address)
+		}
+	}
+	if err := verifyHorizonsCertificate(secretData[tls.CertificateKey()], horizonAddresses); err != nil {
+		return false, errors.Errorf(`the certificate in Secret "%s" can't be used with replicaSetHorizons: %s`, mdb.TLSSecretNamespacedName(), err)
+	}
+	if mdb.Spec.ExternalAccess.Enabled {
+		externalAddresses, _, err := r.getExternalAddresses(mdb)
+		if err != nil {
+			return false, err
+		}
+		if err := verifyHorizonsCertificate(secretData[tls.CertificateKey()], externalAddresses); err != nil {
+			return false, errors.Errorf(`the certificate in Secret "%s" can't be used with externalAccess: %s`, mdb.TLSSecretNamespacedName(), err)
+		}
+	}
+
+	// Before the old CA is removed from the bundle, the server certificate must have been replaced by one signed by the new CA
+	if getCARotationStage(mdb, caData[tls.CAKey()]) == mdbv1.CARotationStageOldCARemoval {
+		if err := verifyServerCertificate(caData[tls.CAKey()], secretData[tls.CertificateKey()]); err != nil {
+			r.log.Warnf(`The old CA can't be removed from %s before the certificate in Secret "%s" is replaced: %s`, caResourceDescription(mdb), mdb.TLSSecretNamespacedName(), err)
+			return false, nil
+		}
+	}
+
 	// Watch certificate-key secret to handle rotations
 	r.secretWatcher.Watch(mdb.TLSSecretNamespacedName(), mdb.NamespacedName())
 
+	// Watch CA ConfigMap or Secret to handle CA rotations
+	if tls.HasCASecret() {
+		r.secretWatcher.Watch(mdb.TLSCASecretNamespacedName(), mdb.NamespacedName())
+	} else {
+		r.configMapWatcher.Watch(mdb.TLSConfigMapNamespacedName(), mdb.NamespacedName())
+	}
+
 	r.log.Infof("Successfully validated TLS config")
 	return true, nil
 }
 
-// getTLSConfigModification creates a modification function which enables TLS in the automation config.
-// It will also ensure that the combined cert-key secret is created.
-func getTLSConfigModification(getUpdateCreator secret.GetUpdateCreator, mdb mdbv1.MongoDBCommunity) (automationconfig.Modification, error) {
-	if !mdb.Spec.Security.TLS.Enabled {
+// verifyHorizonsCertificate checks that the hostnames of the given horizon addresses are covered by the server certificate.
+// Certificates which can't be parsed are left to mongod to reject.
+func verifyHorizonsCertificate(cert string, addresses []string) error {
+	block, _ := pem.Decode([]byte(cert))
+	if block == nil {
+		return nil
+	}
+	serverCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil
+	}
+
+	var uncovered []string
+	for _, address := range addresses {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		if serverCert.VerifyHostname(host) != nil {
+			uncovered = append(uncovered, host)
+		}
+	}
+	if len(uncovered) > 0 {
+		sort.Strings(uncovered)
+		return errors.Errorf("the certificate doesn't cover the hostnames %s", strings.Join(uncovered, ", "))
+	}
+	return nil
+}
+
+// caGetter can read the CA certificate from either a ConfigMap or a Secret.
+type caGetter interface {
+	configmap.Getter
+	secret.Getter
+}
+
+// readCAData reads the data of the ConfigMap or Secret holding the CA certificate.
+func readCAData(getter caGetter, mdb mdbv1.MongoDBCommunity) (map[string]string, error) {
+	if mdb.Spec.Security.TLS.HasCASecret() {
+		return secret.ReadStringData(getter, mdb.TLSCASecretNamespacedName())
+	}
+	return configmap.ReadData(getter, mdb.TLSConfigMapNamespacedName())
+}
+
+// caResourceDescription describes the ConfigMap or Secret holding the CA certificate, for use in messages.
+func caResourceDescription(mdb mdbv1.MongoDBCommunity) string {
+	if mdb.Spec.Security.TLS.HasCASecret() {
+		return fmt.Sprintf(`Secret "%s"`, mdb.TLSCASecretNamespacedName())
+	}
+	return fmt.Sprintf(`ConfigMap "%s"`, mdb.TLSConfigMapNamespacedName())
+}
+
+// getTLSConfigModification creates a modification function which configures TLS in the automation config
+// for the next step of the transition towards the desired TLS mode.
+func getTLSConfigModification(getter secret.Getter, mdb mdbv1.MongoDBCommunity, currentAC automationconfig.AutomationConfig) (automationconfig.Modification, error) {
+	next := nextTLSMode(currentTLSMode(currentAC), mdb.Spec.Security.TLS.GetMode())
+
+	// a new deployment has no clients or members which depend on the current mode,
+	// so it can use the desired mode straight away.
+	if len(currentAC.Processes) == 0 {
+		next = mdb.Spec.Security.TLS.GetMode()
+	}
+
+	if next == automationconfig.TLSModeDisabled {
 		return automationconfig.NOOP(), nil
 	}
 
-	certKey, err := getCertAndKey(getUpdateCreator, mdb)
+	certKey, err := getCertAndKey(getter, mdb)
 	if err != nil {
 		return automationconfig.NOOP(), err
 	}
 
-	return tlsConfigModification(mdb, certKey), nil
+	return tlsConfigModification(mdb, certKey, next), nil
+}
+
+// currentTLSMode returns the TLS mode the given automation config is configured with.
+// Processes without an explicit mode don't use TLS.
+func currentTLSMode(ac automationconfig.AutomationConfig) automationconfig.TLSMode {
+	if len(ac.Processes) == 0 {
+		return automationconfig.TLSModeDisabled
+	}
+
+	// the mode is stored as a TLSMode when built by the operator, and as a string when read from the Secret.
+	switch mode := ac.Processes[0].Args26.Get(tlsModeArg).Data().(type) {
+	case automationconfig.TLSMode:
+		return mode
+	case string:
+		if mode != "" {
+			return automationconfig.TLSMode(mode)
+		}
+	}
+	return automationconfig.TLSModeDisabled
+}
+
+// nextTLSMode returns the TLS mode that should be configured in this reconciliation in order
+// to move from the current to the desired mode.
+func nextTLSMode(current, desired automationconfig.TLSMode) automationconfig.TLSMode {
+	order := make([]string, len(automationconfig.TLSModeTransition))
+	for i, mode := range automationconfig.TLSModeTransition {
+		order[i] = string(mode)
+	}
+	return automationconfig.TLSMode(nextStep(order, string(current), string(desired)))
+}
+
+// nextStep returns the mode which follows the current one on the way to the desired one, in the given order of the
+// TLS or cluster authentication modes. Moving between two modes always walks through every mode in between, one at a time.
+func nextStep(order []string, current, desired string) string {
+	currentIdx, desiredIdx := -1, -1
+	for i, step := range order {
+		if step == current {
+			currentIdx = i
+		}
+		if step == desired {
+			desiredIdx = i
+		}
+	}
+
+	// an unknown current mode can't be transitioned from, go straight to the desired one.
+	if currentIdx == -1 || desiredIdx == -1 || currentIdx == desiredIdx {
+		return desired
+	}
+	if currentIdx < desiredIdx {
+		return order[currentIdx+1]
+	}
+	return order[currentIdx-1]
+}
+
+// isTLSTransitionComplete returns true if the given automation config has the desired TLS mode.
+func isTLSTransitionComplete(mdb mdbv1.MongoDBCommunity, ac automationconfig.AutomationConfig) bool {
+	return currentTLSMode(ac) == mdb.Spec.Security.TLS.GetMode()
 }
 
 // getCertAndKey will fetch the certificate and key from the user-provided Secret.
 func getCertAndKey(getter secret.Getter, mdb mdbv1.MongoDBCommunity) (string, error) {
-	cert, err := secret.ReadKey(getter, tlsSecretCertName, mdb.TLSSecretNamespacedName())
+	cert, err := secret.ReadKey(getter, mdb.Spec.Security.TLS.CertificateKey(), mdb.TLSSecretNamespacedName())
 	if err != nil {
 		return "", err
 	}
 
-	key, err := secret.ReadKey(getter, tlsSecretKeyName, mdb.TLSSecretNamespacedName())
+	key, err := secret.ReadKey(getter, mdb.Spec.Security.TLS.PrivateKeyKey(), mdb.TLSSecretNamespacedName())
 	if err != nil {
 		return "", err
 	}
@@ -152,41 +321,64 @@ func tlsOperatorSecretFileName(certKey string) string {
 	return fmt.Sprintf("%x.pem", hash)
 }
 
-// tlsConfigModification will enable TLS in the automation config.
-func tlsConfigModification(mdb mdbv1.MongoDBCommunity, certKey string) automationconfig.Modification {
+// tlsConfigModification will enable TLS in the automation config with the given mode.
+func tlsConfigModification(mdb mdbv1.MongoDBCommunity, certKey string, mode automationconfig.TLSMode) automationconfig.Modification {
+	tls := mdb.Spec.Security.TLS
 	caCertificatePath := tlsCAMountPath + tlsCACertName
 	certificateKeyPath := tlsOperatorSecretMountPath + tlsOperatorSecretFileName(certKey)
 
-	mode := automationconfig.TLSModeRequired
-	if mdb.Spec.Security.TLS.Optional {
-		// TLSModePreferred requires server-server connections to use TLS but makes it optional for clients.
-		mode = automationconfig.TLSModePreferred
-	}
-
 	return func(config *automationconfig.AutomationConfig) {
 		// Configure CA certificate for agent
 		config.TLSConfig.CAFilePath = caCertificatePath
 
+		// The agent presents the server certificate when the servers require clients to authenticate with one
+		if tls.RequireClientCertificates {
+			config.TLSConfig.ClientCertificateMode = automationconfig.ClientCertificateModeRequired
+			config.TLSConfig.AutoPEMKeyFilePath = certificateKeyPath
+		}
+
 		for i := range config.Processes {
 			args := config.Processes[i].Args26
 
-			args.Set("net.tls.mode", mode)
+			args.Set(tlsModeArg, mode)
 			args.Set("net.tls.CAFile", caCertificatePath)
 			args.Set("net.tls.certificateKeyFile", certificateKeyPath)
-			args.Set("net.tls.allowConnectionsWithoutCertificates", true)
+			args.Set("net.tls.allowConnectionsWithoutCertificates", !tls.RequireClientCertificates)
+
+			if len(tls.DisabledProtocols) > 0 {
+				args.Set("net.tls.disabledProtocols", joinTLSProtocols(tls.DisabledProtocols))
+			}
+			if tls.AllowInvalidHostnames {
+				args.Set("net.tls.allowInvalidHostnames", true)
+			}
+			if tls.FIPSMode {
+				args.Set("net.tls.FIPSMode", true)
+			}
+			if tls.CipherConfig != "" {
+				args.Set("setParameter.opensslCipherConfig", tls.CipherConfig)
+			}
 		}
 	}
 }
 
-// buildTLSPodSpecModification will add the TLS init container and volumes to the pod template if TLS is enabled.
+// joinTLSProtocols returns the protocols in the comma separated format expected by net.tls.disabledProtocols.
+func joinTLSProtocols(protocols []mdbv1.TLSProtocol) string {
+	names := make([]string, len(protocols))
+	for i, protocol := range protocols {
+		names[i] = string(protocol)
+	}
+	return strings.Join(names, ",")
+}
+
+// buildTLSPodSpecModification will add the TLS init container and volumes to the pod template if TLS is in use.
 func buildTLSPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Modification {
-	if !mdb.Spec.Security.TLS.Enabled {
+	if !isTLSInUse(mdb) {
 		return podtemplatespec.NOOP()
 	}
 
-	// Configure a volume which mounts the CA certificate from a ConfigMap
+	// Configure a volume which mounts the CA certificate from a ConfigMap or Secret
 	// The certificate is used by both mongod and the agent
-	caVolume := statefulset.CreateVolumeFromConfigMap("tls-ca", mdb.Spec.Security.TLS.CaConfigMap.Name)
+	caVolume := buildCAVolume(mdb)
 	caVolumeMount := statefulset.CreateVolumeMount(caVolume.Name, tlsCAMountPath, statefulset.WithReadOnly(true))
 
 	// Configure a volume which mounts the secret holding the server key and certificate
@@ -204,3 +396,23 @@ func buildTLSPodSpecModification(mdb mdbv1.MongoDBCommunity) podtemplatespec.Mod
 		podtemplatespec.WithVolumeMounts(construct.MongodbName, tlsSecretVolumeMount, caVolumeMount),
 	)
 }
+
+// buildCAVolume creates the volume holding the CA certificate. The certificate is always mounted
+// as "ca.crt", whichever key it is stored under.
+func buildCAVolume(mdb mdbv1.MongoDBCommunity) corev1.Volume {
+	tls := mdb.Spec.Security.TLS
+	caItems := []corev1.KeyToPath{{Key: tls.CAKey(), Path: tlsCACertName}}
+
+	if tls.HasCASecret() {
+		// The Secret may hold the server key as well, as with cert-manager, so only the CA is mounted
+		return statefulset.CreateVolumeFromSecret("tls-ca", tls.CaCertificateSecret.Name, func(v *corev1.Volume) {
+			v.Secret.Items = caItems
+		})
+	}
+
+	caVolume := statefulset.CreateVolumeFromConfigMap("tls-ca", tls.CaConfigMap.Name)
+	if tls.CAKey() != tlsCACertName {
+		caVolume.ConfigMap.Items = caItems
+	}
+	return caVolume
+}
@@ -85,7 +85,7 @@ func TestAutomationConfig_IsCorrectlyConfiguredWithTLS(t *testing.T) {
 		err := createTLSSecretAndConfigMap(client, mdb)
 		assert.NoError(t, err)
 
-		tlsModification, err := getTLSConfigModification(client, mdb)
+		tlsModification, err := getTLSConfigModification(client, mdb, automationconfig.AutomationConfig{})
 		assert.NoError(t, err)
 		ac, err := buildAutomationConfig(mdb, automationconfig.Auth{}, automationconfig.AutomationConfig{}, tlsModification)
 		assert.NoError(t, err)
@@ -147,6 +147,225 @@ func TestAutomationConfig_IsCorrectlyConfiguredWithTLS(t *testing.T) {
 	})
 }
 
+func TestNextTLSMode(t *testing.T) {
+	tests := []struct {
+		current  automationconfig.TLSMode
+		desired  automationconfig.TLSMode
+		expected automationconfig.TLSMode
+	}{
+		{automationconfig.TLSModeDisabled, automationconfig.TLSModeDisabled, automationconfig.TLSModeDisabled},
+		{automationconfig.TLSModeDisabled, automationconfig.TLSModeRequired, automationconfig.TLSModeAllowed},
+		{automationconfig.TLSModeAllowed, automationconfig.TLSModeRequired, automationconfig.TLSModePreferred},
+		{automationconfig.TLSModePreferred, automationconfig.TLSModeRequired, automationconfig.TLSModeRequired},
+		{automationconfig.TLSModeRequired, automationconfig.TLSModeDisabled, automationconfig.TLSModePreferred},
+		{automationconfig.TLSModeRequired, automationconfig.TLSModeAllowed, automationconfig.TLSModePreferred},
+		{automationconfig.TLSModeAllowed, automationconfig.TLSModeDisabled, automationconfig.TLSModeDisabled},
+		{"unknown", automationconfig.TLSModeRequired, automationconfig.TLSModeRequired},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, nextTLSMode(test.current, test.desired))
+	}
+}
+
+func TestTLSConfigModification_WalksThroughTransitionWhenDisabling(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	c := mdbClient.NewClient(client.NewManager(&mdb).GetClient())
+	assert.NoError(t, createTLSSecretAndConfigMap(c, mdb))
+
+	// start from an existing deployment which requires TLS
+	tlsModification, err := getTLSConfigModification(c, mdb, automationconfig.AutomationConfig{})
+	assert.NoError(t, err)
+	previousAC, err := buildAutomationConfig(mdb, automationconfig.Auth{}, automationconfig.AutomationConfig{}, tlsModification)
+	assert.NoError(t, err)
+	assert.Equal(t, automationconfig.TLSModeRequired, currentTLSMode(previousAC))
+
+	mdb.Spec.Security.TLS.Enabled = false
+	expectedModes := []automationconfig.TLSMode{
+		automationconfig.TLSModePreferred,
+		automationconfig.TLSModeAllowed,
+		automationconfig.TLSModeDisabled,
+		automationconfig.TLSModeDisabled,
+	}
+	assert.False(t, isTLSTransitionComplete(mdb, previousAC))
+	for _, expectedMode := range expectedModes {
+		tlsModification, err := getTLSConfigModification(c, mdb, previousAC)
+		assert.NoError(t, err)
+		ac, err := buildAutomationConfig(mdb, automationconfig.Auth{}, previousAC, tlsModification)
+		assert.NoError(t, err)
+
+		assert.Equal(t, expectedMode, currentTLSMode(ac))
+		previousAC = ac
+	}
+	assert.True(t, isTLSTransitionComplete(mdb, previousAC))
+
+	for _, process := range previousAC.Processes {
+		assert.False(t, process.Args26.Has("net.tls"))
+	}
+	assert.Equal(t, "", previousAC.TLSConfig.CAFilePath)
+}
+
+func TestTLSConfigModification_ModeTakesPrecedenceOverOptional(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Spec.Security.TLS.Optional = true
+	mdb.Spec.Security.TLS.Mode = automationconfig.TLSModeAllowed
+	c := mdbClient.NewClient(client.NewManager(&mdb).GetClient())
+	assert.NoError(t, createTLSSecretAndConfigMap(c, mdb))
+
+	tlsModification, err := getTLSConfigModification(c, mdb, automationconfig.AutomationConfig{})
+	assert.NoError(t, err)
+	ac, err := buildAutomationConfig(mdb, automationconfig.Auth{}, automationconfig.AutomationConfig{}, tlsModification)
+	assert.NoError(t, err)
+
+	for _, process := range ac.Processes {
+		assert.Equal(t, automationconfig.TLSModeAllowed, process.Args26.Get("net.tls.mode").Data())
+	}
+}
+
+func TestStatefulSet_KeepsTLSVolumesWhileDisabling(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Annotations = map[string]string{
+		lastSuccessfulConfiguration: `{"security":{"tls":{"enabled":true}}}`,
+	}
+	mdb.Spec.Security.TLS.Enabled = false
+
+	sts, err := buildStatefulSet(mdb)
+	assert.NoError(t, err)
+	_, err = getVolumeByName(sts, "tls-secret")
+	assert.NoError(t, err)
+
+	// once the transition has completed the volumes are removed
+	mdb.Annotations[lastSuccessfulConfiguration] = `{"security":{"tls":{"enabled":false}}}`
+	sts, err = buildStatefulSet(mdb)
+	assert.NoError(t, err)
+	_, err = getVolumeByName(sts, "tls-secret")
+	assert.Error(t, err)
+}
+
+func TestTLSConfigModification_ClientCertificatesAndProtocols(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Spec.Security.TLS.RequireClientCertificates = true
+	mdb.Spec.Security.TLS.DisabledProtocols = []mdbv1.TLSProtocol{mdbv1.TLSProtocol10, mdbv1.TLSProtocol11}
+	mdb.Spec.Security.TLS.AllowInvalidHostnames = true
+	mdb.Spec.Security.TLS.FIPSMode = true
+	mdb.Spec.Security.TLS.CipherConfig = "HIGH:!EXPORT:!aNULL@STRENGTH"
+	c := mdbClient.NewClient(client.NewManager(&mdb).GetClient())
+	assert.NoError(t, createTLSSecretAndConfigMap(c, mdb))
+
+	tlsModification, err := getTLSConfigModification(c, mdb, automationconfig.AutomationConfig{})
+	assert.NoError(t, err)
+	ac, err := buildAutomationConfig(mdb, automationconfig.Auth{}, automationconfig.AutomationConfig{}, tlsModification)
+	assert.NoError(t, err)
+
+	certificateKeyPath := tlsOperatorSecretMountPath + tlsOperatorSecretFileName("CERT\nKEY")
+	assert.Equal(t, &automationconfig.TLS{
+		CAFilePath:            tlsCAMountPath + tlsCACertName,
+		ClientCertificateMode: automationconfig.ClientCertificateModeRequired,
+		AutoPEMKeyFilePath:    certificateKeyPath,
+	}, ac.TLSConfig)
+
+	for _, process := range ac.Processes {
+		assert.False(t, process.Args26.Get("net.tls.allowConnectionsWithoutCertificates").MustBool())
+		assert.Equal(t, "TLS1_0,TLS1_1", process.Args26.Get("net.tls.disabledProtocols").Str())
+		assert.True(t, process.Args26.Get("net.tls.allowInvalidHostnames").MustBool())
+		assert.True(t, process.Args26.Get("net.tls.FIPSMode").MustBool())
+		assert.Equal(t, "HIGH:!EXPORT:!aNULL@STRENGTH", process.Args26.Get("setParameter.opensslCipherConfig").Str())
+	}
+}
+
+func TestReplicaSet_FailsWhenAllTLSProtocolsAreDisabled(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Spec.Security.TLS.DisabledProtocols = mdbv1.TLSProtocols
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+}
+
+func TestReplicaSet_TLSWithCertManagerSecretLayout(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Spec.Security.TLS.CaConfigMap = mdbv1.LocalObjectReference{}
+	mdb.Spec.Security.TLS.CaCertificateSecret = mdb.Spec.Security.TLS.CertificateKeySecret
+	mgr := client.NewManager(&mdb)
+
+	s := secret.Builder().
+		SetName(mdb.Spec.Security.TLS.CertificateKeySecret.Name).
+		SetNamespace(mdb.Namespace).
+		SetField("tls.crt", "CERT").
+		SetField("tls.key", "KEY").
+		SetField("ca.crt", "CA").
+		Build()
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &s))
+
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	caVolume, err := getVolumeByName(sts, "tls-ca")
+	assert.NoError(t, err)
+	assert.Nil(t, caVolume.ConfigMap)
+	assert.Equal(t, mdb.Spec.Security.TLS.CaCertificateSecret.Name, caVolume.Secret.SecretName)
+	assert.Equal(t, []corev1.KeyToPath{{Key: "ca.crt", Path: tlsCACertName}}, caVolume.Secret.Items)
+	assert.Equal(t, caHash("CA"), sts.Spec.Template.Annotations[caHashAnnotation])
+}
+
+func TestReplicaSet_TLSWithCustomKeys(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Spec.Security.TLS.Keys = mdbv1.TLSKeys{
+		Certificate: "server.pem",
+		PrivateKey:  "server.key",
+		CA:          "root.pem",
+	}
+	mgr := client.NewManager(&mdb)
+
+	s := secret.Builder().
+		SetName(mdb.Spec.Security.TLS.CertificateKeySecret.Name).
+		SetNamespace(mdb.Namespace).
+		SetField("server.pem", "CERT").
+		SetField("server.key", "KEY").
+		Build()
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &s))
+	configMap := configmap.Builder().
+		SetName(mdb.Spec.Security.TLS.CaConfigMap.Name).
+		SetNamespace(mdb.Namespace).
+		SetField("root.pem", "CA").
+		Build()
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &configMap))
+
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	certificateKey, err := secret.ReadKey(mgr.Client, tlsOperatorSecretFileName("CERT\nKEY"), mdb.TLSOperatorSecretNamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, "CERT\nKEY", certificateKey)
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	caVolume, err := getVolumeByName(sts, "tls-ca")
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.KeyToPath{{Key: "root.pem", Path: tlsCACertName}}, caVolume.ConfigMap.Items)
+}
+
+func TestReplicaSet_TLSIsPendingWithoutCustomKeys(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Spec.Security.TLS.Keys.Certificate = "server.pem"
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+
+	r := NewReconciler(mgr)
+	ok, err := r.validateTLSConfig(mdb)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
 func TestTLSOperatorSecret(t *testing.T) {
 	t.Run("Secret is created if it doesn't exist", func(t *testing.T) {
 		mdb := newTestReplicaSetWithTLS()
@@ -241,3 +460,60 @@ func createTLSSecretAndConfigMap(c k8sClient.Client, mdb mdbv1.MongoDBCommunity)
 
 	return nil
 }
+
+func newHorizonsReplicaSet() mdbv1.MongoDBCommunity {
+	mdb := newTestReplicaSetWithTLS()
+	mdb.Spec.ReplicaSetHorizons = mdbv1.ReplicaSetHorizonConfiguration{
+		{"external": "my-rs-0.example.com:27017"},
+		{"external": "my-rs-1.example.com:27017"},
+		{"external": "my-rs-2.example.com:27017"},
+	}
+	return mdb
+}
+
+func TestReplicaSetHorizons_AreKeptWhileScalingDown(t *testing.T) {
+	mdb := newHorizonsReplicaSet()
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+	assert.Len(t, readAutomationConfig(t, mgr, mdb).ReplicaSets[0].Members[2].Horizons, 1)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.Members = 2
+	mdb.Spec.ReplicaSetHorizons = mdb.Spec.ReplicaSetHorizons[:2]
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "replicaSetHorizons must keep an entry for each of the 3 current members until the scale down is complete")
+}
+
+func TestReplicaSetHorizons_MustBeCoveredByTheCertificate(t *testing.T) {
+	ca, caKey := generateCertificate(t, "ca", nil, nil)
+	serverCert, _ := generateCertificate(t, "server", parseCertificate(t, ca), caKey, "*.my-rs-svc.my-ns.svc.cluster.local", "my-rs-0.example.com", "my-rs-1.example.com")
+
+	mdb := newHorizonsReplicaSet()
+	mgr := client.NewManager(&mdb)
+	assert.NoError(t, createTLSSecretAndConfigMap(mgr.GetClient(), mdb))
+	s, err := mgr.Client.GetSecret(mdb.TLSSecretNamespacedName())
+	assert.NoError(t, err)
+	s.Data[tlsSecretCertName] = []byte(serverCert)
+	assert.NoError(t, mgr.Client.UpdateSecret(s))
+
+	r := NewReconciler(mgr)
+	_, err = r.validateTLSConfig(mdb)
+	assert.EqualError(t, err, `the certificate in Secret "my-ns/certificateKeySecret" can't be used with replicaSetHorizons: the certificate doesn't cover the hostnames my-rs-2.example.com`)
+
+	// a wildcard name covers all the members
+	serverCert, _ = generateCertificate(t, "server", parseCertificate(t, ca), caKey, "*.example.com")
+	s.Data[tlsSecretCertName] = []byte(serverCert)
+	assert.NoError(t, mgr.Client.UpdateSecret(s))
+
+	ok, err := r.validateTLSConfig(mdb)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
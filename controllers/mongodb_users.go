@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/validation"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
+)
+
+const defaultUserDatabase = "admin"
+
+// managedUser is a user the operator has created in the deployment.
+type managedUser struct {
+	User                       string `json:"user"`
+	DB                         string `json:"db"`
+	ScramCredentialsSecretName string `json:"scramCredentialsSecretName"`
+}
+
+// rejectedUser is a MongoDBCommunityUser which can't be configured in the deployment it references.
+type rejectedUser struct {
+	user   mdbv1.MongoDBCommunityUser
+	reason string
+}
+
+// communityUsersConfigurable is a scram.Configurable which also configures the users
+// declared in MongoDBCommunityUser resources.
+type communityUsersConfigurable struct {
+	mdbv1.MongoDBCommunity
+	communityUsers []mdbv1.MongoDBCommunityUser
+}
+
+// GetScramUsers returns the users from the spec, followed by the users declared in MongoDBCommunityUser resources.
+func (c communityUsersConfigurable) GetScramUsers() []scram.User {
+	users := c.MongoDBCommunity.GetScramUsers()
+	for _, u := range c.communityUsers {
+		users = append(users, c.MongoDBCommunity.GetCommunityUserScramUser(u))
+	}
+	return users
+}
+
+// getCommunityUsers returns the MongoDBCommunityUser resources referencing the MongoDBCommunity resource.
+// The users which can be configured in the deployment are returned first, followed by the rejected ones.
+func (r *ReplicaSetReconciler) getCommunityUsers(mdb mdbv1.MongoDBCommunity) ([]mdbv1.MongoDBCommunityUser, []rejectedUser, error) {
+	userList := mdbv1.MongoDBCommunityUserList{}
+	if err := r.client.List(context.TODO(), &userList); err != nil {
+		return nil, nil, errors.Errorf("could not list MongoDBCommunityUser resources: %s", err)
+	}
+
+	// users from the spec always take precedence over the ones declared in MongoDBCommunityUser resources.
+	usernames := map[string]bool{}
+	scramSecretNames := map[string]bool{}
+	for _, u := range mdb.Spec.Users {
+		usernames[automationconfig.UserKey(u.DB, u.Name)] = true
+		scramSecretNames[u.ScramCredentialsSecretName] = true
+	}
+
+	var accepted []mdbv1.MongoDBCommunityUser
+	var rejected []rejectedUser
+	for _, u := range userList.Items {
+		if u.MongoDBResourceNamespacedName() != mdb.NamespacedName() {
+			continue
+		}
+
+		reason := ""
+		if !mdb.IsNamespaceAllowed(u.Namespace) {
+			reason = fmt.Sprintf("namespace %s is not allowed by MongoDBCommunity %s", u.Namespace, mdb.NamespacedName())
+		} else if err := validation.ValidateUser(mdb.Spec, u.Spec.MongoDBUser); err != nil {
+			reason = err.Error()
+		} else if usernames[automationconfig.UserKey(u.Spec.DB, u.Spec.Name)] {
+			reason = fmt.Sprintf("user %s already exists in MongoDBCommunity %s", automationconfig.UserKey(u.Spec.DB, u.Spec.Name), mdb.NamespacedName())
+		} else if scramSecretNames[u.Spec.ScramCredentialsSecretName] {
+			reason = fmt.Sprintf("scramCredentialsSecretName %s is already used in MongoDBCommunity %s", u.Spec.ScramCredentialsSecretName, mdb.NamespacedName())
+		} else if err := validateCommunityUserScramSecretName(mdb, u); err != nil {
+			reason = err.Error()
+		}
+
+		if reason != "" {
+			rejected = append(rejected, rejectedUser{user: u, reason: reason})
+			continue
+		}
+
+		usernames[automationconfig.UserKey(u.Spec.DB, u.Spec.Name)] = true
+		scramSecretNames[u.Spec.ScramCredentialsSecretName] = true
+		accepted = append(accepted, u)
+	}
+	return accepted, rejected, nil
+}
+
+// validateCommunityUserScramSecretName checks that the user doesn't store its SCRAM credentials in a Secret of the
+// namespace of the MongoDBCommunity resource it has no claim to. A user from another namespace must prefix the name
+// with its own namespace, and no user can take the Secret of the credentials of the agent.
+func validateCommunityUserScramSecretName(mdb mdbv1.MongoDBCommunity, u mdbv1.MongoDBCommunityUser) error {
+	if u.Namespace != mdb.Namespace && !strings.HasPrefix(u.Spec.ScramCredentialsSecretName, u.Namespace+"-") {
+		return errors.Errorf("scramCredentialsSecretName %s must start with %s- as the user is not in the namespace of MongoDBCommunity %s", u.Spec.ScramCredentialsSecretName, u.Namespace, mdb.NamespacedName())
+	}
+	if u.Spec.GetScramCredentialsSecretName() == mdb.GetAgentScramCredentialsNamespacedName().Name {
+		return errors.Errorf("scramCredentialsSecretName %s is reserved for the agent of MongoDBCommunity %s", u.Spec.ScramCredentialsSecretName, mdb.NamespacedName())
+	}
+	return nil
+}
+
+// watchCommunityUserSecrets watches the password secrets of the MongoDBCommunityUser resources, so that
+// changing the password of a user triggers a reconciliation of the MongoDBCommunity resource.
+func (r *ReplicaSetReconciler) watchCommunityUserSecrets(mdb mdbv1.MongoDBCommunity, users []mdbv1.MongoDBCommunityUser) {
+	for _, u := range users {
+		r.secretWatcher.Watch(u.PasswordSecretNamespacedName(), mdb.NamespacedName())
+	}
+}
+
+// updateCommunityUsersStatus sets the status of the MongoDBCommunityUser resources referencing the MongoDBCommunity resource.
+// Accepted users get the given phase, rejected users are marked as failed with the reason.
+func (r *ReplicaSetReconciler) updateCommunityUsersStatus(mdb mdbv1.MongoDBCommunity, phase mdbv1.Phase) error {
+	accepted, rejected, err := r.getCommunityUsers(mdb)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range accepted {
+		if err := r.updateCommunityUserStatus(u, mdbv1.MongoDBCommunityUserStatus{Phase: phase}); err != nil {
+			return err
+		}
+	}
+	return r.updateRejectedCommunityUsersStatus(rejected)
+}
+
+// updateRejectedCommunityUsersStatus marks the rejected users as failed with the reason. It is called as soon as the
+// users are read, so that they are reported even if the MongoDBCommunity resource doesn't reach the Running phase.
+func (r *ReplicaSetReconciler) updateRejectedCommunityUsersStatus(rejected []rejectedUser) error {
+	for _, rejected := range rejected {
+		if err := r.updateCommunityUserStatus(rejected.user, mdbv1.MongoDBCommunityUserStatus{Phase: mdbv1.Failed, Message: rejected.reason}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateCommunityUserStatus updates the status of the user if it has changed.
+func (r *ReplicaSetReconciler) updateCommunityUserStatus(user mdbv1.MongoDBCommunityUser, status mdbv1.MongoDBCommunityUserStatus) error {
+	if user.Status == status {
+		return nil
+	}
+	user.Status = status
+	if err := r.client.Status().Update(context.TODO(), &user); err != nil {
+		return errors.Errorf("could not update the status of MongoDBCommunityUser %s: %s", user.NamespacedName(), err)
+	}
+	return nil
+}
+
+// communityUserToMongoDB maps a MongoDBCommunityUser to a reconciliation of the MongoDBCommunity resource it references.
+func communityUserToMongoDB(obj k8sClient.Object) []reconcile.Request {
+	user, ok := obj.(*mdbv1.MongoDBCommunityUser)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: user.MongoDBResourceNamespacedName()}}
+}
+
+// toManagedUsers returns the users which are created by the operator when the given users are configured.
+func toManagedUsers(users []scram.User) []managedUser {
+	managedUsers := []managedUser{}
+	for _, u := range users {
+		db := u.Database
+		if db == "" {
+			db = defaultUserDatabase
+		}
+		managedUsers = append(managedUsers, managedUser{User: u.Username, DB: db, ScramCredentialsSecretName: u.ScramCredentialsSecretName})
+	}
+	return managedUsers
+}
+
+// getManagedUsers returns the users saved in the managedUsers annotation by the last successful reconciliation.
+func getManagedUsers(mdb mdbv1.MongoDBCommunity) ([]managedUser, error) {
+	saved, ok := mdb.Annotations[annotations.ManagedUsers]
+	if !ok {
+		return nil, nil
+	}
+	var managedUsers []managedUser
+	if err := json.Unmarshal([]byte(saved), &managedUsers); err != nil {
+		return nil, errors.Errorf("could not read the %s annotation: %s", annotations.ManagedUsers, err)
+	}
+	return managedUsers, nil
+}
+
+// getRemovedUsers returns the users created by the operator which are not declared anymore. The users whose
+// MongoDBCommunityUser resource still exists but is rejected, e.g. after an invalid edit, are not removed: they
+// are returned separately and are kept in the deployment as they were last configured.
+func getRemovedUsers(mdb mdbv1.MongoDBCommunity, users []scram.User, rejected []rejectedUser) ([]managedUser, []managedUser, error) {
+	previous, err := getManagedUsers(mdb)
+	if err != nil {
+		return nil, nil, err
+	}
+	current := map[string]bool{}
+	for _, u := range toManagedUsers(users) {
+		current[fmt.Sprintf("%s.%s", u.DB, u.User)] = true
+	}
+	declared := map[string]bool{}
+	for _, u := range rejected {
+		declared[automationconfig.UserKey(u.user.Spec.DB, u.user.Spec.Name)] = true
+	}
+
+	var removed, kept []managedUser
+	for _, u := range previous {
+		key := fmt.Sprintf("%s.%s", u.DB, u.User)
+		if current[key] {
+			continue
+		}
+		if declared[key] {
+			kept = append(kept, u)
+			continue
+		}
+		removed = append(removed, u)
+	}
+	return removed, kept, nil
+}
+
+// previousUsers returns the entries of the given users in the automation config, so that they keep being configured
+// as they were last deployed.
+func previousUsers(ac automationconfig.AutomationConfig, users []managedUser) []automationconfig.MongoDBUser {
+	var previous []automationconfig.MongoDBUser
+	for _, u := range users {
+		for _, acUser := range ac.Auth.Users {
+			if acUser.Username == u.User && acUser.Database == u.DB {
+				previous = append(previous, acUser)
+			}
+		}
+	}
+	return previous
+}
+
+// toDeletedUsers returns the automation config entries which remove the given users from the deployment.
+func toDeletedUsers(users []managedUser) []automationconfig.DeletedUser {
+	var deletedUsers []automationconfig.DeletedUser
+	for _, u := range users {
+		deletedUsers = append(deletedUsers, automationconfig.DeletedUser{User: u.User, Dbs: []string{u.DB}})
+	}
+	return deletedUsers
+}
+
+// cleanupRemovedUsers deletes the SCRAM credentials secrets which are not used anymore, either because their user has
+// been removed from the deployment or because it now uses another secret, and records the users currently created by
+// the operator in the managedUsers annotation.
+// It must only be called once the agents have reached goal state, as the removed users are dropped by then.
+func (r *ReplicaSetReconciler) cleanupRemovedUsers(mdb mdbv1.MongoDBCommunity) error {
+	communityUsers, rejected, err := r.getCommunityUsers(mdb)
+	if err != nil {
+		return err
+	}
+	users := communityUsersConfigurable{MongoDBCommunity: mdb, communityUsers: communityUsers}.GetScramUsers()
+
+	previous, err := getManagedUsers(mdb)
+	if err != nil {
+		return err
+	}
+	_, kept, err := getRemovedUsers(mdb, users, rejected)
+	if err != nil {
+		return err
+	}
+	managed := append(toManagedUsers(users), kept...)
+
+	// a new user can reuse the credentials secret name of a removed one.
+	secretsInUse := map[string]bool{}
+	for _, u := range managed {
+		secretsInUse[u.ScramCredentialsSecretName] = true
+	}
+	for _, u := range previous {
+		if u.ScramCredentialsSecretName == "" || secretsInUse[u.ScramCredentialsSecretName] {
+			continue
+		}
+		nsName := types.NamespacedName{Name: u.ScramCredentialsSecretName, Namespace: mdb.Namespace}
+		if err := r.secretBackend.DeleteSecret(nsName); err != nil && !apiErrors.IsNotFound(err) {
+			return errors.Errorf("could not delete the SCRAM credentials secret %s of user %s: %s", nsName, u.User, err)
+		}
+		r.log.Infof("Deleted the SCRAM credentials secret %s of user %s.%s, which is not used anymore", nsName, u.DB, u.User)
+	}
+
+	managedUsers, err := json.Marshal(managed)
+	if err != nil {
+		return err
+	}
+	return annotations.SetAnnotations(&mdb, map[string]string{annotations.ManagedUsers: string(managedUsers)}, r.client)
+}
+
+// toInternalUser returns the default user of one of the operator's features, overridden by the configuration of the owner.
+func toInternalUser(config mdbv1.InternalUser, defaults mdbv1.MongoDBUser) mdbv1.MongoDBUser {
+	user := defaults
+	if config.Name != "" {
+		user.Name = config.Name
+	}
+	if len(config.Roles) > 0 {
+		user.Roles = config.Roles
+	}
+	if config.PasswordSecretRef.Name != "" {
+		user.PasswordSecretRef = config.PasswordSecretRef
+	}
+	if config.ScramCredentialsSecretName != "" {
+		user.ScramCredentialsSecretName = config.ScramCredentialsSecretName
+	}
+	return user
+}
+
+// insertInternalUser adds the user of one of the operator's features to the users of the resource.
+// A user declared by the owner with the same name is never replaced.
+func insertInternalUser(mdb *mdbv1.MongoDBCommunity, user mdbv1.MongoDBUser, feature string) error {
+	for _, specUser := range mdb.Spec.Users {
+		if automationconfig.UserKey(specUser.DB, specUser.Name) == automationconfig.UserKey(user.DB, user.Name) {
+			return errors.Errorf("user %s is declared in spec.users, set spec.%s.user.name to create the %s user with another name", automationconfig.UserKey(user.DB, user.Name), feature, feature)
+		}
+	}
+	mdb.Spec.Users = append(mdb.Spec.Users, user)
+	return nil
+}
+
+// ensureInternalUser adds the user of one of the operator's features to the resource, generating its password
+// if its secret doesn't exist, and stores its connection string in the given secret.
+func (r *ReplicaSetReconciler) ensureInternalUser(mdb *mdbv1.MongoDBCommunity, user mdbv1.MongoDBUser, feature, uriSecretName string, port int) error {
+	if err := insertInternalUser(mdb, user, feature); err != nil {
+		return err
+	}
+	if err := r.createUserSecret(*mdb, user); err != nil {
+		return errors.Errorf("error ensuring the user secret exists: %s", err)
+	}
+	if err := r.ensureMongoDbUriSecret(*mdb, user, uriSecretName, port); err != nil {
+		return errors.Errorf("error ensuring the MongoDB URI secret exists: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,575 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scram"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scramcredentials"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/annotations"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/client"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/container"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestCommunityUser(name, namespace string, mdb mdbv1.MongoDBCommunity) mdbv1.MongoDBCommunityUser {
+	return mdbv1.MongoDBCommunityUser{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: mdbv1.MongoDBCommunityUserSpec{
+			MongoDBResourceRef: mdbv1.MongoDBResourceReference{Name: mdb.Name, Namespace: mdb.Namespace},
+			MongoDBUser: mdbv1.MongoDBUser{
+				Name:                       name,
+				DB:                         "admin",
+				PasswordSecretRef:          mdbv1.SecretKeyReference{Name: name + "-password"},
+				ScramCredentialsSecretName: name,
+				Roles:                      []mdbv1.Role{{Name: "readWrite", DB: "app"}},
+			},
+		},
+	}
+}
+
+// createCommunityUser creates the user together with its password secret.
+func createCommunityUser(t *testing.T, mgr *client.MockedManager, user mdbv1.MongoDBCommunityUser) {
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &user))
+	s := secret.Builder().
+		SetName(user.Spec.PasswordSecretRef.Name).
+		SetNamespace(user.Namespace).
+		SetField("password", "my-password").
+		Build()
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &s))
+}
+
+func reconcileAndReadAutomationConfig(t *testing.T, mgr *client.MockedManager, mdb mdbv1.MongoDBCommunity) automationconfig.AutomationConfig {
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	return ac
+}
+
+func getCommunityUser(t *testing.T, mgr *client.MockedManager, nsName types.NamespacedName) mdbv1.MongoDBCommunityUser {
+	user := mdbv1.MongoDBCommunityUser{}
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), nsName, &user))
+	return user
+}
+
+func acUsernames(ac automationconfig.AutomationConfig) []string {
+	var usernames []string
+	for _, u := range ac.Auth.Users {
+		usernames = append(usernames, u.Username)
+	}
+	return usernames
+}
+
+func TestCommunityUser_IsAddedToTheDeployment(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+	user := newTestCommunityUser("app-user", mdb.Namespace, mdb)
+	createCommunityUser(t, mgr, user)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	assert.Contains(t, acUsernames(ac), "app-user")
+	for _, acUser := range ac.Auth.Users {
+		if acUser.Username == "app-user" {
+			assert.Equal(t, []automationconfig.Role{{Role: "readWrite", Database: "app"}}, acUser.Roles)
+			assert.NotNil(t, acUser.ScramSha256Creds)
+		}
+	}
+	assert.Equal(t, mdbv1.Running, getCommunityUser(t, mgr, user.NamespacedName()).Status.Phase)
+}
+
+func TestCommunityUser_InAllowedNamespace_ReadsPasswordFromItsNamespace(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Security.AllowedNamespaces = []string{"app-team"}
+	mgr := client.NewManager(&mdb)
+	user := newTestCommunityUser("app-user", "app-team", mdb)
+	user.Spec.ScramCredentialsSecretName = "app-team-app-user"
+	createCommunityUser(t, mgr, user)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	assert.Contains(t, acUsernames(ac), "app-user")
+	assert.Equal(t, mdbv1.Running, getCommunityUser(t, mgr, user.NamespacedName()).Status.Phase)
+
+	// the credentials are stored in the namespace of the MongoDBCommunity resource
+	_, err := mgr.Client.GetSecret(types.NamespacedName{Name: user.Spec.GetScramCredentialsSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+}
+
+func TestCommunityUser_InNamespaceNotAllowed_IsRejected(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+	user := newTestCommunityUser("app-user", "app-team", mdb)
+	createCommunityUser(t, mgr, user)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	assert.NotContains(t, acUsernames(ac), "app-user")
+	rejected := getCommunityUser(t, mgr, user.NamespacedName())
+	assert.Equal(t, mdbv1.Failed, rejected.Status.Phase)
+	assert.Contains(t, rejected.Status.Message, "namespace app-team is not allowed")
+}
+
+func TestCommunityUser_InAllowedNamespace_MustPrefixItsCredentialsSecret(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Security.AllowedNamespaces = []string{"app-team"}
+	mgr := client.NewManager(&mdb)
+	user := newTestCommunityUser("app-user", "app-team", mdb)
+	user.Spec.ScramCredentialsSecretName = "other-app-user"
+	createCommunityUser(t, mgr, user)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	assert.NotContains(t, acUsernames(ac), "app-user")
+	rejected := getCommunityUser(t, mgr, user.NamespacedName())
+	assert.Equal(t, mdbv1.Failed, rejected.Status.Phase)
+	assert.Contains(t, rejected.Status.Message, "scramCredentialsSecretName other-app-user must start with app-team-")
+	_, err := mgr.Client.GetSecret(types.NamespacedName{Name: user.Spec.GetScramCredentialsSecretName(), Namespace: mdb.Namespace})
+	assert.True(t, apiErrors.IsNotFound(err))
+}
+
+func TestCommunityUser_CantUseTheAgentCredentialsSecret(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+	user := newTestCommunityUser("app-user", mdb.Namespace, mdb)
+	user.Spec.ScramCredentialsSecretName = mdb.Name + "-agent"
+	createCommunityUser(t, mgr, user)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	assert.NotContains(t, acUsernames(ac), "app-user")
+	rejected := getCommunityUser(t, mgr, user.NamespacedName())
+	assert.Equal(t, mdbv1.Failed, rejected.Status.Phase)
+	assert.Contains(t, rejected.Status.Message, "is reserved for the agent")
+}
+
+func TestCommunityUser_IsRejectedWhileTheDeploymentIsPending(t *testing.T) {
+	mdb := newTestReplicaSetWithTLS()
+	mgr := client.NewManager(&mdb)
+	user := newTestCommunityUser("app-user", "app-team", mdb)
+	createCommunityUser(t, mgr, user)
+
+	// the TLS secrets don't exist, so the deployment stays pending
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Pending, mdb.Status.Phase)
+
+	rejected := getCommunityUser(t, mgr, user.NamespacedName())
+	assert.Equal(t, mdbv1.Failed, rejected.Status.Phase)
+	assert.Contains(t, rejected.Status.Message, "namespace app-team is not allowed")
+}
+
+func TestCommunityUser_WithSameUsernameAsSpecUser_IsRejected(t *testing.T) {
+	mdb := newScramReplicaSet(mdbv1.MongoDBUser{
+		Name:                       "app-user",
+		DB:                         "admin",
+		PasswordSecretRef:          mdbv1.SecretKeyReference{Name: "spec-user-password"},
+		ScramCredentialsSecretName: "spec-user",
+	})
+	mgr := client.NewManager(&mdb)
+	s := secret.Builder().
+		SetName("spec-user-password").
+		SetNamespace(mdb.Namespace).
+		SetField("password", "my-password").
+		Build()
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &s))
+
+	user := newTestCommunityUser("app-user", mdb.Namespace, mdb)
+	createCommunityUser(t, mgr, user)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	count := 0
+	for _, username := range acUsernames(ac) {
+		if username == "app-user" {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+	rejected := getCommunityUser(t, mgr, user.NamespacedName())
+	assert.Equal(t, mdbv1.Failed, rejected.Status.Phase)
+	assert.Contains(t, rejected.Status.Message, "user admin.app-user already exists")
+}
+
+func TestCommunityUserToMongoDB(t *testing.T) {
+	mdb := newScramReplicaSet()
+	user := newTestCommunityUser("app-user", "app-team", mdb)
+	assert.Equal(t, []reconcile.Request{{NamespacedName: mdb.NamespacedName()}}, communityUserToMongoDB(&user))
+
+	user.Spec.MongoDBResourceRef.Namespace = ""
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: mdb.Name, Namespace: "app-team"}}}, communityUserToMongoDB(&user))
+}
+
+func TestRemovedUser_IsDroppedAndItsCredentialsAreDeleted(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+	user := newTestCommunityUser("app-user", mdb.Namespace, mdb)
+	createCommunityUser(t, mgr, user)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Contains(t, acUsernames(ac), "app-user")
+	assert.Empty(t, ac.Auth.UsersDeleted)
+	_, err := mgr.Client.GetSecret(types.NamespacedName{Name: user.Spec.GetScramCredentialsSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Delete(context.TODO(), &user))
+
+	ac = reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.NotContains(t, acUsernames(ac), "app-user")
+	assert.Equal(t, []automationconfig.DeletedUser{{User: "app-user", Dbs: []string{"admin"}}}, ac.Auth.UsersDeleted)
+	_, err = mgr.Client.GetSecret(types.NamespacedName{Name: user.Spec.GetScramCredentialsSecretName(), Namespace: mdb.Namespace})
+	assert.Error(t, err)
+
+	// once removed, the user is not tracked anymore
+	ac = reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Empty(t, ac.Auth.UsersDeleted)
+}
+
+func TestRejectedUser_IsKeptAsLastDeployed(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+	user := newTestCommunityUser("app-user", mdb.Namespace, mdb)
+	createCommunityUser(t, mgr, user)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Contains(t, acUsernames(ac), "app-user")
+
+	// an invalid edit rejects the user
+	user = getCommunityUser(t, mgr, user.NamespacedName())
+	user.Spec.AuthenticationRestrictions = []mdbv1.AuthenticationRestriction{{ClientSource: []string{"not-an-address"}}}
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &user))
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	ac = reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Equal(t, mdbv1.Failed, getCommunityUser(t, mgr, user.NamespacedName()).Status.Phase)
+	assert.Contains(t, acUsernames(ac), "app-user")
+	assert.Empty(t, ac.Auth.UsersDeleted)
+	_, err := mgr.Client.GetSecret(types.NamespacedName{Name: user.Spec.GetScramCredentialsSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+
+	// the user is still tracked, and is dropped once its resource is deleted
+	assert.NoError(t, mgr.GetClient().Delete(context.TODO(), &user))
+	ac = reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.NotContains(t, acUsernames(ac), "app-user")
+	assert.Equal(t, []automationconfig.DeletedUser{{User: "app-user", Dbs: []string{"admin"}}}, ac.Auth.UsersDeleted)
+}
+
+func TestRenamedCredentialsSecret_IsDeleted(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+	user := newTestCommunityUser("app-user", mdb.Namespace, mdb)
+	createCommunityUser(t, mgr, user)
+	reconcileAndReadAutomationConfig(t, mgr, mdb)
+	previousSecretName := user.Spec.GetScramCredentialsSecretName()
+
+	user = getCommunityUser(t, mgr, user.NamespacedName())
+	user.Spec.ScramCredentialsSecretName = "app-user-renamed"
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &user))
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Contains(t, acUsernames(ac), "app-user")
+	assert.Empty(t, ac.Auth.UsersDeleted)
+	_, err := mgr.Client.GetSecret(types.NamespacedName{Name: user.Spec.GetScramCredentialsSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	_, err = mgr.Client.GetSecret(types.NamespacedName{Name: previousSecretName, Namespace: mdb.Namespace})
+	assert.True(t, apiErrors.IsNotFound(err))
+}
+
+func TestGetRemovedUsers(t *testing.T) {
+	mdb := newScramReplicaSet()
+	users := []scram.User{{Username: "kept", Database: "admin", ScramCredentialsSecretName: "kept-scram-credentials"}}
+
+	t.Run("Nothing is removed without the annotation", func(t *testing.T) {
+		removed, _, err := getRemovedUsers(mdb, users, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, removed)
+	})
+
+	t.Run("Only the users created by the operator are removed", func(t *testing.T) {
+		mdb.Annotations[annotations.ManagedUsers] = `[{"user":"kept","db":"admin","scramCredentialsSecretName":"kept-scram-credentials"},{"user":"removed","db":"admin","scramCredentialsSecretName":"removed-scram-credentials"}]`
+		removed, _, err := getRemovedUsers(mdb, users, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []managedUser{{User: "removed", DB: "admin", ScramCredentialsSecretName: "removed-scram-credentials"}}, removed)
+	})
+
+	t.Run("A rejected user is kept", func(t *testing.T) {
+		mdb.Annotations[annotations.ManagedUsers] = `[{"user":"rejected","db":"admin","scramCredentialsSecretName":"rejected-scram-credentials"}]`
+		rejected := []rejectedUser{{user: newTestCommunityUser("rejected", mdb.Namespace, mdb), reason: "invalid"}}
+		removed, kept, err := getRemovedUsers(mdb, users, rejected)
+		assert.NoError(t, err)
+		assert.Empty(t, removed)
+		assert.Equal(t, []managedUser{{User: "rejected", DB: "admin", ScramCredentialsSecretName: "rejected-scram-credentials"}}, kept)
+	})
+
+	t.Run("A user moved to another database is removed from the previous one", func(t *testing.T) {
+		mdb.Annotations[annotations.ManagedUsers] = `[{"user":"kept","db":"app","scramCredentialsSecretName":"kept-scram-credentials"}]`
+		removed, _, err := getRemovedUsers(mdb, users, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []automationconfig.DeletedUser{{User: "kept", Dbs: []string{"app"}}}, toDeletedUsers(removed))
+	})
+}
+
+func TestSecretBackend_StoresPasswordsAndCredentials(t *testing.T) {
+	mdb := newScramReplicaSet(mdbv1.MongoDBUser{
+		Name:                       "my-user",
+		DB:                         "admin",
+		PasswordSecretRef:          mdbv1.SecretKeyReference{Name: "my-user-password"},
+		ScramCredentialsSecretName: "my-user",
+	})
+	mgr := client.NewManager(&mdb)
+	backend := client.NewClient(client.NewMockedClient())
+	s := secret.Builder().
+		SetName("my-user-password").
+		SetNamespace(mdb.Namespace).
+		SetField("password", "my-password").
+		Build()
+	assert.NoError(t, backend.CreateSecret(s))
+
+	r := NewReconciler(mgr).WithSecretBackend(backend)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Contains(t, acUsernames(ac), "my-user")
+
+	for _, nsName := range []types.NamespacedName{
+		mdb.GetAgentPasswordSecretNamespacedName(),
+		mdb.GetAgentKeyfileSecretNamespacedName(),
+		{Name: mdb.Spec.Users[0].GetScramCredentialsSecretName(), Namespace: mdb.Namespace},
+	} {
+		_, err := backend.GetSecret(nsName)
+		assert.NoError(t, err)
+		_, err = mgr.Client.GetSecret(nsName)
+		assert.Error(t, err, "secret %s should not be stored in Kubernetes", nsName)
+	}
+}
+
+func TestSecretBackend_ChangesArePickedUpAtTheNextResync(t *testing.T) {
+	mdb := newScramReplicaSet(mdbv1.MongoDBUser{
+		Name:                       "my-user",
+		DB:                         "admin",
+		PasswordSecretRef:          mdbv1.SecretKeyReference{Name: "my-user-password"},
+		ScramCredentialsSecretName: "my-user",
+	})
+	mgr := client.NewManager(&mdb)
+	backend := client.NewClient(client.NewMockedClient())
+	s := secret.Builder().
+		SetName("my-user-password").
+		SetNamespace(mdb.Namespace).
+		SetField("password", "my-password").
+		Build()
+	assert.NoError(t, backend.CreateSecret(s))
+
+	r := NewReconciler(mgr).WithSecretBackend(backend).WithSecretResyncInterval(5 * time.Minute)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, res.RequeueAfter)
+
+	userCreds := func() *scramcredentials.ScramCreds {
+		ac, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+		assert.NoError(t, err)
+		for _, u := range ac.Auth.Users {
+			if u.Username == "my-user" {
+				return u.ScramSha256Creds
+			}
+		}
+		return nil
+	}
+	before := userCreds()
+	assert.NotNil(t, before)
+
+	s.Data["password"] = []byte("my-new-password")
+	assert.NoError(t, backend.UpdateSecret(s))
+
+	res, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, res.RequeueAfter)
+	assert.NotEqual(t, before, userCreds())
+}
+
+func TestMetricsAndBackupUsers_AreNotCreatedByDefault(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.NotContains(t, acUsernames(ac), metricsUsername)
+	assert.NotContains(t, acUsernames(ac), backupUsername)
+
+	_, err := mgr.Client.GetSecret(types.NamespacedName{Name: metricsURISecretName(mdb), Namespace: mdb.Namespace})
+	assert.True(t, apiErrors.IsNotFound(err))
+	svc := corev1.Service{}
+	err = mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: mdb.Name + "-exporter-svc", Namespace: mdb.Namespace}, &svc)
+	assert.True(t, apiErrors.IsNotFound(err))
+	cj := batchv1beta1.CronJob{}
+	err = mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: mdb.Name + "-backup", Namespace: mdb.Namespace}, &cj)
+	assert.True(t, apiErrors.IsNotFound(err))
+}
+
+func TestMetricsUser_IsConfigurable(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Metrics = mdbv1.Metrics{
+		Enabled: true,
+		User: mdbv1.InternalUser{
+			Name:  "prometheus",
+			Roles: []mdbv1.Role{{Name: "clusterMonitor", DB: "admin"}},
+		},
+	}
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	for _, u := range ac.Auth.Users {
+		if u.Username == "prometheus" {
+			assert.Equal(t, []automationconfig.Role{{Role: "clusterMonitor", Database: "admin"}}, u.Roles)
+		}
+	}
+	assert.Contains(t, acUsernames(ac), "prometheus")
+
+	uri, err := secret.ReadKey(mgr.Client, "mongodb-uri", types.NamespacedName{Name: metricsURISecretName(mdb), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Contains(t, uri, "mongodb://prometheus:")
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Len(t, sts.Spec.Template.Spec.Containers, 3)
+	exporter := container.GetByName(construct.ExporterName, sts.Spec.Template.Spec.Containers)
+	if assert.NotNil(t, exporter) {
+		assert.Equal(t, metricsURISecretName(mdb), exporter.Env[0].ValueFrom.SecretKeyRef.Name)
+	}
+}
+
+func TestBackupUser_DoesNotReplaceDeclaredUser(t *testing.T) {
+	mdb := newScramReplicaSet(mdbv1.MongoDBUser{
+		Name:                       backupUsername,
+		DB:                         "admin",
+		PasswordSecretRef:          mdbv1.SecretKeyReference{Name: "my-backup-password"},
+		ScramCredentialsSecretName: "my-backup",
+	})
+	mdb.Spec.Backup.Enabled = true
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "user admin.backup is declared in spec.users")
+
+	_, err = mgr.Client.GetSecret(types.NamespacedName{Name: mdb.Name + "-backup-user", Namespace: mdb.Namespace})
+	assert.True(t, apiErrors.IsNotFound(err))
+}
+
+func TestBackupCronJob_IsDeletedWhenBackupIsDisabled(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Backup.Enabled = true
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Contains(t, acUsernames(ac), backupUsername)
+	cj := batchv1beta1.CronJob{}
+	cjName := types.NamespacedName{Name: mdb.Name + "-backup", Namespace: mdb.Namespace}
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), cjName, &cj))
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	// the mocked client stores the whole resource on status updates, including the users inserted by the operator
+	mdb.Spec.Users = nil
+	mdb.Spec.Backup.Enabled = false
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+	ac = reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.NotContains(t, acUsernames(ac), backupUsername)
+	assert.True(t, apiErrors.IsNotFound(mgr.GetClient().Get(context.TODO(), cjName, &cj)))
+}
+
+func TestExporterServiceMonitor_IsDeletedWhenMetricsAreDisabled(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Metrics.Enabled = true
+	mgr := client.NewManager(&mdb)
+
+	_ = reconcileAndReadAutomationConfig(t, mgr, mdb)
+	svcMon := monitoringv1.ServiceMonitor{}
+	svcMonName := types.NamespacedName{Name: mdb.Name + "-exporter", Namespace: prometheusNamespace}
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), svcMonName, &svcMon))
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.Users = nil
+	mdb.Spec.Metrics.Enabled = false
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+	_ = reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.True(t, apiErrors.IsNotFound(mgr.GetClient().Get(context.TODO(), svcMonName, &svcMon)))
+	svc := corev1.Service{}
+	err := mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: mdb.Name + "-exporter-svc", Namespace: mdb.Namespace}, &svc)
+	assert.True(t, apiErrors.IsNotFound(err))
+}
+
+func TestMetricsAndBackup_AreEnabledForDeploymentsOfPreviousOperators(t *testing.T) {
+	mdb := newScramReplicaSet()
+	// previous versions of the operator always deployed the exporter and the backup CronJob,
+	// and had no metrics field in the spec.
+	mdb.Annotations = map[string]string{lastSuccessfulConfiguration: `{"members":3,"type":"ReplicaSet","version":"4.2.6"}`}
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Contains(t, acUsernames(ac), metricsUsername)
+	assert.Contains(t, acUsernames(ac), backupUsername)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.False(t, mdb.Spec.Metrics.Enabled)
+	assert.False(t, mdb.Spec.Backup.Enabled)
+	assert.Equal(t, "true", mdb.Annotations[legacyMetricsAndBackup])
+	cj := batchv1beta1.CronJob{}
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: mdb.Name + "-backup", Namespace: mdb.Namespace}, &cj))
+
+	t.Run("They are kept on the next reconciliations", func(t *testing.T) {
+		// the mocked client persists the internal users added to the spec by the status update
+		mdb.Spec.Users = nil
+		assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+		ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+		assert.Contains(t, acUsernames(ac), metricsUsername)
+		assert.Contains(t, acUsernames(ac), backupUsername)
+	})
+
+	t.Run("They can be disabled afterwards", func(t *testing.T) {
+		assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+		mdb.Spec.Users = nil
+		delete(mdb.Annotations, legacyMetricsAndBackup)
+		assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+		ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+		assert.NotContains(t, acUsernames(ac), metricsUsername)
+		assert.NotContains(t, acUsernames(ac), backupUsername)
+	})
+}
+
+func TestMetricsAndBackup_StayDisabledForNewDeployments(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mgr := client.NewManager(&mdb)
+
+	_ = reconcileAndReadAutomationConfig(t, mgr, mdb)
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.NotContains(t, acUsernames(ac), metricsUsername)
+	assert.NotContains(t, acUsernames(ac), backupUsername)
+}
@@ -22,3 +22,28 @@ func OnlyOnSpecChange() predicate.Funcs {
 		},
 	}
 }
+
+// OnlyOnUserSpecChange returns a set of predicates indicating that the MongoDBCommunity resource referenced
+// by a MongoDBCommunityUser should only be reconciled on changes to the Spec of the user.
+// This allows the status of the user to be updated without triggering a reconciliation.
+func OnlyOnUserSpecChange() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldResource := e.ObjectOld.(*mdbv1.MongoDBCommunityUser)
+			newResource := e.ObjectNew.(*mdbv1.MongoDBCommunityUser)
+			return !reflect.DeepEqual(oldResource.Spec, newResource.Spec)
+		},
+	}
+}
+
+// OnlyOnRoleSpecChange returns a set of predicates indicating that the MongoDBCommunity resource referenced
+// by a MongoDBCommunityRole should only be reconciled on changes to the Spec of the role.
+func OnlyOnRoleSpecChange() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldResource := e.ObjectOld.(*mdbv1.MongoDBCommunityRole)
+			newResource := e.ObjectNew.(*mdbv1.MongoDBCommunityRole)
+			return !reflect.DeepEqual(oldResource.Spec, newResource.Spec)
+		},
+	}
+}
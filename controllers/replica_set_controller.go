@@ -8,6 +8,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/container"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
@@ -29,6 +30,7 @@ import (
 	"github.com/stretchr/objx"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/controllers/construct"
+	"github.com/mongodb/mongodb-kubernetes-operator/controllers/predicates"
 	"github.com/mongodb/mongodb-kubernetes-operator/controllers/validation"
 	"github.com/mongodb/mongodb-kubernetes-operator/controllers/watch"
 
@@ -49,15 +51,22 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
 	clusterDNSName = "CLUSTER_DNS_NAME"
 
 	lastSuccessfulConfiguration = "mongodb.com/v1.lastSuccessfulConfiguration"
+
+	// legacyMetricsAndBackup marks the deployments last configured by an operator which always deployed the exporter
+	// and the backup CronJob, and which keep both until the annotation is removed.
+	legacyMetricsAndBackup = "mongodb.com/v1.legacyMetricsAndBackup"
 )
 
 func init() {
@@ -71,19 +80,41 @@ func init() {
 func NewReconciler(mgr manager.Manager) *ReplicaSetReconciler {
 	mgrClient := mgr.GetClient()
 	secretWatcher := watch.New()
+	configMapWatcher := watch.New()
 
+	client := kubernetesClient.NewClient(mgrClient)
 	return &ReplicaSetReconciler{
-		client:        kubernetesClient.NewClient(mgrClient),
-		scheme:        mgr.GetScheme(),
-		log:           zap.S(),
-		secretWatcher: &secretWatcher,
+		client:           client,
+		scheme:           mgr.GetScheme(),
+		log:              zap.S(),
+		secretWatcher:    &secretWatcher,
+		configMapWatcher: &configMapWatcher,
+		secretBackend:    client,
 	}
 }
 
+// WithSecretBackend configures the reconciler to store the user passwords, the agent password and keyfile,
+// and the generated SCRAM credentials in the given backend instead of Kubernetes Secrets.
+func (r *ReplicaSetReconciler) WithSecretBackend(backend secret.GetUpdateCreateDeleter) *ReplicaSetReconciler {
+	r.secretBackend = backend
+	return r
+}
+
+// WithSecretResyncInterval configures the reconciler to reconcile the MongoDB resources at least once per interval,
+// so that the changes made in a secret backend which can't be watched are applied.
+func (r *ReplicaSetReconciler) WithSecretResyncInterval(interval time.Duration) *ReplicaSetReconciler {
+	r.secretResyncInterval = interval
+	return r
+}
+
 // SetupWithManager sets up the controller with the Manager and configures the necessary watches.
 func (r *ReplicaSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&mdbv1.MongoDBCommunity{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, r.secretWatcher).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, r.configMapWatcher).
+		Watches(&source.Kind{Type: &mdbv1.MongoDBCommunityUser{}}, handler.EnqueueRequestsFromMapFunc(communityUserToMongoDB), builder.WithPredicates(predicates.OnlyOnUserSpecChange())).
+		Watches(&source.Kind{Type: &mdbv1.MongoDBCommunityRole{}}, handler.EnqueueRequestsFromMapFunc(communityRoleToMongoDB), builder.WithPredicates(predicates.OnlyOnRoleSpecChange())).
 		Complete(r)
 }
 
@@ -91,15 +122,27 @@ func (r *ReplicaSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 type ReplicaSetReconciler struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client        kubernetesClient.Client
-	scheme        *runtime.Scheme
-	log           *zap.SugaredLogger
-	secretWatcher *watch.ResourceWatcher
+	client           kubernetesClient.Client
+	scheme           *runtime.Scheme
+	log              *zap.SugaredLogger
+	secretWatcher    *watch.ResourceWatcher
+	configMapWatcher *watch.ResourceWatcher
+
+	// secretBackend stores the user passwords, the agent password and keyfile, and the generated SCRAM credentials.
+	// Defaults to the Kubernetes client.
+	secretBackend secret.GetUpdateCreateDeleter
+	// secretResyncInterval is how often the resources are reconciled to pick up changes in the secret backend.
+	// It is 0 when the backend is watched.
+	secretResyncInterval time.Duration
 }
 
 // +kubebuilder:rbac:groups=mongodbcommunity.mongodb.com,resources=mongodbcommunity,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=mongodbcommunity.mongodb.com,resources=mongodbcommunity/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=mongodbcommunity.mongodb.com,resources=mongodbcommunity/finalizers,verbs=update
+// +kubebuilder:rbac:groups=mongodbcommunity.mongodb.com,resources=mongodbcommunityusers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=mongodbcommunity.mongodb.com,resources=mongodbcommunityusers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=mongodbcommunity.mongodb.com,resources=mongodbcommunityroles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=mongodbcommunity.mongodb.com,resources=mongodbcommunityroles/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
 
@@ -126,89 +169,132 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 		return result.Failed()
 	}
 
-	metricsUser := insertMetricsUser(&mdb)
-	backupUser := insertBackupUser(&mdb)
+	if hasLegacyMetricsAndBackup(mdb) {
+		r.log.Debug("Keeping the metrics and backup deployed by a previous version of the operator")
+	}
 
-	r.log.Debug("Ensuring the MongoDB metrics user secret exists")
-	if err := r.createUserSecret(mdb, metricsUser); err != nil {
+	// the Services and the connection strings keep the port of the last successful reconciliation until the
+	// agents have reached goal state with a new spec.port.
+	port, err := deployedPort(mdb)
+	if err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
-				withMessage(Error, fmt.Sprintf("Error ensuring the metrics user secret exists: %s", err)).
+				withMessage(Error, fmt.Sprintf("Error reading the last successful configuration: %s", err)).
 				withFailedPhase(),
 		)
 	}
 
-	r.log.Debug("Ensuring the MongoDB backup user secret exists")
-	if err := r.createUserSecret(mdb, backupUser); err != nil {
-		return status.Update(r.client.Status(), &mdb,
-			statusOptions().
-				withMessage(Error, fmt.Sprintf("Error ensuring the backup user secret exists: %s", err)).
-				withFailedPhase(),
-		)
+	if metricsEnabled(mdb) {
+		r.log.Debug("Ensuring the MongoDB metrics user exists")
+		if err := r.ensureInternalUser(&mdb, metricsUser(mdb), "metrics", metricsURISecretName(mdb), port); err != nil {
+			return status.Update(r.client.Status(), &mdb,
+				statusOptions().
+					withMessage(Error, fmt.Sprintf("Error configuring the metrics user: %s", err)).
+					withFailedPhase(),
+			)
+		}
+	}
+
+	if backupEnabled(mdb) {
+		r.log.Debug("Ensuring the MongoDB backup user exists")
+		if err := r.ensureInternalUser(&mdb, backupUser(mdb), "backup", backupURISecretName(mdb), port); err != nil {
+			return status.Update(r.client.Status(), &mdb,
+				statusOptions().
+					withMessage(Error, fmt.Sprintf("Error configuring the backup user: %s", err)).
+					withFailedPhase(),
+			)
+		}
 	}
 
-	r.log.Debug("Ensuring the metrics MongoDB URI secret exists")
-	if err := r.ensureMongoDbUriSecret(mdb, metricsUser); err != nil {
+	r.log = zap.S().With("ReplicaSet", request.NamespacedName)
+	r.log.Infow("Reconciling MongoDB", "MongoDB.Spec", mdb.Spec, "MongoDB.Status", mdb.Status)
+
+	r.log.Debug("Validating MongoDB.Spec")
+	if err := r.validateUpdate(mdb); err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
-				withMessage(Error, fmt.Sprintf("Error ensuring the metrics MongoDB URI secret exists: %s", err)).
+				withMessage(Error, fmt.Sprintf("error validating new Spec: %s", err)).
 				withFailedPhase(),
 		)
 	}
 
-	r.log.Debug("Ensuring the backup MongoDB URI secret exists")
-	if err := r.ensureMongoDbUriSecret(mdb, backupUser); err != nil {
+	r.warnInvalidDeployedRoles(mdb)
+
+	r.log.Debug("Validating MongoDBCommunityRole resources")
+	if err := r.updateCommunityRolesStatus(mdb, mdbv1.Pending); err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
-				withMessage(Error, fmt.Sprintf("Error ensuring the backup MongoDB URI secret exists: %s", err)).
+				withMessage(Error, fmt.Sprintf("Error validating MongoDBCommunityRole resources: %s", err)).
 				withFailedPhase(),
 		)
 	}
 
-	r.log = zap.S().With("ReplicaSet", request.NamespacedName)
-	r.log.Infow("Reconciling MongoDB", "MongoDB.Spec", mdb.Spec, "MongoDB.Status", mdb.Status)
+	r.log.Debug("Validating MongoDBCommunityUser resources")
+	if _, rejectedUsers, err := r.getCommunityUsers(mdb); err != nil {
+		r.log.Errorf("Could not validate the MongoDBCommunityUser resources: %s", err)
+	} else if err := r.updateRejectedCommunityUsersStatus(rejectedUsers); err != nil {
+		r.log.Errorf("Could not update the status of the rejected MongoDBCommunityUser resources: %s", err)
+	}
 
-	r.log.Debug("Validating MongoDB.Spec")
-	if err := r.validateUpdate(mdb); err != nil {
+	r.log.Debug("Ensuring the service exists")
+	if err := r.ensureService(mdb, port); err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
-				withMessage(Error, fmt.Sprintf("error validating new Spec: %s", err)).
+				withMessage(Error, fmt.Sprintf("Error ensuring the service exists: %s", err)).
 				withFailedPhase(),
 		)
 	}
 
-	r.log.Debug("Ensuring the service exists")
-	if err := r.ensureService(mdb); err != nil {
+	r.log.Debug("Ensuring the external services exist")
+	if err := r.ensureExternalServices(mdb, port); err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
-				withMessage(Error, fmt.Sprintf("Error ensuring the service exists: %s", err)).
+				withMessage(Error, fmt.Sprintf("Error ensuring the external services exist: %s", err)).
 				withFailedPhase(),
 		)
 	}
 
-	r.log.Debug("Ensuring the exporter service exists")
-	if err := r.ensureExporterService(mdb); err != nil {
+	r.log.Debug("Ensuring the additional services exist")
+	if err := r.ensureAdditionalServices(mdb, port); err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
-				withMessage(Error, fmt.Sprintf("Error ensuring the exporter service exists: %s", err)).
+				withMessage(Error, fmt.Sprintf("Error ensuring the additional services exist: %s", err)).
 				withFailedPhase(),
 		)
 	}
 
-	r.log.Debug("Ensuring the exporter service monitor exists")
-	if err := r.ensureExporterServiceMonitor(mdb); err != nil {
+	if mdb.Spec.ExternalAccess.Enabled {
+		// the external addresses are written to the horizons of the members, so the automation config
+		// can't be updated until all of them have been allocated.
+		_, allocated, err := r.getExternalAddresses(mdb)
+		if err != nil {
+			return status.Update(r.client.Status(), &mdb,
+				statusOptions().
+					withMessage(Error, fmt.Sprintf("Error reading the external addresses: %s", err)).
+					withFailedPhase(),
+			)
+		}
+		if !allocated {
+			return status.Update(r.client.Status(), &mdb,
+				statusOptions().
+					withMessage(Info, "Waiting for the external addresses of the members, retrying in 10 seconds").
+					withPendingPhase(10),
+			)
+		}
+	}
+
+	if err := r.ensureMetricsResources(mdb); err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
-				withMessage(Error, fmt.Sprintf("Error ensuring the exporter service monitor exists: %s", err)).
+				withMessage(Error, fmt.Sprintf("Error ensuring the exporter resources: %s", err)).
 				withFailedPhase(),
 		)
 	}
 
-	r.log.Debug("Ensuring the backup CronJob exists")
-	if err := r.ensureBackupCronJob(mdb); err != nil {
+	if err := r.ensureBackupResources(mdb); err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
-				withMessage(Error, fmt.Sprintf("Error ensuring the backup cronjob exists: %s", err)).
+				withMessage(Error, fmt.Sprintf("Error ensuring the backup resources: %s", err)).
 				withFailedPhase(),
 		)
 	}
@@ -230,6 +316,40 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 		)
 	}
 
+	isClusterAuthValid, err := r.validateClusterAuthConfig(mdb)
+	if err != nil {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error validating cluster authentication config: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	if !isClusterAuthValid {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Info, "Cluster authentication config is not yet valid, retrying in 10 seconds").
+				withPendingPhase(10),
+		)
+	}
+
+	isLDAPValid, err := r.validateLDAPConfig(mdb)
+	if err != nil {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error validating LDAP config: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	if !isLDAPValid {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Info, "LDAP config is not yet valid, retrying in 10 seconds").
+				withPendingPhase(10),
+		)
+	}
+
 	if err := r.ensureTLSResources(mdb); err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
@@ -238,6 +358,20 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 		)
 	}
 
+	ca, err := readCA(r.client, mdb)
+	if err != nil {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error reading CA: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	// the stage of the key rotation is read when building the automation config, and is persisted
+	// with the status at the end of this reconciliation.
+	now := time.Now()
+	mdb.Status.KeyRotation.Stage = getKeyRotationStage(mdb, now)
+
 	ready, err := r.deployMongoDBReplicaSet(mdb)
 	if err != nil {
 		return status.Update(r.client.Status(), &mdb,
@@ -248,15 +382,31 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 	}
 
 	if !ready {
+		components := r.shardedClusterStatus(mdb)
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
-				withMessage(Info, "ReplicaSet is not yet ready, retrying in 10 seconds").
+				withMessage(Info, notReadyMessage(mdb, components)).
+				withShardedCluster(withPreviousReplicas(mdb, components)).
+				withCARotationStage(getCARotationStage(mdb, ca)).
+				withPendingPhase(10),
+		)
+	}
+
+	// the agents accept both keyfiles once they all reached goal state, the new keyfile and agent password
+	// can then be rolled out.
+	if mdb.Status.KeyRotation.Stage == mdbv1.KeyRotationStageKeyfileBundle {
+		keyRotation := mdb.Status.KeyRotation
+		keyRotation.Stage = mdbv1.KeyRotationStageNewCredentials
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Info, "Rolling out the new keyfile and agent password, retrying in 10 seconds").
+				withKeyRotation(keyRotation).
 				withPendingPhase(10),
 		)
 	}
 
 	r.log.Debug("Resetting StatefulSet UpdateStrategy to RollingUpdate")
-	if err := statefulset.ResetUpdateStrategy(&mdb, r.client); err != nil {
+	if err := r.resetUpdateStrategy(mdb); err != nil {
 		return status.Update(r.client.Status(), &mdb,
 			statusOptions().
 				withMessage(Error, fmt.Sprintf("Error resetting StatefulSet UpdateStrategyType: %s", err)).
@@ -264,22 +414,85 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 		)
 	}
 
-	if scale.IsStillScaling(mdb) {
+	currentAC, err := r.readAutomationConfig(mdb)
+	if err != nil {
+		return status.Update(r.client.Status(), &mdb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error reading the deployed automation config: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	if !isTLSTransitionComplete(mdb, currentAC) {
 		return status.Update(r.client.Status(), &mdb, statusOptions().
 			withMongoDBMembers(mdb.AutomationConfigMembersThisReconciliation()).
-			withMessage(Info, fmt.Sprintf("Performing scaling operation, currentMembers=%d, desiredMembers=%d",
-				mdb.CurrentReplicas(), mdb.DesiredReplicas())).
+			withMessage(Info, fmt.Sprintf("Changing TLS mode, currentMode=%s, desiredMode=%s", currentTLSMode(currentAC), mdb.Spec.Security.TLS.GetMode())).
 			withStatefulSetReplicas(mdb.StatefulSetReplicasThisReconciliation()).
+			withMongoDBArbiters(mdb.AutomationConfigArbitersThisReconciliation()).
+			withStatefulSetArbiters(mdb.StatefulSetArbitersThisReconciliation()).
 			withPendingPhase(10),
 		)
 	}
 
+	if !isClusterAuthTransitionComplete(mdb, currentAC) {
+		return status.Update(r.client.Status(), &mdb, statusOptions().
+			withMongoDBMembers(mdb.AutomationConfigMembersThisReconciliation()).
+			withMessage(Info, fmt.Sprintf("Changing cluster authentication mode, desiredMode=%s", mdb.GetClusterAuthMode())).
+			withStatefulSetReplicas(mdb.StatefulSetReplicasThisReconciliation()).
+			withMongoDBArbiters(mdb.AutomationConfigArbitersThisReconciliation()).
+			withStatefulSetArbiters(mdb.StatefulSetArbitersThisReconciliation()).
+			withPendingPhase(10),
+		)
+	}
+
+	if mdb.IsStillScaling() {
+		return status.Update(r.client.Status(), &mdb, statusOptions().
+			withMongoDBMembers(mdb.AutomationConfigMembersThisReconciliation()).
+			withMessage(Info, fmt.Sprintf("Performing scaling operation, currentMembers=%d, desiredMembers=%d, currentArbiters=%d, desiredArbiters=%d",
+				mdb.CurrentReplicas(), mdb.DesiredReplicas(), mdb.Status.CurrentMongoDBArbiters, mdb.Spec.Arbiters)).
+			withStatefulSetReplicas(mdb.StatefulSetReplicasThisReconciliation()).
+			withMongoDBArbiters(mdb.AutomationConfigArbitersThisReconciliation()).
+			withStatefulSetArbiters(mdb.StatefulSetArbitersThisReconciliation()).
+			withShardedCluster(r.shardedClusterStatus(mdb)).
+			withPendingPhase(10),
+		)
+	}
+
+	if port != mdb.GetPort() {
+		r.log.Infof("Updating the Services and the connection strings from port %d to port %d", port, mdb.GetPort())
+		if err := r.ensurePortResources(mdb, mdb.GetPort()); err != nil {
+			return status.Update(r.client.Status(), &mdb,
+				statusOptions().
+					withMessage(Error, fmt.Sprintf("Error changing the port: %s", err)).
+					withFailedPhase(),
+			)
+		}
+	}
+
+	keyRotation := mdb.Status.KeyRotation
+	if keyRotation.Stage == mdbv1.KeyRotationStageNewCredentials {
+		if err := completeKeyRotation(r.secretBackend, mdb); err != nil {
+			return status.Update(r.client.Status(), &mdb,
+				statusOptions().
+					withMessage(Error, fmt.Sprintf("Error completing the key rotation: %s", err)).
+					withFailedPhase(),
+			)
+		}
+		keyRotation = completedKeyRotation(mdb, now)
+	}
+
 	res, err := status.Update(r.client.Status(), &mdb,
 		statusOptions().
 			withMongoURI(mdb.MongoURI()).
+			withKeyRotation(keyRotation).
 			withMongoDBMembers(mdb.AutomationConfigMembersThisReconciliation()).
 			withStatefulSetReplicas(mdb.StatefulSetReplicasThisReconciliation()).
+			withMongoDBArbiters(mdb.AutomationConfigArbitersThisReconciliation()).
+			withStatefulSetArbiters(mdb.StatefulSetArbitersThisReconciliation()).
 			withMessage(None, "").
+			withCAHash(caHash(ca)).
+			withCARotationStage(completedCARotationStage(ca)).
+			withShardedCluster(r.shardedClusterStatus(mdb)).
 			withRunningPhase(),
 	)
 	if err != nil {
@@ -287,6 +500,17 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 		return res, err
 	}
 
+	if err := r.updateCommunityUsersStatus(mdb, mdbv1.Running); err != nil {
+		r.log.Errorf("Could not update the status of the MongoDBCommunityUser resources: %s", err)
+	}
+	if err := r.updateCommunityRolesStatus(mdb, mdbv1.Running); err != nil {
+		r.log.Errorf("Could not update the status of the MongoDBCommunityRole resources: %s", err)
+	}
+
+	if err := r.cleanupRemovedUsers(mdb); err != nil {
+		r.log.Errorf("Could not clean up the removed users: %s", err)
+	}
+
 	// the last version will be duplicated in two annotations.
 	// This is needed to reuse the update strategy logic in enterprise
 	if err := annotations.UpdateLastAppliedMongoDBVersion(&mdb, r.client); err != nil {
@@ -296,6 +520,13 @@ func (r ReplicaSetReconciler) Reconcile(ctx context.Context, request reconcile.R
 		r.log.Errorf("Could not save current spec as an annotation: %s", err)
 	}
 
+	if next := nextScheduledKeyRotation(mdb, now); next > 0 && res.RequeueAfter == 0 {
+		res.RequeueAfter = next
+	}
+	if r.secretResyncInterval > 0 && (res.RequeueAfter == 0 || res.RequeueAfter > r.secretResyncInterval) {
+		res.RequeueAfter = r.secretResyncInterval
+	}
+
 	if res.RequeueAfter > 0 || res.Requeue {
 		r.log.Infow("Requeuing reconciliation", "MongoDB.Spec:", mdb.Spec, "MongoDB.Status:", mdb.Status)
 		return res, nil
@@ -315,26 +546,40 @@ func (r *ReplicaSetReconciler) updateLastSuccessfulConfiguration(mdb mdbv1.Mongo
 	specAnnotations := map[string]string{
 		lastSuccessfulConfiguration: string(currentSpec),
 	}
+	if hasLegacyMetricsAndBackup(mdb) {
+		specAnnotations[legacyMetricsAndBackup] = "true"
+	}
 	return annotations.SetAnnotations(&mdb, specAnnotations, r.client)
 }
 
 // ensureTLSResources creates any required TLS resources that the MongoDBCommunity
 // requires for TLS configuration.
 func (r *ReplicaSetReconciler) ensureTLSResources(mdb mdbv1.MongoDBCommunity) error {
-	if !mdb.Spec.Security.TLS.Enabled {
+	if !isTLSInUse(mdb) {
 		return nil
 	}
 	// the TLS secret needs to be created beforehand, as both the StatefulSet and AutomationConfig
 	// require the contents.
-	if mdb.Spec.Security.TLS.Enabled {
-		r.log.Infof("TLS is enabled, creating/updating TLS secret")
+	if isTLSInUse(mdb) {
+		r.log.Infof("TLS is in use, creating/updating TLS secret")
 		if err := ensureTLSSecret(r.client, mdb); err != nil {
 			return errors.Errorf("could not ensure TLS secret: %s", err)
 		}
 	}
+	if mdb.HasClusterCertificate() {
+		r.log.Infof("Member certificate is configured, creating/updating cluster certificate secret")
+		if err := ensureClusterAuthSecret(r.client, mdb); err != nil {
+			return errors.Errorf("could not ensure cluster certificate secret: %s", err)
+		}
+	}
 	return nil
 }
 
+// readAutomationConfig reads the AutomationConfig which is currently deployed for the MongoDBCommunity resource.
+func (r *ReplicaSetReconciler) readAutomationConfig(mdb mdbv1.MongoDBCommunity) (automationconfig.AutomationConfig, error) {
+	return automationconfig.ReadFromSecret(r.client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+}
+
 // deployStatefulSet deploys the backing StatefulSet of the MongoDBCommunity resource.
 // The returned boolean indicates that the StatefulSet is ready.
 func (r *ReplicaSetReconciler) deployStatefulSet(mdb mdbv1.MongoDBCommunity) (bool, error) {
@@ -361,7 +606,14 @@ func (r *ReplicaSetReconciler) deployStatefulSet(mdb mdbv1.MongoDBCommunity) (bo
 		)
 	}
 
-	return isReady || currentSts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType, nil
+	if !isReady && currentSts.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType {
+		return false, nil
+	}
+
+	if ready, err := r.isArbitersStatefulSetReady(mdb); err != nil || !ready {
+		return ready, err
+	}
+	return r.isAnalyticsStatefulSetReady(mdb)
 }
 
 // deployAutomationConfig deploys the AutomationConfig for the MongoDBCommunity resource.
@@ -397,46 +649,87 @@ func (r *ReplicaSetReconciler) deployAutomationConfig(mdb mdbv1.MongoDBCommunity
 	if err != nil {
 		return false, fmt.Errorf("failed to ensure agents have reached goal state: %s", err)
 	}
+	if !ready {
+		return false, nil
+	}
 
-	return ready, nil
+	ready, err = r.componentReachedGoalState(mdb.ArbitersNamespacedName(), mdb.StatefulSetArbitersThisReconciliation(), ac.Version)
+	if err != nil || !ready {
+		return ready, err
+	}
+	return r.componentReachedGoalState(mdb.AnalyticsNamespacedName(), mdb.Spec.AnalyticsMembers.Members, ac.Version)
+}
+
+// componentReachedGoalState returns true if the agents of the arbiters or of the analytics members have reached goal
+// state, or if their StatefulSet doesn't exist.
+func (r *ReplicaSetReconciler) componentReachedGoalState(nsName types.NamespacedName, replicas, version int) (bool, error) {
+	sts, err := r.client.GetStatefulSet(nsName)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to get the StatefulSet %s: %s", nsName.Name, err)
+	}
+	return agent.AllReachedGoalState(sts, r.client, replicas, version, r.log)
 }
 
 // shouldRunInOrder returns true if the order of execution of the AutomationConfig & StatefulSet
 // functions should be sequential or not. A value of false indicates they will run in reversed order.
-func (r *ReplicaSetReconciler) shouldRunInOrder(mdb mdbv1.MongoDBCommunity) bool {
+func (r *ReplicaSetReconciler) shouldRunInOrder(mdb mdbv1.MongoDBCommunity) (bool, error) {
 	// The only case when we push the StatefulSet first is when we are ensuring TLS for the already existing ReplicaSet
 	_, err := r.client.GetStatefulSet(mdb.NamespacedName())
 	if err == nil && mdb.Spec.Security.TLS.Enabled {
 		r.log.Debug("Enabling TLS on an existing deployment, the StatefulSet must be updated first")
-		return false
+		return false, nil
 	}
 
 	// if we are scaling up, we need to make sure the StatefulSet is scaled up first.
 	if scale.IsScalingUp(mdb) {
 		r.log.Debug("Scaling up the ReplicaSet, the StatefulSet must be updated first")
-		return false
+		return false, nil
 	}
 
 	if scale.IsScalingDown(mdb) {
 		r.log.Debug("Scaling down the ReplicaSet, the Automation Config must be updated first")
-		return true
+		return true, nil
+	}
+
+	if mdb.Status.CurrentStatefulSetReplicas != 0 && mdb.StatefulSetArbitersThisReconciliation() > mdb.Status.CurrentStatefulSetArbitersReplicas {
+		r.log.Debug("Scaling up the arbiters, the StatefulSet must be updated first")
+		return false, nil
+	}
+
+	scalingUpAnalyticsMembers, err := r.isScalingUpAnalyticsMembers(mdb)
+	if err != nil {
+		return false, err
+	}
+	if scalingUpAnalyticsMembers {
+		r.log.Debug("Scaling up the analytics members, the StatefulSet must be updated first")
+		return false, nil
 	}
 
 	// when we change version, we need the StatefulSet images to be updated first, then the agent can get to goal
 	// state on the new version.
 	if mdb.IsChangingVersion() {
 		r.log.Debug("Version change in progress, the StatefulSet must be updated first")
-		return false
+		return false, nil
 	}
 
-	return true
+	return true, nil
 }
 
 // deployMongoDBReplicaSet will ensure that both the AutomationConfig secret and backing StatefulSet
 // have been successfully created. A boolean is returned indicating if the process is complete
 // and an error if there was one.
 func (r *ReplicaSetReconciler) deployMongoDBReplicaSet(mdb mdbv1.MongoDBCommunity) (bool, error) {
-	return functions.RunSequentially(r.shouldRunInOrder(mdb),
+	if mdb.IsShardedCluster() {
+		return r.deployShardedCluster(mdb)
+	}
+	inOrder, err := r.shouldRunInOrder(mdb)
+	if err != nil {
+		return false, err
+	}
+	return functions.RunSequentially(inOrder,
 		func() (bool, error) {
 			return r.deployAutomationConfig(mdb)
 		},
@@ -445,27 +738,78 @@ func (r *ReplicaSetReconciler) deployMongoDBReplicaSet(mdb mdbv1.MongoDBCommunit
 		})
 }
 
-func (r *ReplicaSetReconciler) ensureService(mdb mdbv1.MongoDBCommunity) error {
-	svc := buildService(mdb)
-	err := r.client.Create(context.TODO(), &svc)
-	if err != nil && apiErrors.IsAlreadyExists(err) {
-		r.log.Infof("The service already exists... moving forward: %s", err)
-		return nil
+// ensureService creates the headless Service of the members, or updates its port.
+func (r *ReplicaSetReconciler) ensureService(mdb mdbv1.MongoDBCommunity, port int) error {
+	return service.CreateOrUpdate(r.client, buildService(mdb, port))
+}
+
+// deployedPort returns the port of the last successful reconciliation, which the members listen on until
+// the agents have reached goal state with a new spec.port.
+func deployedPort(mdb mdbv1.MongoDBCommunity) (int, error) {
+	prevSpec, ok, err := getLastSuccessfulSpec(mdb)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return mdb.GetPort(), nil
+	}
+	return mdbv1.MongoDBCommunity{Spec: prevSpec}.GetPort(), nil
+}
+
+// ensurePortResources updates the Services and the connection strings of the internal users to the given port.
+func (r *ReplicaSetReconciler) ensurePortResources(mdb mdbv1.MongoDBCommunity, port int) error {
+	if err := r.ensureService(mdb, port); err != nil {
+		return errors.Errorf("error updating the service: %s", err)
+	}
+	if err := r.ensureExternalServices(mdb, port); err != nil {
+		return err
+	}
+	if err := r.ensureAdditionalServices(mdb, port); err != nil {
+		return err
+	}
+	if metricsEnabled(mdb) {
+		if err := r.ensureMongoDbUriSecret(mdb, metricsUser(mdb), metricsURISecretName(mdb), port); err != nil {
+			return errors.Errorf("error updating the MongoDB URI secret of the metrics user: %s", err)
+		}
 	}
-	return err
+	if backupEnabled(mdb) {
+		if err := r.ensureMongoDbUriSecret(mdb, backupUser(mdb), backupURISecretName(mdb), port); err != nil {
+			return errors.Errorf("error updating the MongoDB URI secret of the backup user: %s", err)
+		}
+	}
+	return nil
+}
+
+// deleteIfExists deletes the object, ignoring objects which don't exist.
+func (r *ReplicaSetReconciler) deleteIfExists(obj k8sClient.Object) error {
+	return k8sClient.IgnoreNotFound(r.client.Delete(context.TODO(), obj))
 }
 
 func (r *ReplicaSetReconciler) createOrUpdateStatefulSet(mdb mdbv1.MongoDBCommunity) error {
 	set := appsv1.StatefulSet{}
 	err := r.client.Get(context.TODO(), mdb.NamespacedName(), &set)
+	exists := err == nil
 	err = k8sClient.IgnoreNotFound(err)
 	if err != nil {
 		return errors.Errorf("error getting StatefulSet: %s", err)
 	}
+	ca, err := readCA(r.client, mdb)
+	if err != nil {
+		return errors.Errorf("error reading CA: %s", err)
+	}
 	buildStatefulSetModificationFunction(mdb)(&set)
+	withCAHashAnnotation(mdb, ca, exists)(&set)
 	if _, err = statefulset.CreateOrUpdate(r.client, set); err != nil {
 		return errors.Errorf("error creating/updating StatefulSet: %s", err)
 	}
+
+	if err := r.createOrUpdateArbitersStatefulSet(mdb, ca); err != nil {
+		return err
+	}
+
+	if err := r.createOrUpdateAnalyticsStatefulSet(mdb, ca); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -491,11 +835,18 @@ func buildAutomationConfig(mdb mdbv1.MongoDBCommunity, auth automationconfig.Aut
 	zap.S().Debugw("AutomationConfigMembersThisReconciliation", "mdb.AutomationConfigMembersThisReconciliation()", mdb.AutomationConfigMembersThisReconciliation())
 
 	return automationconfig.NewBuilder().
-		SetTopology(automationconfig.ReplicaSetTopology).
+		SetTopology(automationConfigTopology(mdb)).
 		SetName(mdb.Name).
 		SetDomain(domain).
 		SetMembers(mdb.AutomationConfigMembersThisReconciliation()).
+		SetPort(mdb.GetPort()).
+		SetArbiters(mdb.AutomationConfigArbitersThisReconciliation()).
+		SetAnalyticsMembers(mdb.Spec.AnalyticsMembers.Members).
 		SetReplicaSetHorizons(mdb.Spec.ReplicaSetHorizons).
+		SetMemberOptions(mdbv1.ConvertMemberConfigToAutomationConfigMemberOptions(mdb.Spec.MemberConfig)).
+		SetReplicaSetSettings(mdb.Spec.ReplicaSetSettings.ConvertToAutomationConfigReplicaSetSettings()).
+		SetDefaultRWConcern(mdb.Spec.ReplicaSetSettings.ConvertToAutomationConfigDefaultRWConcern()).
+		SetShardedCluster(mdb.AutomationConfigShardedCluster()).
 		SetPreviousAutomationConfig(currentAc).
 		SetMongoDBVersion(mdb.Spec.Version).
 		SetFCV(mdb.Spec.FeatureCompatibilityVersion).
@@ -510,7 +861,7 @@ func buildAutomationConfig(mdb mdbv1.MongoDBCommunity, auth automationconfig.Aut
 // that allows all the members of the STS to see each other.
 // TODO: Make sure this Service is as minimal as possible, to not interfere with
 // future implementations and Service Discovery mechanisms we might implement.
-func buildService(mdb mdbv1.MongoDBCommunity) corev1.Service {
+func buildService(mdb mdbv1.MongoDBCommunity, port int) corev1.Service {
 	label := make(map[string]string)
 	label["app"] = mdb.ServiceName()
 	return service.Builder().
@@ -519,7 +870,7 @@ func buildService(mdb mdbv1.MongoDBCommunity) corev1.Service {
 		SetSelector(label).
 		SetServiceType(corev1.ServiceTypeClusterIP).
 		SetClusterIP("None").
-		SetPort(27017).
+		SetPort(int32(port)).
 		SetPublishNotReadyAddresses(true).
 		Build()
 }
@@ -528,23 +879,74 @@ func buildService(mdb mdbv1.MongoDBCommunity) corev1.Service {
 // is still valid. If there is no a previous Spec, then the function assumes this is
 // the first version of the MongoDB resource and skips.
 func (r ReplicaSetReconciler) validateUpdate(mdb mdbv1.MongoDBCommunity) error {
+	prevSpec, ok, err := getLastSuccessfulSpec(mdb)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// First version of Spec, there is no transition to validate
+		return validation.ValidateInitialSpec(mdb.Spec)
+	}
+
+	return validation.Validate(prevSpec, mdb.Spec)
+}
+
+// getLastSuccessfulSpec returns the Spec saved in the lastSuccessfulConfiguration annotation.
+// The returned boolean is false if the resource has never reached the Running phase.
+func getLastSuccessfulSpec(mdb mdbv1.MongoDBCommunity) (mdbv1.MongoDBCommunitySpec, bool, error) {
 	lastSuccessfulConfigurationSaved, ok := mdb.Annotations[lastSuccessfulConfiguration]
 	if !ok {
-		// First version of Spec, no need to validate
-		return nil
+		return mdbv1.MongoDBCommunitySpec{}, false, nil
 	}
 
 	prevSpec := mdbv1.MongoDBCommunitySpec{}
-	err := json.Unmarshal([]byte(lastSuccessfulConfigurationSaved), &prevSpec)
-	if err != nil {
-		return err
+	if err := json.Unmarshal([]byte(lastSuccessfulConfigurationSaved), &prevSpec); err != nil {
+		return mdbv1.MongoDBCommunitySpec{}, false, err
 	}
+	return prevSpec, true, nil
+}
 
-	return validation.Validate(prevSpec, mdb.Spec)
+// hasLegacyMetricsAndBackup returns true if neither the metrics nor the backup are enabled in the spec of a deployment
+// last configured by an operator which always deployed them, so that they are not removed on upgrade. Such a deployment
+// is recognized by the lastSuccessfulConfiguration annotation, which has no metrics field, and is marked with the
+// legacyMetricsAndBackup annotation from its next successful reconciliation.
+func hasLegacyMetricsAndBackup(mdb mdbv1.MongoDBCommunity) bool {
+	if mdb.Spec.Metrics.Enabled || mdb.Spec.Backup.Enabled {
+		return false
+	}
+	if _, ok := mdb.Annotations[legacyMetricsAndBackup]; ok {
+		return true
+	}
+	lastSuccessfulConfigurationSaved, ok := mdb.Annotations[lastSuccessfulConfiguration]
+	if !ok {
+		return false
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(lastSuccessfulConfigurationSaved), &fields); err != nil {
+		return false
+	}
+	_, ok = fields["metrics"]
+	return !ok
+}
+
+// metricsEnabled returns true if the exporter is enabled in the spec, or kept from a previous version of the operator.
+func metricsEnabled(mdb mdbv1.MongoDBCommunity) bool {
+	return mdb.Spec.Metrics.Enabled || hasLegacyMetricsAndBackup(mdb)
 }
 
-func getCustomRolesModification(mdb mdbv1.MongoDBCommunity) (automationconfig.Modification, error) {
+// backupEnabled returns true if the backups are enabled in the spec, or kept from a previous version of the operator.
+func backupEnabled(mdb mdbv1.MongoDBCommunity) bool {
+	return mdb.Spec.Backup.Enabled || hasLegacyMetricsAndBackup(mdb)
+}
+
+func getCustomRolesModification(mdb mdbv1.MongoDBCommunity, communityRoles []mdbv1.MongoDBCommunityRole) (automationconfig.Modification, error) {
 	roles := mdb.Spec.Security.Roles
+	if len(communityRoles) > 0 {
+		roles = append([]mdbv1.CustomRole{}, roles...)
+		for _, role := range communityRoles {
+			roles = append(roles, role.Spec.CustomRole)
+		}
+	}
 	if roles == nil {
 		return automationconfig.NOOP(), nil
 	}
@@ -555,32 +957,77 @@ func getCustomRolesModification(mdb mdbv1.MongoDBCommunity) (automationconfig.Mo
 }
 
 func (r ReplicaSetReconciler) buildAutomationConfig(mdb mdbv1.MongoDBCommunity) (automationconfig.AutomationConfig, error) {
-	tlsModification, err := getTLSConfigModification(r.client, mdb)
+	communityRoles, _, err := r.getCommunityRoles(mdb)
 	if err != nil {
-		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure TLS modification: %s", err)
+		return automationconfig.AutomationConfig{}, errors.Errorf("could not get MongoDBCommunityRole resources: %s", err)
 	}
 
-	customRolesModification, err := getCustomRolesModification(mdb)
+	customRolesModification, err := getCustomRolesModification(mdb, communityRoles)
 	if err != nil {
 		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure custom roles: %s", err)
 	}
 
-	currentAC, err := automationconfig.ReadFromSecret(r.client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	currentAC, err := r.readAutomationConfig(mdb)
 	if err != nil {
 		return automationconfig.AutomationConfig{}, errors.Errorf("could not read existing automation config: %s", err)
 	}
 
+	tlsModification, err := getTLSConfigModification(r.client, mdb, currentAC)
+	if err != nil {
+		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure TLS modification: %s", err)
+	}
+
+	clusterAuthModification, err := getClusterAuthModification(r.client, mdb, currentAC)
+	if err != nil {
+		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure cluster authentication: %s", err)
+	}
+
+	ldapModification, err := getLDAPModification(r.client, mdb)
+	if err != nil {
+		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure LDAP: %s", err)
+	}
+
+	communityUsers, rejectedUsers, err := r.getCommunityUsers(mdb)
+	if err != nil {
+		return automationconfig.AutomationConfig{}, errors.Errorf("could not get MongoDBCommunityUser resources: %s", err)
+	}
+	r.watchCommunityUserSecrets(mdb, communityUsers)
+
+	users := communityUsersConfigurable{MongoDBCommunity: mdb, communityUsers: communityUsers}
 	auth := automationconfig.Auth{}
-	if err := scram.Enable(&auth, r.client, mdb); err != nil {
+	if err := scram.Enable(&auth, r.secretBackend, users); err != nil {
 		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure scram authentication: %s", err)
 	}
 
+	// users created by the operator and removed since the last successful reconciliation are dropped,
+	// the users created by hand are left alone.
+	removedUsers, keptUsers, err := getRemovedUsers(mdb, users.GetScramUsers(), rejectedUsers)
+	if err != nil {
+		return automationconfig.AutomationConfig{}, errors.Errorf("could not determine the removed users: %s", err)
+	}
+	auth.UsersDeleted = toDeletedUsers(removedUsers)
+	auth.Users = append(auth.Users, previousUsers(currentAC, keptUsers)...)
+
+	keyRotationModification, err := getKeyRotationModification(r.secretBackend, mdb, mdb.Status.KeyRotation.Stage)
+	if err != nil {
+		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure the key rotation: %s", err)
+	}
+
+	externalAccessModification, err := r.getExternalAccessModification(mdb)
+	if err != nil {
+		return automationconfig.AutomationConfig{}, errors.Errorf("could not configure the external access: %s", err)
+	}
+
 	return buildAutomationConfig(
 		mdb,
 		auth,
 		currentAC,
 		tlsModification,
 		customRolesModification,
+		clusterAuthModification,
+		ldapModification,
+		keyRotationModification,
+		externalAccessModification,
 	)
 }
 
@@ -589,6 +1036,10 @@ func (r ReplicaSetReconciler) buildAutomationConfig(mdb mdbv1.MongoDBCommunity)
 func getMongodConfigModification(mdb mdbv1.MongoDBCommunity) automationconfig.Modification {
 	return func(ac *automationconfig.AutomationConfig) {
 		for i := range ac.Processes {
+			// the additional configuration only applies to mongod
+			if ac.Processes[i].ProcessType == automationconfig.Mongos {
+				continue
+			}
 			// Mergo requires both objects to have the same type
 			// TODO: handle this error gracefully, we may need to add an error as second argument for all modification functions
 			_ = mergo.Merge(&ac.Processes[i].Args26, objx.New(mdb.Spec.AdditionalMongodConfig.Object), mergo.WithOverride)
@@ -606,17 +1057,19 @@ func buildStatefulSet(mdb mdbv1.MongoDBCommunity) (appsv1.StatefulSet, error) {
 
 func buildStatefulSetModificationFunction(mdb mdbv1.MongoDBCommunity) statefulset.Modification {
 	commonModification := construct.BuildMongoDBReplicaSetStatefulSetModificationFunction(&mdb, mdb)
-	return statefulset.Apply(
+	return withComponentLabel(componentMember, statefulset.Apply(
 		commonModification,
 		statefulset.WithOwnerReference([]metav1.OwnerReference{getOwnerReference(mdb)}),
 		statefulset.WithPodSpecTemplate(
 			podtemplatespec.Apply(
 				buildTLSPodSpecModification(mdb),
+				buildClusterAuthPodSpecModification(mdb),
+				buildExporterPodSpecModification(mdb),
 			),
 		),
 
 		statefulset.WithCustomSpecs(mdb.Spec.StatefulSetConfiguration.SpecWrapper.Spec),
-	)
+	))
 }
 
 func getOwnerReference(mdb mdbv1.MongoDBCommunity) metav1.OwnerReference {
@@ -641,14 +1094,11 @@ func isPreReadinessInitContainerStatefulSet(sts appsv1.StatefulSet) bool {
 }
 
 func (r *ReplicaSetReconciler) createUserSecret(mdb mdbv1.MongoDBCommunity, user mdbv1.MongoDBUser) error {
-	userSecret := corev1.Secret{}
-	err := r.client.Get(
-		context.TODO(),
+	userSecret, err := r.secretBackend.GetSecret(
 		types.NamespacedName{
 			Name:      user.PasswordSecretRef.Name,
 			Namespace: mdb.Namespace,
 		},
-		&userSecret,
 	)
 	err = k8sClient.IgnoreNotFound(err)
 	if err != nil {
@@ -666,7 +1116,7 @@ func (r *ReplicaSetReconciler) createUserSecret(mdb mdbv1.MongoDBCommunity, user
 			SetNamespace(mdb.Namespace).
 			SetField(user.GetPasswordSecretKey(), string(password)).
 			Build()
-		err := r.client.Create(context.TODO(), &userSecret)
+		err := r.secretBackend.CreateSecret(userSecret)
 		if err != nil && apiErrors.IsAlreadyExists(err) {
 			r.log.Infof("The %s user secret already exists... moving forward: %s", user.Name, err)
 			return nil
@@ -676,9 +1126,9 @@ func (r *ReplicaSetReconciler) createUserSecret(mdb mdbv1.MongoDBCommunity, user
 	return nil
 }
 
-func (r *ReplicaSetReconciler) ensureMongoDbUriSecret(mdb mdbv1.MongoDBCommunity, user mdbv1.MongoDBUser) error {
+func (r *ReplicaSetReconciler) ensureMongoDbUriSecret(mdb mdbv1.MongoDBCommunity, user mdbv1.MongoDBUser, uriSecretName string, port int) error {
 	password, err := secret.ReadKey(
-		r.client,
+		r.secretBackend,
 		user.GetPasswordSecretKey(),
 		types.NamespacedName{
 			Name:      user.PasswordSecretRef.Name,
@@ -689,25 +1139,27 @@ func (r *ReplicaSetReconciler) ensureMongoDbUriSecret(mdb mdbv1.MongoDBCommunity
 		return err
 	}
 
-	uriSecret := buildMongoDbUriSecret(mdb, user.Name, password)
-	err = r.client.Create(context.TODO(), &uriSecret)
-	if err != nil && apiErrors.IsAlreadyExists(err) {
-		r.log.Infof("The mongodb URI secret already exists... moving forward: %s", err)
-		return nil
-	}
-	return err
+	// the secret is updated so that it follows changes of the name or the password of the user.
+	// It is always stored in Kubernetes, whatever the secret backend, as the exporter and backup Pods mount it.
+	uriSecret := buildMongoDbUriSecret(mdb, uriSecretName, user.Name, password, port)
+	return secret.CreateOrUpdate(r.client, uriSecret)
 }
 
-func buildMongoDbUriSecret(mdb mdbv1.MongoDBCommunity, username string, password string) corev1.Secret {
+func buildMongoDbUriSecret(mdb mdbv1.MongoDBCommunity, uriSecretName string, username string, password string, port int) corev1.Secret {
+	// the clients of a sharded cluster connect to the mongos routers, and a standalone is not part of a replica set.
+	replicaSet := fmt.Sprintf("&replicaSet=%s", mdb.Name)
+	if mdb.IsShardedCluster() || mdb.IsStandalone() {
+		replicaSet = ""
+	}
 	fullUri := fmt.Sprintf(
-		"mongodb://%s:%s@%s/?authSource=admin&replicaSet=%s&compressors=disabled&gssapiServiceName=mongodb",
+		"mongodb://%s:%s@%s/?authSource=admin%s&compressors=disabled&gssapiServiceName=mongodb",
 		username,
 		password,
-		strings.Join(mdb.Hosts(), ","),
-		mdb.Name,
+		strings.Join(mdb.HostsWithPort(port), ","),
+		replicaSet,
 	)
 	return secret.Builder().
-		SetName(fmt.Sprintf("%s-%s-uri", mdb.Name, username)).
+		SetName(uriSecretName).
 		SetNamespace(mdb.Namespace).
 		SetField("mongodb-uri", fullUri).
 		Build()
@@ -36,6 +36,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -135,7 +136,7 @@ func TestStatefulSet_IsCorrectlyConfigured(t *testing.T) {
 	err = mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: mdb.Name, Namespace: mdb.Namespace}, &sts)
 	assert.NoError(t, err)
 
-	assert.Len(t, sts.Spec.Template.Spec.Containers, 3)
+	assert.Len(t, sts.Spec.Template.Spec.Containers, 2)
 
 	agentContainer := sts.Spec.Template.Spec.Containers[1]
 	assert.Equal(t, construct.AgentName, agentContainer.Name)
@@ -363,6 +364,159 @@ func TestAutomationConfig_CustomMongodConfig(t *testing.T) {
 	}
 }
 
+func TestAutomationConfig_MemberConfig(t *testing.T) {
+	mdb := newTestReplicaSet()
+	zero := 0
+	mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{
+		{Tags: map[string]string{"dc": "east"}},
+		{},
+		{Votes: &zero, Hidden: true, SecondaryDelaySecs: 3600},
+	}
+
+	mgr := client.NewManager(&mdb)
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	members := ac.ReplicaSets[0].Members
+	assert.Equal(t, map[string]string{"dc": "east"}, members[0].Tags)
+	assert.Equal(t, 1, members[0].Priority)
+	assert.Equal(t, 1, members[1].Votes)
+	assert.True(t, members[2].Hidden)
+	assert.Equal(t, 3600, members[2].SecondaryDelaySecs)
+	assert.Equal(t, 0, members[2].Votes)
+	assert.Equal(t, 0, members[2].Priority)
+}
+
+func TestMemberConfig_IsValidated(t *testing.T) {
+	zero, one := 0, 1
+	noIndexes := false
+	tests := map[string]struct {
+		memberConfig []mdbv1.MemberConfiguration
+		message      string
+	}{
+		"Hidden members can't be electable": {
+			memberConfig: []mdbv1.MemberConfiguration{{Hidden: true, Priority: &one}},
+			message:      "memberConfig[0]: hidden members must have priority 0",
+		},
+		"Non-voting members can't be electable": {
+			memberConfig: []mdbv1.MemberConfiguration{{}, {Votes: &zero, Priority: &one}},
+			message:      "memberConfig[1]: members with 0 votes must have priority 0",
+		},
+		"Members without indexes can't be electable": {
+			memberConfig: []mdbv1.MemberConfiguration{{BuildIndexes: &noIndexes, Priority: &one}},
+			message:      "memberConfig[0]: members which don't build indexes must have priority 0",
+		},
+		"At least one member must be electable": {
+			memberConfig: []mdbv1.MemberConfiguration{{Priority: &zero}, {Hidden: true}, {Votes: &zero}},
+			message:      "at least one member must be able to become primary",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mdb := newTestReplicaSet()
+			mdb.Spec.MemberConfig = tc.memberConfig
+			mgr := client.NewManager(&mdb)
+			r := NewReconciler(mgr)
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+			assert.NoError(t, err)
+
+			assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+			assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+			assert.Contains(t, mdb.Status.Message, tc.message)
+		})
+	}
+}
+
+func TestMemberConfig_BuildIndexesCannotBeChanged(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	noIndexes := false
+	zero := 0
+	mdb.Spec.MemberConfig = []mdbv1.MemberConfiguration{{}, {}, {BuildIndexes: &noIndexes, Priority: &zero}}
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "memberConfig[2]: buildIndexes can't be changed on an existing member")
+}
+
+func TestAutomationConfig_ReplicaSetSettings(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Version = "4.4.0"
+	chainingAllowed := false
+	majority := intstr.FromString("majority")
+	mdb.Spec.ReplicaSetSettings = mdbv1.ReplicaSetSettings{
+		ChainingAllowed:      &chainingAllowed,
+		HeartbeatTimeoutSecs: 5,
+		DefaultReadConcern:   "majority",
+		DefaultWriteConcern:  &mdbv1.WriteConcern{W: &majority},
+	}
+
+	mgr := client.NewManager(&mdb)
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Equal(t, 1, ac.Version)
+
+	rs := ac.ReplicaSets[0]
+	assert.Equal(t, &chainingAllowed, rs.Settings.ChainingAllowed)
+	assert.Equal(t, 5, rs.Settings.HeartbeatTimeoutSecs)
+	assert.Equal(t, "majority", rs.DefaultRWConcern.DefaultReadConcern.Level)
+	assert.Equal(t, "majority", rs.DefaultRWConcern.DefaultWriteConcern.W)
+
+	// changing the settings bumps the version of the automation config
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.ReplicaSetSettings.ElectionTimeoutMillis = 5000
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+	ac = reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Equal(t, 2, ac.Version)
+	assert.Equal(t, 5000, ac.ReplicaSets[0].Settings.ElectionTimeoutMillis)
+}
+
+func TestReplicaSetSettings_AreValidated(t *testing.T) {
+	four := intstr.FromInt(4)
+	tests := map[string]struct {
+		version  string
+		settings mdbv1.ReplicaSetSettings
+		message  string
+	}{
+		"The write concern must be satisfiable": {
+			version:  "4.4.0",
+			settings: mdbv1.ReplicaSetSettings{DefaultWriteConcern: &mdbv1.WriteConcern{W: &four}},
+			message:  "replicaSetSettings.defaultWriteConcern: w must be between 0 and the number of members (3)",
+		},
+		"The default read concern requires MongoDB 4.4": {
+			version:  "4.2.2",
+			settings: mdbv1.ReplicaSetSettings{DefaultReadConcern: "majority"},
+			message:  "the default read and write concern require MongoDB 4.4 or later",
+		},
+		"getLastErrorDefaults is removed in MongoDB 5.0": {
+			version:  "5.0.5",
+			settings: mdbv1.ReplicaSetSettings{GetLastErrorDefaults: &mdbv1.WriteConcern{WTimeout: 1000}},
+			message:  "getLastErrorDefaults is not supported from MongoDB 5.0",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mdb := newTestReplicaSet()
+			mdb.Spec.Version = tc.version
+			mdb.Spec.ReplicaSetSettings = tc.settings
+			mgr := client.NewManager(&mdb)
+			r := NewReconciler(mgr)
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+			assert.NoError(t, err)
+
+			assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+			assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+			assert.Contains(t, mdb.Status.Message, tc.message)
+		})
+	}
+}
+
 func TestExistingPasswordAndKeyfile_AreUsedWhenTheSecretExists(t *testing.T) {
 	mdb := newScramReplicaSet()
 	mgr := client.NewManager(&mdb)
@@ -412,6 +566,88 @@ func TestScramIsConfiguredWhenNotSpecified(t *testing.T) {
 	assertReplicaSetIsConfiguredWithScram(t, newTestReplicaSet())
 }
 
+func TestScramDeploymentMechanisms_AreConfigured(t *testing.T) {
+	mdb := newScramReplicaSet()
+	mdb.Spec.Security.Authentication.Scram.Mechanisms = []mdbv1.ScramMechanism{mdbv1.ScramSha1, mdbv1.ScramSha256}
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	currentAc, err := automationconfig.ReadFromSecret(mgr.Client, types.NamespacedName{Name: mdb.AutomationConfigSecretName(), Namespace: mdb.Namespace})
+	assert.NoError(t, err)
+	assert.Contains(t, currentAc.Auth.DeploymentAuthMechanisms, scram.Sha1)
+	assert.Contains(t, currentAc.Auth.DeploymentAuthMechanisms, scram.Sha256)
+}
+
+func TestScramUserMechanism_MustBeEnabledInTheDeployment(t *testing.T) {
+	mdb := newScramReplicaSet(mdbv1.MongoDBUser{
+		Name:                       "my-user",
+		DB:                         "admin",
+		PasswordSecretRef:          mdbv1.SecretKeyReference{Name: "my-user-password"},
+		ScramCredentialsSecretName: "my-user",
+		Scram:                      mdbv1.ScramSettings{Mechanisms: []mdbv1.ScramMechanism{mdbv1.ScramSha1}},
+	})
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+}
+
+func TestUserAuthenticationRestrictions_AreConfigured(t *testing.T) {
+	mdb := newScramReplicaSet(mdbv1.MongoDBUser{
+		Name:                       "my-user",
+		DB:                         "admin",
+		PasswordSecretRef:          mdbv1.SecretKeyReference{Name: "my-user-password"},
+		ScramCredentialsSecretName: "my-user",
+		AuthenticationRestrictions: []mdbv1.AuthenticationRestriction{
+			{ClientSource: []string{"10.244.0.0/16"}, ServerAddress: []string{"10.96.0.10"}},
+		},
+	})
+	mgr := client.NewManager(&mdb)
+	s := secret.Builder().
+		SetName("my-user-password").
+		SetNamespace(mdb.Namespace).
+		SetField("password", "my-password").
+		Build()
+	assert.NoError(t, mgr.GetClient().Create(context.TODO(), &s))
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+
+	for _, acUser := range ac.Auth.Users {
+		if acUser.Username == "my-user" {
+			assert.Equal(t, []automationconfig.AuthenticationRestriction{
+				{ClientSource: []string{"10.244.0.0/16"}, ServerAddress: []string{"10.96.0.10"}},
+			}, acUser.AuthenticationRestrictions)
+			return
+		}
+	}
+	t.Fatal("my-user was not found in the automation config")
+}
+
+func TestUserAuthenticationRestrictions_MustBeIPsOrCIDRs(t *testing.T) {
+	mdb := newScramReplicaSet(mdbv1.MongoDBUser{
+		Name:                       "my-user",
+		DB:                         "admin",
+		PasswordSecretRef:          mdbv1.SecretKeyReference{Name: "my-user-password"},
+		ScramCredentialsSecretName: "my-user",
+		AuthenticationRestrictions: []mdbv1.AuthenticationRestriction{
+			{ClientSource: []string{"app.example.com"}},
+		},
+	})
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "clientSource app.example.com is not an IP address or a CIDR range")
+}
+
 func TestReplicaSet_IsScaledDown_OneMember_AtATime_WhenItAlreadyExists(t *testing.T) {
 	mdb := newTestReplicaSet()
 	mdb.Spec.Members = 5
@@ -727,3 +963,45 @@ func marshalRuntimeObjectFromYAMLBytes(bytes []byte, obj runtime.Object) error {
 	}
 	return json.Unmarshal(jsonBytes, &obj)
 }
+
+func TestStandalone_IsCreated(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mdb.Spec.Type = mdbv1.Standalone
+	mdb.Spec.Members = 1
+	mgr := client.NewManager(&mdb)
+
+	ac := reconcileAndReadAutomationConfig(t, mgr, mdb)
+	assert.Len(t, ac.Processes, 1)
+	assert.Empty(t, ac.ReplicaSets)
+	assert.Nil(t, ac.Processes[0].Args26.Get("replication").Data())
+
+	sts, err := mgr.Client.GetStatefulSet(mdb.NamespacedName())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *sts.Spec.Replicas)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Running, mdb.Status.Phase)
+
+	uriSecret := buildMongoDbUriSecret(mdb, "uri", "user", "password", mdb.GetPort())
+	assert.Contains(t, string(uriSecret.Data["mongodb-uri"]), "my-rs-0.my-rs-svc")
+	assert.NotContains(t, string(uriSecret.Data["mongodb-uri"]), "replicaSet")
+}
+
+func TestDeploymentType_CannotBeChanged(t *testing.T) {
+	mdb := newTestReplicaSet()
+	mgr := client.NewManager(&mdb)
+	r := NewReconciler(mgr)
+	res, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assertReconciliationSuccessful(t, res, err)
+
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	mdb.Spec.Type = mdbv1.Standalone
+	mdb.Spec.Members = 1
+	assert.NoError(t, mgr.GetClient().Update(context.TODO(), &mdb))
+
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: mdb.NamespacedName()})
+	assert.NoError(t, err)
+	assert.NoError(t, mgr.GetClient().Get(context.TODO(), mdb.NamespacedName(), &mdb))
+	assert.Equal(t, mdbv1.Failed, mdb.Status.Phase)
+	assert.Contains(t, mdb.Status.Message, "the type of the deployment can't be changed")
+}
@@ -0,0 +1,213 @@
+package validation
+
+import (
+	"reflect"
+
+	"github.com/blang/semver"
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
+	"github.com/pkg/errors"
+)
+
+// privilegeActions is the list of privilege actions known by the server. The value is the first version of
+// the server which accepts the action, or an empty string for the actions accepted by all the supported versions.
+// See https://docs.mongodb.com/manual/reference/privilege-actions/
+var privilegeActions = map[string]string{
+	// Query and write actions
+	"find": "", "insert": "", "remove": "", "update": "", "bypassDocumentValidation": "", "useUUID": "",
+	// Database management actions
+	"changeCustomData": "", "changeOwnCustomData": "", "changeOwnPassword": "", "changePassword": "",
+	"createCollection": "", "createIndex": "", "createRole": "", "createUser": "", "dropCollection": "",
+	"dropRole": "", "dropUser": "", "enableProfiler": "", "grantRole": "", "killCursors": "",
+	"killAnyCursor": "", "planCacheIndexFilter": "", "revokeRole": "", "setAuthenticationRestriction": "",
+	"setFeatureCompatibilityVersion": "", "unlock": "", "viewRole": "", "viewUser": "",
+	// Deployment management actions
+	"authSchemaUpgrade": "", "cleanupOrphaned": "", "cpuProfiler": "", "inprog": "",
+	"invalidateUserCache": "", "killop": "", "planCacheRead": "", "planCacheWrite": "", "storageDetails": "",
+	"getClusterParameter": "6.0.0", "setClusterParameter": "6.0.0", "setUserWriteBlockMode": "6.0.0",
+	"bypassWriteBlockingMode": "6.0.0",
+	// Change stream actions
+	"changeStream": "",
+	// Replication actions
+	"appendOplogNote": "", "replSetConfigure": "", "replSetGetConfig": "", "replSetGetStatus": "",
+	"replSetHeartbeat": "", "replSetResizeOplog": "", "replSetStateChange": "", "resync": "",
+	// Sharding actions
+	"addShard": "", "clearJumboFlag": "", "enableSharding": "", "refineCollectionShardKey": "",
+	"reshardCollection": "", "flushRouterConfig": "", "getShardMap": "", "getShardVersion": "",
+	"listShards": "", "moveChunk": "", "removeShard": "", "shardingState": "", "splitChunk": "",
+	"splitVector": "", "analyzeShardKey": "7.0.0", "checkMetadataConsistency": "7.0.0", "shardCollection": "6.0.0",
+	// Server administration actions
+	"applicationMessage": "", "closeAllDatabases": "", "collMod": "", "compact": "", "connPoolSync": "",
+	"convertToCapped": "", "dropConnections": "", "dropDatabase": "", "dropIndex": "", "forceUUID": "",
+	"fsync": "", "getDefaultRWConcern": "", "getParameter": "", "hostInfo": "", "logRotate": "",
+	"reIndex": "", "renameCollectionSameDB": "", "rotateCertificates": "", "setDefaultRWConcern": "",
+	"setParameter": "", "shutdown": "", "touch": "",
+	// Session actions
+	"impersonate": "", "listSessions": "", "killAnySession": "",
+	// Free monitoring actions
+	"checkFreeMonitoringStatus": "", "setFreeMonitoring": "",
+	// Diagnostic actions
+	"collStats": "", "connPoolStats": "", "dbHash": "", "dbStats": "", "getCmdLineOpts": "",
+	"getLog": "", "listDatabases": "", "listCollections": "", "listIndexes": "", "netstat": "",
+	"serverStatus": "", "validate": "", "top": "", "indexStats": "",
+	// Atlas Search actions
+	"listSearchIndexes": "6.0.7", "createSearchIndexes": "6.0.7", "dropSearchIndex": "6.0.7", "updateSearchIndex": "6.0.7",
+	// Internal actions
+	"anyAction": "", "internal": "",
+}
+
+// builtInRoles is the list of roles built into the server. The value is true for the roles
+// which only exist in the admin database.
+// See https://docs.mongodb.com/manual/reference/built-in-roles/
+var builtInRoles = map[string]bool{
+	"read": false, "readWrite": false, "dbAdmin": false, "dbOwner": false, "userAdmin": false,
+	"clusterAdmin": true, "clusterManager": true, "clusterMonitor": true, "hostManager": true,
+	"backup": true, "restore": true,
+	"readAnyDatabase": true, "readWriteAnyDatabase": true, "userAdminAnyDatabase": true, "dbAdminAnyDatabase": true,
+	"root": true, "__system": true, "enableSharding": true,
+}
+
+// ValidateCustomRole checks that the actions of the role are accepted by the given version of the server, and that
+// the roles it inherits from are either built-in roles or one of the given custom roles, without cycles.
+// It is also used for the roles declared in MongoDBCommunityRole resources.
+func ValidateCustomRole(role mdbv1.CustomRole, customRoles []mdbv1.CustomRole, version string) error {
+	if role.Role == "" || role.DB == "" {
+		return errors.New("custom roles require both role and db to be set")
+	}
+	for _, privilege := range role.Privileges {
+		if len(privilege.Actions) == 0 {
+			return errors.Errorf("role %s has a privilege without actions", automationconfig.RoleKey(role.DB, role.Role))
+		}
+		for _, action := range privilege.Actions {
+			if err := validatePrivilegeAction(action, version); err != nil {
+				return errors.Errorf("role %s %s", automationconfig.RoleKey(role.DB, role.Role), err)
+			}
+		}
+	}
+
+	rolesByKey := map[string]mdbv1.CustomRole{}
+	for _, r := range customRoles {
+		rolesByKey[automationconfig.RoleKey(r.DB, r.Role)] = r
+	}
+	rolesByKey[automationconfig.RoleKey(role.DB, role.Role)] = role
+	for _, inherited := range role.Roles {
+		if !isBuiltInRole(inherited) {
+			if _, ok := rolesByKey[automationconfig.RoleKey(inherited.DB, inherited.Name)]; !ok {
+				return errors.Errorf("role %s inherits role %s which does not exist", automationconfig.RoleKey(role.DB, role.Role), automationconfig.RoleKey(inherited.DB, inherited.Name))
+			}
+		}
+	}
+
+	if cycle := findRoleCycle(automationconfig.RoleKey(role.DB, role.Role), rolesByKey); cycle != "" {
+		return errors.Errorf("role %s inherits from itself through %s", automationconfig.RoleKey(role.DB, role.Role), cycle)
+	}
+	return nil
+}
+
+// validatePrivilegeAction checks that the action is known, and accepted by the given version of the server.
+// The version is not checked if it can't be parsed.
+func validatePrivilegeAction(action, version string) error {
+	minVersion, ok := privilegeActions[action]
+	if !ok {
+		return errors.Errorf("uses unknown privilege action %s", action)
+	}
+	if minVersion == "" {
+		return nil
+	}
+	serverVersion, err := semver.ParseTolerant(version)
+	if err != nil {
+		return nil
+	}
+	if serverVersion.LT(semver.MustParse(minVersion)) {
+		return errors.Errorf("uses privilege action %s, which requires MongoDB %s or later", action, minVersion)
+	}
+	return nil
+}
+
+// validateCustomRoles checks that the roles in the spec are unique and valid. The roles in the spec
+// can only inherit from built-in roles and the other roles in the spec. The roles which are unchanged since
+// the last successful deployment are not checked, so that the deployments created before the roles were
+// validated keep working; InvalidDeployedCustomRoles reports them instead.
+func validateCustomRoles(spec mdbv1.MongoDBCommunitySpec, deployedRoles []mdbv1.CustomRole) error {
+	roles := spec.Security.Roles
+	seen := map[string]bool{}
+	for _, role := range roles {
+		key := automationconfig.RoleKey(role.DB, role.Role)
+		duplicate := seen[key]
+		seen[key] = true
+		if isDeployedRole(role, deployedRoles) {
+			continue
+		}
+		if duplicate {
+			return errors.Errorf("role %s is defined more than once", key)
+		}
+
+		if err := ValidateCustomRole(role, roles, spec.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InvalidDeployedCustomRoles returns the reasons why the roles of the new spec which are unchanged since the
+// last successful deployment would be rejected if they were new.
+func InvalidDeployedCustomRoles(oldSpec, newSpec mdbv1.MongoDBCommunitySpec) []string {
+	var reasons []string
+	for _, role := range newSpec.Security.Roles {
+		if !isDeployedRole(role, oldSpec.Security.Roles) {
+			continue
+		}
+		if err := ValidateCustomRole(role, newSpec.Security.Roles, newSpec.Version); err != nil {
+			reasons = append(reasons, err.Error())
+		}
+	}
+	return reasons
+}
+
+// isDeployedRole returns true if the role is one of the deployed roles, without any change.
+func isDeployedRole(role mdbv1.CustomRole, deployedRoles []mdbv1.CustomRole) bool {
+	for _, deployed := range deployedRoles {
+		if reflect.DeepEqual(role, deployed) {
+			return true
+		}
+	}
+	return false
+}
+
+// findRoleCycle returns the key of the role inherited by the role with the given key through which
+// it inherits from itself, or an empty string if there is no cycle.
+func findRoleCycle(key string, rolesByKey map[string]mdbv1.CustomRole) string {
+	for _, inherited := range rolesByKey[key].Roles {
+		inheritedKey := automationconfig.RoleKey(inherited.DB, inherited.Name)
+		if inheritsFrom(inheritedKey, key, rolesByKey, map[string]bool{}) {
+			return inheritedKey
+		}
+	}
+	return ""
+}
+
+// inheritsFrom returns true if the role with the given key is, or inherits from, the target role.
+func inheritsFrom(key, target string, rolesByKey map[string]mdbv1.CustomRole, visited map[string]bool) bool {
+	if key == target {
+		return true
+	}
+	if visited[key] {
+		return false
+	}
+	visited[key] = true
+	for _, inherited := range rolesByKey[key].Roles {
+		if inheritsFrom(automationconfig.RoleKey(inherited.DB, inherited.Name), target, rolesByKey, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBuiltInRole returns true if the role is built into the server in the given database.
+func isBuiltInRole(role mdbv1.Role) bool {
+	adminOnly, ok := builtInRoles[role.Name]
+	if !ok {
+		return false
+	}
+	return !adminOnly || role.DB == "admin"
+}
@@ -1,14 +1,672 @@
 package validation
 
 import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
 	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 )
 
+// minScramIterations is the minimum iteration count accepted by mongod for both SCRAM mechanisms.
+const minScramIterations = 5000
+
+// maxVotingMembers is the maximum number of voting members of a replica set.
+const maxVotingMembers = 7
+
+// minKeyRotationInterval is the minimum interval between two scheduled rotations of the keyfile and the agent password.
+const minKeyRotationInterval = time.Hour
+
+// ValidateInitialSpec checks if the resource's initial Spec is valid.
+func ValidateInitialSpec(spec mdbv1.MongoDBCommunitySpec) error {
+	return validateSpec(spec, nil)
+}
+
+// Validate checks if the new Spec is valid, and if the transition from the old Spec is allowed.
 func Validate(oldSpec, newSpec mdbv1.MongoDBCommunitySpec) error {
 	if oldSpec.Security.TLS.Enabled && !newSpec.Security.TLS.Enabled {
-		return errors.New("TLS can't be set to disabled after it has been enabled")
+		// the deployment keeps using the certificates until the transition to disabled has completed.
+		if newSpec.Security.TLS.CertificateKeySecret.Name == "" || !hasCARef(newSpec.Security.TLS) {
+			return errors.New("TLS can't be disabled without keeping certificateKeySecretRef and the CA reference until the resource reaches the Running phase")
+		}
+	}
+
+	if err := validateBuildIndexesTransition(oldSpec, newSpec); err != nil {
+		return err
+	}
+
+	if err := validateShardedClusterTransition(oldSpec, newSpec); err != nil {
+		return err
+	}
+
+	if err := validateReplicaSetHorizonsTransition(oldSpec, newSpec); err != nil {
+		return err
+	}
+
+	return validateSpec(newSpec, oldSpec.Security.Roles)
+}
+
+// validateSpec checks the Spec for settings which are invalid on their own. The custom roles which are part of
+// deployedRoles are not checked.
+func validateSpec(spec mdbv1.MongoDBCommunitySpec, deployedRoles []mdbv1.CustomRole) error {
+	if err := validateTLS(spec); err != nil {
+		return err
+	}
+	if err := validateScram(spec); err != nil {
+		return err
+	}
+	if err := validateLDAPSettings(spec.Security.LDAP); err != nil {
+		return err
+	}
+	if err := validateCustomRoles(spec, deployedRoles); err != nil {
+		return err
+	}
+	if err := validateKeyRotation(spec.Security.Authentication.KeyRotation); err != nil {
+		return err
+	}
+	if err := validateShardedCluster(spec); err != nil {
+		return err
+	}
+	if err := validateStandalone(spec); err != nil {
+		return err
+	}
+	if err := validateExternalAccess(spec); err != nil {
+		return err
+	}
+	if err := validateReplicaSetHorizons(spec); err != nil {
+		return err
+	}
+	if err := validateServices(spec); err != nil {
+		return err
+	}
+	if err := validatePort(spec); err != nil {
+		return err
+	}
+	if err := validateArbiters(spec); err != nil {
+		return err
+	}
+	if err := validateAnalyticsMembers(spec); err != nil {
+		return err
+	}
+	if err := validateMemberConfig(spec); err != nil {
+		return err
+	}
+	if err := validateReplicaSetSettings(spec); err != nil {
+		return err
+	}
+	return validateClusterAuth(spec)
+}
+
+// validateMemberConfig checks the replica set configuration of the members against the rules enforced by mongod.
+// Entries beyond spec.members are ignored.
+func validateMemberConfig(spec mdbv1.MongoDBCommunitySpec) error {
+	if len(spec.MemberConfig) == 0 {
+		return nil
+	}
+	electable := false
+	explicitVotes := 0
+	for i := 0; i < spec.Members; i++ {
+		if i >= len(spec.MemberConfig) {
+			electable = true
+			continue
+		}
+		config := spec.MemberConfig[i]
+		votes := 1
+		if config.Votes != nil {
+			votes = *config.Votes
+			explicitVotes += votes
+		}
+		if votes != 0 && votes != 1 {
+			return errors.Errorf("memberConfig[%d]: votes must be 0 or 1", i)
+		}
+		if config.SecondaryDelaySecs < 0 {
+			return errors.Errorf("memberConfig[%d]: secondaryDelaySecs must not be negative", i)
+		}
+		if config.Priority == nil {
+			electable = electable || (votes == 1 && !cannotBePrimary(config))
+			continue
+		}
+		priority := *config.Priority
+		if priority < 0 || priority > 1000 {
+			return errors.Errorf("memberConfig[%d]: priority must be between 0 and 1000", i)
+		}
+		if priority > 0 {
+			if votes == 0 {
+				return errors.Errorf("memberConfig[%d]: members with 0 votes must have priority 0", i)
+			}
+			if config.Hidden {
+				return errors.Errorf("memberConfig[%d]: hidden members must have priority 0", i)
+			}
+			if config.SecondaryDelaySecs > 0 {
+				return errors.Errorf("memberConfig[%d]: delayed members must have priority 0", i)
+			}
+			if config.BuildIndexes != nil && !*config.BuildIndexes {
+				return errors.Errorf("memberConfig[%d]: members which don't build indexes must have priority 0", i)
+			}
+			electable = true
+		}
+	}
+	if explicitVotes > maxVotingMembers {
+		return errors.Errorf("a replica set can have at most %d voting members", maxVotingMembers)
+	}
+	if !electable {
+		return errors.New("at least one member must be able to become primary")
+	}
+	return nil
+}
+
+// cannotBePrimary returns true if the member configuration prevents the member from becoming primary.
+func cannotBePrimary(config mdbv1.MemberConfiguration) bool {
+	return config.Hidden || config.SecondaryDelaySecs > 0 || (config.BuildIndexes != nil && !*config.BuildIndexes)
+}
+
+// validateBuildIndexesTransition checks that buildIndexes is not changed on the existing members,
+// as mongod doesn't allow it.
+func validateBuildIndexesTransition(oldSpec, newSpec mdbv1.MongoDBCommunitySpec) error {
+	for i := 0; i < oldSpec.Members && i < newSpec.Members; i++ {
+		if buildsIndexes(oldSpec.MemberConfig, i) != buildsIndexes(newSpec.MemberConfig, i) {
+			return errors.Errorf("memberConfig[%d]: buildIndexes can't be changed on an existing member", i)
+		}
+	}
+	return nil
+}
+
+func buildsIndexes(memberConfig []mdbv1.MemberConfiguration, i int) bool {
+	if i >= len(memberConfig) || memberConfig[i].BuildIndexes == nil {
+		return true
+	}
+	return *memberConfig[i].BuildIndexes
+}
+
+// validateReplicaSetSettings checks the write concerns, and that the settings are supported by the MongoDB version.
+func validateReplicaSetSettings(spec mdbv1.MongoDBCommunitySpec) error {
+	settings := spec.ReplicaSetSettings
+	if settings.ElectionTimeoutMillis < 0 || settings.HeartbeatTimeoutSecs < 0 {
+		return errors.New("replicaSetSettings: electionTimeoutMillis and heartbeatTimeoutSecs must not be negative")
+	}
+	if settings.GetLastErrorDefaults != nil {
+		if err := validateWriteConcern(*settings.GetLastErrorDefaults, dataBearingMembers(spec)); err != nil {
+			return errors.Errorf("replicaSetSettings.getLastErrorDefaults: %s", err)
+		}
+	}
+	if settings.DefaultWriteConcern != nil {
+		if err := validateWriteConcern(*settings.DefaultWriteConcern, dataBearingMembers(spec)); err != nil {
+			return errors.Errorf("replicaSetSettings.defaultWriteConcern: %s", err)
+		}
+		if w := settings.DefaultWriteConcern.W; w != nil && w.Type == intstr.Int && w.IntValue() == 0 {
+			return errors.New("replicaSetSettings.defaultWriteConcern: w can't be 0")
+		}
+	}
+
+	version, err := semver.ParseTolerant(spec.Version)
+	if err != nil {
+		return nil
+	}
+	if (settings.DefaultReadConcern != "" || settings.DefaultWriteConcern != nil) && version.LT(semver.MustParse("4.4.0")) {
+		return errors.New("replicaSetSettings: the default read and write concern require MongoDB 4.4 or later")
+	}
+	if settings.GetLastErrorDefaults != nil && version.GTE(semver.MustParse("5.0.0")) {
+		return errors.New("replicaSetSettings: getLastErrorDefaults is not supported from MongoDB 5.0, use defaultWriteConcern instead")
+	}
+	return nil
+}
+
+// validateWriteConcern checks that the write concern can be satisfied by the data-bearing members.
+func validateWriteConcern(writeConcern mdbv1.WriteConcern, members int) error {
+	if writeConcern.WTimeout < 0 {
+		return errors.New("wtimeout must not be negative")
+	}
+	w := writeConcern.W
+	if w == nil {
+		return nil
+	}
+	if w.Type == intstr.Int {
+		if w.IntValue() < 0 || w.IntValue() > members {
+			return errors.Errorf("w must be between 0 and the number of members (%d)", members)
+		}
+		if w.IntValue() == 0 && writeConcern.J != nil && *writeConcern.J {
+			return errors.New("w: 0 can't be combined with j: true")
+		}
+		return nil
+	}
+	if w.StrVal == "" {
+		return errors.New("w must not be empty")
+	}
+	return nil
+}
+
+// validateShardedCluster checks the components of a sharded cluster, and that the settings which only apply
+// to a single replica set are not used with it.
+func validateShardedCluster(spec mdbv1.MongoDBCommunitySpec) error {
+	if !isShardedCluster(spec) {
+		return nil
+	}
+	config := spec.ShardedCluster
+	if config.ConfigServerMembers < 0 {
+		return errors.New("shardedCluster.configServerMembers must not be negative")
+	}
+	if config.Shards < 1 || config.MembersPerShard < 1 || config.MongosReplicas < 1 {
+		return errors.New("shardedCluster: shards, membersPerShard and mongosReplicas must be at least 1")
+	}
+	if spec.Arbiters > 0 || len(spec.MemberConfig) > 0 || len(spec.ReplicaSetHorizons) > 0 {
+		return errors.New("arbiters, memberConfig and replicaSetHorizons are not supported with a ShardedCluster")
+	}
+	if spec.ReplicaSetSettings.DefaultReadConcern != "" || spec.ReplicaSetSettings.DefaultWriteConcern != nil {
+		return errors.New("the default read and write concern are not supported with a ShardedCluster")
+	}
+	return nil
+}
+
+// validateStandalone checks that a standalone has a single member, and that no replica set setting is used with it.
+func validateStandalone(spec mdbv1.MongoDBCommunitySpec) error {
+	if deploymentType(spec) != mdbv1.Standalone {
+		return nil
+	}
+	if spec.Members != 1 {
+		return errors.Errorf("a Standalone must have exactly 1 member, not %d", spec.Members)
+	}
+	if spec.Arbiters > 0 || len(spec.MemberConfig) > 0 || len(spec.ReplicaSetHorizons) > 0 || spec.ReplicaSetSettings != (mdbv1.ReplicaSetSettings{}) {
+		return errors.New("arbiters, memberConfig, replicaSetHorizons and replicaSetSettings are not supported with a Standalone")
+	}
+	return nil
+}
+
+// validateExternalAccess checks that the external addresses can be added to the horizons of the members.
+func validateExternalAccess(spec mdbv1.MongoDBCommunitySpec) error {
+	externalAccess := spec.ExternalAccess
+	if !externalAccess.Enabled {
+		return nil
+	}
+	if deploymentType(spec) != mdbv1.ReplicaSet {
+		return errors.Errorf("externalAccess is not supported with a %s", spec.Type)
+	}
+	if externalAccess.ServiceType() == corev1.ServiceTypeNodePort && externalAccess.NodeHostname == "" {
+		return errors.New("externalAccess.nodeHostname is required when the type is NodePort")
+	}
+	if net.ParseIP(externalAccess.NodeHostname) != nil {
+		return errors.Errorf("externalAccess.nodeHostname must be a hostname, as split horizons don't support IP addresses: %s", externalAccess.NodeHostname)
+	}
+	for i, horizons := range spec.ReplicaSetHorizons {
+		if _, ok := horizons[externalAccess.GetHorizonName()]; ok {
+			return errors.Errorf("replicaSetHorizons[%d]: the horizon %q is set by externalAccess", i, externalAccess.GetHorizonName())
+		}
+	}
+	return nil
+}
+
+// validateServices checks that the Services of the primary and of the secondaries are only created for a replica set,
+// as the role labels they select are only set on its members.
+func validateServices(spec mdbv1.MongoDBCommunitySpec) error {
+	if spec.Services != (mdbv1.Services{}) && deploymentType(spec) != mdbv1.ReplicaSet {
+		return errors.Errorf("services are not supported with a %s", spec.Type)
+	}
+	return nil
+}
+
+// validatePort checks that net.port of additionalMongodConfig doesn't contradict spec.port, as it would override it
+// in the automation config while the Services and the connection strings use spec.port.
+func validatePort(spec mdbv1.MongoDBCommunitySpec) error {
+	dbPort := spec.AdditionalMongodConfig.GetDBPort()
+	if spec.Port != 0 && dbPort != 0 && spec.Port != dbPort {
+		return errors.Errorf("spec.port %d and net.port %d of additionalMongodConfig must be the same", spec.Port, dbPort)
+	}
+	return nil
+}
+
+// validateReplicaSetHorizons checks that each member has an entry with the same horizons, whose addresses are unique
+// and valid, and that TLS is enabled, as mongod selects the horizon of a client with the SNI of its TLS connection.
+func validateReplicaSetHorizons(spec mdbv1.MongoDBCommunitySpec) error {
+	horizons := spec.ReplicaSetHorizons
+	if len(horizons) == 0 && !spec.ExternalAccess.Enabled {
+		return nil
+	}
+	if !spec.Security.TLS.Enabled {
+		return errors.New("replicaSetHorizons and externalAccess require TLS to be enabled, as the horizons are selected with the SNI of the clients")
+	}
+	if len(horizons) == 0 {
+		return nil
+	}
+	if len(horizons) < spec.Members {
+		return errors.Errorf("replicaSetHorizons must have an entry for each of the %d members, it has %d", spec.Members, len(horizons))
+	}
+
+	usedBy := map[string]int{}
+	for i, memberHorizons := range horizons {
+		if !sameHorizonNames(horizons[0], memberHorizons) {
+			return errors.Errorf("replicaSetHorizons[%d]: every member must have the same horizons as replicaSetHorizons[0]", i)
+		}
+		for name, address := range memberHorizons {
+			if name == "" {
+				return errors.Errorf("replicaSetHorizons[%d]: the name of a horizon must not be empty", i)
+			}
+			if err := validateHorizonAddress(address); err != nil {
+				return errors.Errorf("replicaSetHorizons[%d].%s: %s", i, name, err)
+			}
+			if j, ok := usedBy[address]; ok {
+				return errors.Errorf("replicaSetHorizons[%d].%s: the address %s is already used by replicaSetHorizons[%d]", i, name, address, j)
+			}
+			usedBy[address] = i
+		}
+	}
+	return nil
+}
+
+// validateHorizonAddress checks that the address of a horizon is a DNS name and a port.
+func validateHorizonAddress(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.Errorf("%q must be a hostname and a port: %s", address, err)
+	}
+	if errs := k8svalidation.IsDNS1123Subdomain(host); len(errs) > 0 {
+		return errors.Errorf("%q is not a valid DNS name: %s", host, strings.Join(errs, ", "))
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return errors.Errorf("%q is not a valid port", port)
+	}
+	return nil
+}
+
+func sameHorizonNames(a, b automationconfig.ReplicaSetHorizons) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name := range a {
+		if _, ok := b[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// validateReplicaSetHorizonsTransition checks that the horizons of the members being removed are kept until the
+// scale down is complete, as they stay in the replica set until then.
+func validateReplicaSetHorizonsTransition(oldSpec, newSpec mdbv1.MongoDBCommunitySpec) error {
+	if len(newSpec.ReplicaSetHorizons) == 0 || len(newSpec.ReplicaSetHorizons) >= oldSpec.Members {
+		return nil
+	}
+	return errors.Errorf("replicaSetHorizons must keep an entry for each of the %d current members until the scale down is complete, it has %d", oldSpec.Members, len(newSpec.ReplicaSetHorizons))
+}
+
+// validateShardedClusterTransition checks that the type of the deployment is not changed, and that no shard is removed.
+func validateShardedClusterTransition(oldSpec, newSpec mdbv1.MongoDBCommunitySpec) error {
+	if deploymentType(oldSpec) != deploymentType(newSpec) {
+		return errors.New("the type of the deployment can't be changed")
+	}
+	if isShardedCluster(newSpec) && newSpec.ShardedCluster.Shards < oldSpec.ShardedCluster.Shards {
+		return errors.Errorf("shards can't be removed: the number of shards can't be decreased from %d to %d", oldSpec.ShardedCluster.Shards, newSpec.ShardedCluster.Shards)
+	}
+	return nil
+}
+
+// deploymentType returns the type of the deployment, which is a replica set when the type is not set.
+func deploymentType(spec mdbv1.MongoDBCommunitySpec) mdbv1.Type {
+	if spec.Type == "" {
+		return mdbv1.ReplicaSet
+	}
+	return spec.Type
+}
+
+func isShardedCluster(spec mdbv1.MongoDBCommunitySpec) bool {
+	return spec.Type == mdbv1.ShardedCluster
+}
+
+// dataBearingMembers returns the number of members of each replica set the write concerns apply to.
+func dataBearingMembers(spec mdbv1.MongoDBCommunitySpec) int {
+	if isShardedCluster(spec) {
+		return spec.ShardedCluster.MembersPerShard
+	}
+	return spec.Members
+}
+
+// validateArbiters checks that the data-bearing members outnumber the arbiters.
+func validateArbiters(spec mdbv1.MongoDBCommunitySpec) error {
+	if spec.Arbiters < 0 {
+		return errors.New("arbiters must not be negative")
+	}
+	if spec.Arbiters > 0 && spec.Arbiters >= spec.Members {
+		return errors.Errorf("the number of arbiters (%d) must be lower than the number of members (%d)", spec.Arbiters, spec.Members)
+	}
+	return nil
+}
+
+// maxReplicaSetMembers is the maximum number of members of a replica set, including the arbiters.
+const maxReplicaSetMembers = 50
+
+// validateAnalyticsMembers checks that the analytics members are only added to a replica set without horizons, as
+// they have none, and that the replica set doesn't exceed the maximum number of members.
+func validateAnalyticsMembers(spec mdbv1.MongoDBCommunitySpec) error {
+	analytics := spec.AnalyticsMembers.Members
+	if analytics < 0 {
+		return errors.New("analyticsMembers.members must not be negative")
+	}
+	if analytics == 0 {
+		return nil
+	}
+	if deploymentType(spec) != mdbv1.ReplicaSet {
+		return errors.Errorf("analyticsMembers are not supported with a %s", spec.Type)
+	}
+	if len(spec.ReplicaSetHorizons) > 0 || spec.ExternalAccess.Enabled {
+		return errors.New("analyticsMembers are not supported with replicaSetHorizons or externalAccess")
+	}
+	if total := spec.Members + spec.Arbiters + analytics; total > maxReplicaSetMembers {
+		return errors.Errorf("a replica set can't have more than %d members, including %d arbiters and %d analytics members", maxReplicaSetMembers, spec.Arbiters, analytics)
+	}
+	return nil
+}
+
+// validateKeyRotation checks that the keyfile and the agent password are not rotated more often than once an hour,
+// as each rotation updates the members twice.
+func validateKeyRotation(rotation mdbv1.KeyRotation) error {
+	if rotation.Interval != nil && rotation.Interval.Duration < minKeyRotationInterval {
+		return errors.Errorf("keyRotation.interval must be at least %s", minKeyRotationInterval)
+	}
+	return nil
+}
+
+// validateTLS checks that the TLS mode doesn't contradict the other TLS settings.
+func validateTLS(spec mdbv1.MongoDBCommunitySpec) error {
+	tls := spec.Security.TLS
+	if tls.CaConfigMap.Name != "" && tls.CaCertificateSecret.Name != "" {
+		return errors.New("only one of caConfigMapRef and caCertificateSecretRef can be set")
+	}
+	if tls.Enabled && !hasCARef(tls) {
+		return errors.New("TLS requires either caConfigMapRef or caCertificateSecretRef to be set")
+	}
+	if tls.Mode != "" {
+		if !tls.Enabled {
+			return errors.Errorf("TLS mode %s requires TLS to be enabled", tls.Mode)
+		}
+		if tls.Optional && tls.Mode == automationconfig.TLSModeRequired {
+			return errors.New("TLS can't be optional with mode requireTLS")
+		}
+	}
+	return validateTLSProtocols(tls.DisabledProtocols)
+}
+
+// validateTLSProtocols checks that the disabled protocols are known, and that at least one protocol remains enabled.
+func validateTLSProtocols(disabledProtocols []mdbv1.TLSProtocol) error {
+	disabled := map[mdbv1.TLSProtocol]bool{}
+	for _, protocol := range disabledProtocols {
+		if !containsTLSProtocol(mdbv1.TLSProtocols, protocol) {
+			return errors.Errorf("unknown TLS protocol %s", protocol)
+		}
+		if disabled[protocol] {
+			return errors.Errorf("TLS protocol %s is disabled more than once", protocol)
+		}
+		disabled[protocol] = true
+	}
+	if len(disabled) == len(mdbv1.TLSProtocols) {
+		return errors.New("at least one TLS protocol must remain enabled")
+	}
+	return nil
+}
+
+// validateScram checks that the SCRAM mechanisms of every user are enabled in the deployment.
+func validateScram(spec mdbv1.MongoDBCommunitySpec) error {
+	if err := validateScramSettings(spec.Security.Authentication.Scram); err != nil {
+		return err
+	}
+
+	for _, user := range spec.Users {
+		if err := ValidateUser(spec, user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateUser checks that the user can be configured in the deployment described by the Spec.
+// It is also used for the users declared in MongoDBCommunityUser resources.
+func ValidateUser(spec mdbv1.MongoDBCommunitySpec, user mdbv1.MongoDBUser) error {
+	if err := validateScramSettings(user.Scram); err != nil {
+		return errors.Errorf("user %s: %s", user.Name, err)
+	}
+	deploymentMechanisms := spec.Security.Authentication.GetDeploymentScramMechanisms()
+	for _, mechanism := range user.Scram.Mechanisms {
+		if !containsScramMechanism(deploymentMechanisms, mechanism) {
+			return errors.Errorf("user %s uses mechanism %s which is not enabled in the deployment", user.Name, mechanism)
+		}
+	}
+	if err := validateAuthenticationRestrictions(user.AuthenticationRestrictions); err != nil {
+		return errors.Errorf("user %s: %s", user.Name, err)
+	}
+	return nil
+}
+
+// validateAuthenticationRestrictions checks that the client sources and server addresses are IP addresses or CIDR ranges.
+func validateAuthenticationRestrictions(restrictions []mdbv1.AuthenticationRestriction) error {
+	for _, restriction := range restrictions {
+		for _, address := range restriction.ClientSource {
+			if !isIPOrCIDR(address) {
+				return errors.Errorf("clientSource %s is not an IP address or a CIDR range", address)
+			}
+		}
+		for _, address := range restriction.ServerAddress {
+			if !isIPOrCIDR(address) {
+				return errors.Errorf("serverAddress %s is not an IP address or a CIDR range", address)
+			}
+		}
+	}
+	return nil
+}
+
+func isIPOrCIDR(address string) bool {
+	if net.ParseIP(address) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(address)
+	return err == nil
+}
+
+// validateScramSettings checks that mechanisms are not repeated and that the iteration counts are above the minimum accepted by mongod.
+func validateScramSettings(settings mdbv1.ScramSettings) error {
+	for i, mechanism := range settings.Mechanisms {
+		if containsScramMechanism(settings.Mechanisms[i+1:], mechanism) {
+			return errors.Errorf("SCRAM mechanism %s is listed more than once", mechanism)
+		}
+	}
+	if settings.Sha1Iterations != 0 && settings.Sha1Iterations < minScramIterations {
+		return errors.Errorf("sha1Iterations must be at least %d", minScramIterations)
+	}
+	if settings.Sha256Iterations != 0 && settings.Sha256Iterations < minScramIterations {
+		return errors.Errorf("sha256Iterations must be at least %d", minScramIterations)
+	}
+	return nil
+}
+
+func containsScramMechanism(mechanisms []mdbv1.ScramMechanism, mechanism mdbv1.ScramMechanism) bool {
+	for _, m := range mechanisms {
+		if m == mechanism {
+			return true
+		}
+	}
+	return false
+}
+
+// validateLDAPSettings checks the format of the LDAP settings. It doesn't contact the LDAP servers: the members
+// check that they can reach them on startup, unless validateLDAPServerConfig is disabled.
+func validateLDAPSettings(ldap mdbv1.LDAP) error {
+	if !ldap.Enabled {
+		return nil
+	}
+	if len(ldap.Servers) == 0 {
+		return errors.New("LDAP requires at least one server")
+	}
+	for _, server := range ldap.Servers {
+		if err := validateLDAPServer(server); err != nil {
+			return err
+		}
+	}
+	if ldap.TransportSecurity != "" && ldap.TransportSecurity != mdbv1.LDAPTransportSecurityTLS && ldap.TransportSecurity != mdbv1.LDAPTransportSecurityNone {
+		return errors.Errorf("unknown LDAP transport security %s", ldap.TransportSecurity)
+	}
+	if ldap.BindQueryUser == "" && ldap.HasBindQueryPassword() {
+		return errors.New("LDAP bindQueryPasswordSecretRef requires bindQueryUser to be set")
+	}
+	if ldap.BindQueryUser != "" && !ldap.HasBindQueryPassword() {
+		return errors.New("LDAP bindQueryUser requires bindQueryPasswordSecretRef to be set")
+	}
+	if ldap.UserToDNMapping != "" {
+		var mapping []map[string]string
+		if err := json.Unmarshal([]byte(ldap.UserToDNMapping), &mapping); err != nil {
+			return errors.Errorf("LDAP userToDNMapping must be a JSON array of mappings: %s", err)
+		}
+	}
+	return nil
+}
+
+// validateLDAPServer checks that the server is in the host[:port] format.
+func validateLDAPServer(server string) error {
+	host := server
+	if strings.Contains(server, ":") {
+		h, port, err := net.SplitHostPort(server)
+		if err != nil {
+			return errors.Errorf("invalid LDAP server %s: %s", server, err)
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return errors.Errorf("invalid port in LDAP server %s", server)
+		}
+		host = h
 	}
+	if host == "" || strings.ContainsAny(host, " ,/") {
+		return errors.Errorf("invalid LDAP server %s", server)
+	}
+	return nil
+}
+
+// hasCARef returns true if the CA certificate is referenced from either a ConfigMap or a Secret.
+func hasCARef(tls mdbv1.TLS) bool {
+	return tls.CaConfigMap.Name != "" || tls.CaCertificateSecret.Name != ""
+}
 
+func containsTLSProtocol(protocols []mdbv1.TLSProtocol, protocol mdbv1.TLSProtocol) bool {
+	for _, p := range protocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// validateClusterAuth checks that x509 cluster authentication has TLS and a member certificate configured.
+func validateClusterAuth(spec mdbv1.MongoDBCommunitySpec) error {
+	internalCluster := spec.Security.Authentication.InternalCluster
+	if !internalCluster.Mode.UsesX509() {
+		return nil
+	}
+	if !spec.Security.TLS.Enabled {
+		return errors.Errorf("cluster authentication mode %s requires TLS to be enabled", internalCluster.Mode)
+	}
+	if internalCluster.CertificateKeySecret.Name == "" {
+		return errors.Errorf("cluster authentication mode %s requires a member certificate in internalCluster.certificateKeySecretRef", internalCluster.Mode)
+	}
 	return nil
 }
@@ -0,0 +1,258 @@
+package validation
+
+import (
+	"testing"
+
+	mdbv1 "github.com/mongodb/mongodb-kubernetes-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateInitialSpec(t *testing.T) {
+	tests := map[string]struct {
+		modify func(spec *mdbv1.MongoDBCommunitySpec)
+		// message is the expected validation error, or empty when the spec is valid.
+		message string
+	}{
+		"replica set": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {},
+		},
+
+		// sharded cluster
+		"sharded cluster": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.Type = mdbv1.ShardedCluster
+				spec.ShardedCluster = mdbv1.ShardedClusterConfiguration{Shards: 2, MembersPerShard: 3, MongosReplicas: 2}
+			},
+		},
+		"sharded cluster without shards": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.Type = mdbv1.ShardedCluster
+				spec.ShardedCluster = mdbv1.ShardedClusterConfiguration{Shards: 0, MembersPerShard: 3, MongosReplicas: 2}
+			},
+			message: "shards, membersPerShard and mongosReplicas must be at least 1",
+		},
+		"sharded cluster with arbiters": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.Type = mdbv1.ShardedCluster
+				spec.ShardedCluster = mdbv1.ShardedClusterConfiguration{Shards: 2, MembersPerShard: 3, MongosReplicas: 2}
+				spec.Arbiters = 1
+			},
+			message: "arbiters, memberConfig and replicaSetHorizons are not supported with a ShardedCluster",
+		},
+		"sharded cluster with a default read concern": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.Type = mdbv1.ShardedCluster
+				spec.ShardedCluster = mdbv1.ShardedClusterConfiguration{Shards: 2, MembersPerShard: 3, MongosReplicas: 2}
+				spec.Version = "4.4.0"
+				spec.ReplicaSetSettings.DefaultReadConcern = "majority"
+			},
+			message: "the default read and write concern are not supported with a ShardedCluster",
+		},
+
+		// standalone
+		"standalone": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.Type = mdbv1.Standalone
+				spec.Members = 1
+			},
+		},
+		"standalone with more than one member": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.Type = mdbv1.Standalone
+				spec.Members = 3
+			},
+			message: "a Standalone must have exactly 1 member, not 3",
+		},
+		"standalone with replica set settings": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.Type = mdbv1.Standalone
+				spec.Members = 1
+				spec.ReplicaSetSettings.ElectionTimeoutMillis = 5000
+			},
+			message: "arbiters, memberConfig, replicaSetHorizons and replicaSetSettings are not supported with a Standalone",
+		},
+
+		// external access
+		"external access": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ExternalAccess.Enabled = true
+			},
+		},
+		"external access on node ports without hostname": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ExternalAccess.Enabled = true
+				spec.ExternalAccess.Type = corev1.ServiceTypeNodePort
+			},
+			message: "externalAccess.nodeHostname is required when the type is NodePort",
+		},
+		"external access on node ports with an IP address": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ExternalAccess.Enabled = true
+				spec.ExternalAccess.Type = corev1.ServiceTypeNodePort
+				spec.ExternalAccess.NodeHostname = "203.0.113.10"
+			},
+			message: "externalAccess.nodeHostname must be a hostname",
+		},
+		"external access with a conflicting horizon": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ExternalAccess.Enabled = true
+				spec.ReplicaSetHorizons = mdbv1.ReplicaSetHorizonConfiguration{{"external": "a:27017"}, {"external": "b:27017"}, {"external": "c:27017"}}
+			},
+			message: `replicaSetHorizons[0]: the horizon "external" is set by externalAccess`,
+		},
+		"external access of a standalone": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ExternalAccess.Enabled = true
+				spec.Type = mdbv1.Standalone
+				spec.Members = 1
+			},
+			message: "externalAccess is not supported with a Standalone",
+		},
+
+		// replica set horizons
+		"horizons": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ReplicaSetHorizons = mdbv1.ReplicaSetHorizonConfiguration{
+					{"external": "my-rs-0.example.com:27017"},
+					{"external": "my-rs-1.example.com:27017"},
+					{"external": "my-rs-2.example.com:27017"},
+				}
+			},
+		},
+		"horizons with fewer entries than members": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ReplicaSetHorizons = mdbv1.ReplicaSetHorizonConfiguration{
+					{"external": "my-rs-0.example.com:27017"},
+					{"external": "my-rs-1.example.com:27017"},
+					{"external": "my-rs-2.example.com:27017"},
+				}
+				spec.Members = 4
+			},
+			message: "replicaSetHorizons must have an entry for each of the 4 members, it has 3",
+		},
+		"horizons without TLS": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.ReplicaSetHorizons = mdbv1.ReplicaSetHorizonConfiguration{
+					{"external": "my-rs-0.example.com:27017"},
+					{"external": "my-rs-1.example.com:27017"},
+					{"external": "my-rs-2.example.com:27017"},
+				}
+			},
+			message: "replicaSetHorizons and externalAccess require TLS to be enabled",
+		},
+		"different horizons": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ReplicaSetHorizons = mdbv1.ReplicaSetHorizonConfiguration{
+					{"external": "my-rs-0.example.com:27017"},
+					{"other": "my-rs-1.example.com:27017"},
+					{"external": "my-rs-2.example.com:27017"},
+				}
+			},
+			message: "replicaSetHorizons[1]: every member must have the same horizons as replicaSetHorizons[0]",
+		},
+		"horizons with a duplicate address": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ReplicaSetHorizons = mdbv1.ReplicaSetHorizonConfiguration{
+					{"external": "my-rs-0.example.com:27017"},
+					{"external": "my-rs-1.example.com:27017"},
+					{"external": "my-rs-0.example.com:27017"},
+				}
+			},
+			message: "replicaSetHorizons[2].external: the address my-rs-0.example.com:27017 is already used by replicaSetHorizons[0]",
+		},
+		"horizons with an invalid DNS name": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ReplicaSetHorizons = mdbv1.ReplicaSetHorizonConfiguration{
+					{"external": "My_RS.example.com:27017"},
+					{"external": "my-rs-1.example.com:27017"},
+					{"external": "my-rs-2.example.com:27017"},
+				}
+			},
+			message: `replicaSetHorizons[0].external: "My_RS.example.com" is not a valid DNS name`,
+		},
+		"horizons without port": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ReplicaSetHorizons = mdbv1.ReplicaSetHorizonConfiguration{
+					{"external": "my-rs-0.example.com"},
+					{"external": "my-rs-1.example.com:27017"},
+					{"external": "my-rs-2.example.com:27017"},
+				}
+			},
+			message: `replicaSetHorizons[0].external: "my-rs-0.example.com" must be a hostname and a port`,
+		},
+
+		// analytics members
+		"analytics members": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.Arbiters = 1
+				spec.AnalyticsMembers.Members = 2
+			},
+		},
+		"negative analytics members": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.AnalyticsMembers.Members = -1
+			},
+			message: "analyticsMembers.members must not be negative",
+		},
+		"analytics members of a sharded cluster": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.Type = mdbv1.ShardedCluster
+				spec.ShardedCluster = mdbv1.ShardedClusterConfiguration{Shards: 2, MembersPerShard: 3, MongosReplicas: 2}
+				spec.AnalyticsMembers.Members = 2
+			},
+			message: "analyticsMembers are not supported with a ShardedCluster",
+		},
+		"analytics members with external access": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				enableTLS(spec)
+				spec.ExternalAccess.Enabled = true
+				spec.AnalyticsMembers.Members = 2
+			},
+			message: "analyticsMembers are not supported with replicaSetHorizons or externalAccess",
+		},
+		"too many analytics members": {
+			modify: func(spec *mdbv1.MongoDBCommunitySpec) {
+				spec.Arbiters = 1
+				spec.AnalyticsMembers.Members = 47
+			},
+			message: "a replica set can't have more than 50 members, including 1 arbiters and 47 analytics members",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			spec := mdbv1.MongoDBCommunitySpec{
+				Members: 3,
+				Version: "4.2.2",
+			}
+			tc.modify(&spec)
+			err := ValidateInitialSpec(spec)
+			if tc.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.message)
+			}
+		})
+	}
+}
+
+func enableTLS(spec *mdbv1.MongoDBCommunitySpec) {
+	spec.Security.TLS = mdbv1.TLS{
+		Enabled:              true,
+		CaConfigMap:          mdbv1.LocalObjectReference{Name: "caConfigMap"},
+		CertificateKeySecret: mdbv1.LocalObjectReference{Name: "certificateKeySecret"},
+	}
+}
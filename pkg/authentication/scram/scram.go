@@ -2,6 +2,7 @@ package scram
 
 import (
 	"encoding/base64"
+	"strconv"
 
 	"github.com/pkg/errors"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/automationconfig"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/kube/secret"
+	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/contains"
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/util/generate"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
@@ -25,6 +27,9 @@ const (
 
 	sha1StoredKeyKey   = "sha-1-stored-key"
 	sha256StoredKeyKey = "sha-256-stored-key"
+
+	sha1IterationsKey   = "sha-1-iterations"
+	sha256IterationsKey = "sha-256-iterations"
 )
 
 // Configurable is an interface which any resource which can configure ScramSha authentication should implement.
@@ -72,10 +77,68 @@ type User struct {
 	// PasswordSecretName is the name of the secret which stores this user's password.
 	PasswordSecretName string
 
+	// PasswordSecretNamespace is the namespace of the secret which stores this user's password.
+	// Defaults to the namespace of the resource.
+	PasswordSecretNamespace string
+
 	// ScramCredentialsSecretName returns the name of the secret which stores the generated credentials
 	// for this user. These credentials will be generated if they do not exist, or used if they do.
 	// Note: there will be one secret with credentials per user created.
 	ScramCredentialsSecretName string
+
+	// Mechanisms is the list of mechanisms credentials are generated for, Sha1 and/or Sha256.
+	// Credentials are generated for both if empty.
+	Mechanisms []string
+
+	// ScramSha1Iterations is the iteration count of the SCRAM-SHA-1 credentials.
+	// Defaults to scramcredentials.DefaultScramSha1Iterations.
+	ScramSha1Iterations int
+
+	// ScramSha256Iterations is the iteration count of the SCRAM-SHA-256 credentials.
+	// Defaults to scramcredentials.DefaultScramSha256Iterations.
+	ScramSha256Iterations int
+
+	// AuthenticationRestrictions restricts the addresses the user can connect from and to.
+	AuthenticationRestrictions []automationconfig.AuthenticationRestriction
+}
+
+// passwordSecretNamespacedName returns the NamespacedName of the secret which stores this user's password.
+func (u User) passwordSecretNamespacedName(mdbNamespacedName types.NamespacedName) types.NamespacedName {
+	namespace := u.PasswordSecretNamespace
+	if namespace == "" {
+		namespace = mdbNamespacedName.Namespace
+	}
+	return types.NamespacedName{Name: u.PasswordSecretName, Namespace: namespace}
+}
+
+// getMechanisms returns the mechanisms credentials are generated for.
+func (u User) getMechanisms() []string {
+	if len(u.Mechanisms) == 0 {
+		return []string{Sha1, Sha256}
+	}
+	return u.Mechanisms
+}
+
+func (u User) usesSha1() bool {
+	return contains.String(u.getMechanisms(), Sha1)
+}
+
+func (u User) usesSha256() bool {
+	return contains.String(u.getMechanisms(), Sha256)
+}
+
+func (u User) getSha1Iterations() int {
+	if u.ScramSha1Iterations == 0 {
+		return scramcredentials.DefaultScramSha1Iterations
+	}
+	return u.ScramSha1Iterations
+}
+
+func (u User) getSha256Iterations() int {
+	if u.ScramSha256Iterations == 0 {
+		return scramcredentials.DefaultScramSha256Iterations
+	}
+	return u.ScramSha256Iterations
 }
 
 // Options contains a set of values that can be used for more fine grained configuration of authentication.
@@ -94,6 +157,10 @@ type Options struct {
 
 	// AutoAuthMechanism is the desired authentication mechanism that the agents will use.
 	AutoAuthMechanism string
+
+	// DeploymentAuthMechanisms is a list of additional authentication mechanisms enabled in the deployment.
+	// The AutoAuthMechanisms are always enabled.
+	DeploymentAuthMechanisms []string
 }
 
 // Enable will configure all of the required Kubernetes resources for SCRAM-SHA to be enabled.
@@ -134,23 +201,25 @@ func Enable(auth *automationconfig.Auth, secretGetUpdateCreateDeleter secret.Get
 }
 
 // ensureScramCredentials will ensure that the ScramSha1 & ScramSha256 credentials exist and are stored in the credentials
-// secret corresponding to user of the given MongoDB deployment.
+// secret corresponding to user of the given MongoDB deployment. Credentials are only generated for the mechanisms
+// of the user, the credentials of the other mechanism are returned empty.
 func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User, mdbNamespacedName types.NamespacedName) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
 
-	password, err := secret.ReadKey(getUpdateCreator, user.PasswordSecretKey, types.NamespacedName{Name: user.PasswordSecretName, Namespace: mdbNamespacedName.Namespace})
+	password, err := secret.ReadKey(getUpdateCreator, user.PasswordSecretKey, user.passwordSecretNamespacedName(mdbNamespacedName))
 	if err != nil {
 		// if the password is deleted, that's fine we can read from the stored credentials that were previously generated
 		if apiErrors.IsNotFound(err) {
 			zap.S().Debugf("password secret was not found, reading from credentials from secret/%s", user.ScramCredentialsSecretName)
-			return readExistingCredentials(getUpdateCreator, mdbNamespacedName, user.ScramCredentialsSecretName)
+			return readExistingCredentials(getUpdateCreator, mdbNamespacedName, user.ScramCredentialsSecretName, user.getMechanisms()...)
 		}
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not read secret key: %s", err)
 	}
 
-	// we should only need to generate new credentials in two situations.
+	// we should only need to generate new credentials in three situations.
 	// 1. We are creating the credentials for the first time
 	// 2. We are changing the password
-	shouldGenerateNewCredentials, err := needToGenerateNewCredentials(getUpdateCreator, user.Username, user.ScramCredentialsSecretName, mdbNamespacedName, password)
+	// 3. We are changing the mechanisms or iteration counts of the user
+	shouldGenerateNewCredentials, err := needToGenerateNewCredentials(getUpdateCreator, user, mdbNamespacedName, password)
 	if err != nil {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not determine if new credentials need to be generated: %s", err)
 	}
@@ -158,12 +227,12 @@ func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User,
 	// there are no changes required, we can re-use the same credentials.
 	if !shouldGenerateNewCredentials {
 		zap.S().Debugf("Credentials have not changed, using credentials stored in: secret/%s", user.ScramCredentialsSecretName)
-		return readExistingCredentials(getUpdateCreator, mdbNamespacedName, user.ScramCredentialsSecretName)
+		return readExistingCredentials(getUpdateCreator, mdbNamespacedName, user.ScramCredentialsSecretName, user.getMechanisms()...)
 	}
 
 	// the password has changed, or we are generating it for the first time
 	zap.S().Debugf("Generating new credentials and storing in secret/%s", user.ScramCredentialsSecretName)
-	sha1Creds, sha256Creds, err := generateScramShaCredentials(user.Username, password)
+	sha1Creds, sha256Creds, err := generateScramShaCredentials(user, password)
 	if err != nil {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("failed generating scram credentials: %s", err)
 	}
@@ -178,9 +247,10 @@ func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User,
 }
 
 // needToGenerateNewCredentials determines if it is required to generate new credentials or not.
-// this will be the case if we are either changing password, or are generating credentials for the first time.
-func needToGenerateNewCredentials(secretGetter secret.Getter, username, scramCredentialsSecretName string, mdbNamespacedName types.NamespacedName, password string) (bool, error) {
-	s, err := secretGetter.GetSecret(types.NamespacedName{Name: scramCredentialsSecretName, Namespace: mdbNamespacedName.Namespace})
+// this will be the case if we are either changing password, are generating credentials for the first time,
+// or the mechanisms or iteration counts of the user have changed.
+func needToGenerateNewCredentials(secretGetter secret.Getter, user User, mdbNamespacedName types.NamespacedName, password string) (bool, error) {
+	s, err := secretGetter.GetSecret(types.NamespacedName{Name: user.ScramCredentialsSecretName, Namespace: mdbNamespacedName.Namespace})
 	if err != nil {
 		// haven't generated credentials yet, so we are changing password
 		if apiErrors.IsNotFound(err) {
@@ -190,27 +260,30 @@ func needToGenerateNewCredentials(secretGetter secret.Getter, username, scramCre
 		return false, err
 	}
 
-	existingSha1Salt := s.Data[sha1SaltKey]
-	existingSha256Salt := s.Data[sha256SaltKey]
+	// a mechanism has been enabled for the user since the credentials were generated
+	if !secret.HasAllKeys(s, credentialKeys(user.getMechanisms()...)...) {
+		zap.S().Debugf("Existing credentials are missing a mechanism, generating new credentials")
+		return true, nil
+	}
 
 	// the salts are stored encoded, we need to decode them before we use them for
 	// salt generation
-	decodedSha1Salt, err := base64.StdEncoding.DecodeString(string(existingSha1Salt))
+	decodedSha1Salt, err := base64.StdEncoding.DecodeString(string(s.Data[sha1SaltKey]))
 	if err != nil {
 		return false, err
 	}
-	decodedSha256Salt, err := base64.StdEncoding.DecodeString(string(existingSha256Salt))
+	decodedSha256Salt, err := base64.StdEncoding.DecodeString(string(s.Data[sha256SaltKey]))
 	if err != nil {
 		return false, err
 	}
 
 	// regenerate credentials using the existing salts in order to see if the password has changed.
-	sha1Creds, sha256Creds, err := computeScramShaCredentials(username, password, decodedSha1Salt, decodedSha256Salt)
+	sha1Creds, sha256Creds, err := computeScramShaCredentials(user, password, decodedSha1Salt, decodedSha256Salt)
 	if err != nil {
 		return false, err
 	}
 
-	existingSha1Creds, existingSha256Creds, err := readExistingCredentials(secretGetter, mdbNamespacedName, scramCredentialsSecretName)
+	existingSha1Creds, existingSha256Creds, err := readExistingCredentials(secretGetter, mdbNamespacedName, user.ScramCredentialsSecretName, user.getMechanisms()...)
 	if err != nil {
 		return false, err
 	}
@@ -223,79 +296,133 @@ func needToGenerateNewCredentials(secretGetter secret.Getter, username, scramCre
 
 // generateScramShaCredentials creates a new set of credentials using randomly generated salts. The first returned element is
 // sha1 credentials, the second is sha256 credentials
-func generateScramShaCredentials(username string, password string) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
+func generateScramShaCredentials(user User, password string) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
 	sha1Salt, sha256Salt, err := generate.Salts()
 	if err != nil {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, err
 	}
 
-	sha1Creds, sha256Creds, err := computeScramShaCredentials(username, password, sha1Salt, sha256Salt)
+	sha1Creds, sha256Creds, err := computeScramShaCredentials(user, password, sha1Salt, sha256Salt)
 	if err != nil {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, err
 	}
 	return sha1Creds, sha256Creds, nil
 }
 
-// computeScramShaCredentials computes ScramSha 1 & 256 credentials using the provided salts
-func computeScramShaCredentials(username, password string, sha1Salt, sha256Salt []byte) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
-	scram1Creds, err := scramcredentials.ComputeScramSha1Creds(username, password, sha1Salt)
-	if err != nil {
-		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not generate scramSha1Creds: %s", err)
+// computeScramShaCredentials computes ScramSha 1 & 256 credentials for the mechanisms of the user using the provided salts
+func computeScramShaCredentials(user User, password string, sha1Salt, sha256Salt []byte) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
+	scram1Creds, scram256Creds := scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}
+	var err error
+
+	if user.usesSha1() {
+		scram1Creds, err = scramcredentials.ComputeScramSha1CredsWithIterations(user.Username, password, sha1Salt, user.getSha1Iterations())
+		if err != nil {
+			return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not generate scramSha1Creds: %s", err)
+		}
 	}
 
-	scram256Creds, err := scramcredentials.ComputeScramSha256Creds(password, sha256Salt)
-	if err != nil {
-		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not generate scramSha256Creds: %s", err)
+	if user.usesSha256() {
+		scram256Creds, err = scramcredentials.ComputeScramSha256CredsWithIterations(password, sha256Salt, user.getSha256Iterations())
+		if err != nil {
+			return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not generate scramSha256Creds: %s", err)
+		}
 	}
 
 	return scram1Creds, scram256Creds, nil
 }
 
 // createScramCredentialsSecret will create a Secret that contains all of the fields required to read these credentials
-// back in the future.
+// back in the future. Only the credentials which have been generated are stored.
 func createScramCredentialsSecret(getUpdateCreator secret.GetUpdateCreator, mdbObjectKey types.NamespacedName, scramCredentialsSecretName string, sha1Creds, sha256Creds scramcredentials.ScramCreds) error {
-	scramCredsSecret := secret.Builder().
+	builder := secret.Builder().
 		SetName(scramCredentialsSecretName).
-		SetNamespace(mdbObjectKey.Namespace).
-		SetField(sha1SaltKey, sha1Creds.Salt).
-		SetField(sha1StoredKeyKey, sha1Creds.StoredKey).
-		SetField(sha1ServerKeyKey, sha1Creds.ServerKey).
-		SetField(sha256SaltKey, sha256Creds.Salt).
-		SetField(sha256StoredKeyKey, sha256Creds.StoredKey).
-		SetField(sha256ServerKeyKey, sha256Creds.ServerKey).
-		Build()
-	return secret.CreateOrUpdate(getUpdateCreator, scramCredsSecret)
+		SetNamespace(mdbObjectKey.Namespace)
+
+	if sha1Creds != (scramcredentials.ScramCreds{}) {
+		builder.SetField(sha1SaltKey, sha1Creds.Salt).
+			SetField(sha1StoredKeyKey, sha1Creds.StoredKey).
+			SetField(sha1ServerKeyKey, sha1Creds.ServerKey).
+			SetField(sha1IterationsKey, strconv.Itoa(sha1Creds.IterationCount))
+	}
+	if sha256Creds != (scramcredentials.ScramCreds{}) {
+		builder.SetField(sha256SaltKey, sha256Creds.Salt).
+			SetField(sha256StoredKeyKey, sha256Creds.StoredKey).
+			SetField(sha256ServerKeyKey, sha256Creds.ServerKey).
+			SetField(sha256IterationsKey, strconv.Itoa(sha256Creds.IterationCount))
+	}
+
+	return secret.CreateOrUpdate(getUpdateCreator, builder.Build())
+}
+
+// ImportCredentials stores the existing credentials of a user in the secret the credentials are read from
+// when the password secret of the user doesn't exist. This allows users created outside of the operator
+// to be configured without knowing their password.
+func ImportCredentials(getUpdateCreator secret.GetUpdateCreator, mdbObjectKey types.NamespacedName, scramCredentialsSecretName string, sha1Creds, sha256Creds scramcredentials.ScramCreds) error {
+	return createScramCredentialsSecret(getUpdateCreator, mdbObjectKey, scramCredentialsSecretName, sha1Creds, sha256Creds)
 }
 
-// readExistingCredentials reads the existing set of credentials for both ScramSha 1 & 256
-func readExistingCredentials(secretGetter secret.Getter, mdbObjectKey types.NamespacedName, scramCredentialsSecretName string) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
+// credentialKeys returns the keys of the credentials secret required for the given mechanisms.
+func credentialKeys(mechanisms ...string) []string {
+	var keys []string
+	if contains.String(mechanisms, Sha1) {
+		keys = append(keys, sha1SaltKey, sha1ServerKeyKey, sha1StoredKeyKey)
+	}
+	if contains.String(mechanisms, Sha256) {
+		keys = append(keys, sha256SaltKey, sha256ServerKeyKey, sha256StoredKeyKey)
+	}
+	return keys
+}
+
+// readExistingCredentials reads the existing set of credentials for the given mechanisms, both ScramSha 1 & 256 if none are specified.
+// The credentials of a mechanism which is not requested are returned empty.
+func readExistingCredentials(secretGetter secret.Getter, mdbObjectKey types.NamespacedName, scramCredentialsSecretName string, mechanisms ...string) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
+	if len(mechanisms) == 0 {
+		mechanisms = []string{Sha1, Sha256}
+	}
+
 	credentialsSecret, err := secretGetter.GetSecret(types.NamespacedName{Name: scramCredentialsSecretName, Namespace: mdbObjectKey.Namespace})
 	if err != nil {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not get secret %s/%s: %s", mdbObjectKey.Namespace, scramCredentialsSecretName, err)
 	}
 
 	// we should really never hit this situation. It would only be possible if the secret storing credentials is manually edited.
-	if !secret.HasAllKeys(credentialsSecret, sha1SaltKey, sha1ServerKeyKey, sha1ServerKeyKey, sha256SaltKey, sha256ServerKeyKey, sha256StoredKeyKey) {
+	if !secret.HasAllKeys(credentialsSecret, credentialKeys(mechanisms...)...) {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("credentials secret did not have all of the required keys")
 	}
 
-	scramSha1Creds := scramcredentials.ScramCreds{
-		IterationCount: scramcredentials.DefaultScramSha1Iterations,
-		Salt:           string(credentialsSecret.Data[sha1SaltKey]),
-		ServerKey:      string(credentialsSecret.Data[sha1ServerKeyKey]),
-		StoredKey:      string(credentialsSecret.Data[sha1StoredKeyKey]),
+	scramSha1Creds := scramcredentials.ScramCreds{}
+	if contains.String(mechanisms, Sha1) {
+		scramSha1Creds = scramcredentials.ScramCreds{
+			IterationCount: readIterationCount(credentialsSecret.Data[sha1IterationsKey], scramcredentials.DefaultScramSha1Iterations),
+			Salt:           string(credentialsSecret.Data[sha1SaltKey]),
+			ServerKey:      string(credentialsSecret.Data[sha1ServerKeyKey]),
+			StoredKey:      string(credentialsSecret.Data[sha1StoredKeyKey]),
+		}
 	}
 
-	scramSha256Creds := scramcredentials.ScramCreds{
-		IterationCount: scramcredentials.DefaultScramSha256Iterations,
-		Salt:           string(credentialsSecret.Data[sha256SaltKey]),
-		ServerKey:      string(credentialsSecret.Data[sha256ServerKeyKey]),
-		StoredKey:      string(credentialsSecret.Data[sha256StoredKeyKey]),
+	scramSha256Creds := scramcredentials.ScramCreds{}
+	if contains.String(mechanisms, Sha256) {
+		scramSha256Creds = scramcredentials.ScramCreds{
+			IterationCount: readIterationCount(credentialsSecret.Data[sha256IterationsKey], scramcredentials.DefaultScramSha256Iterations),
+			Salt:           string(credentialsSecret.Data[sha256SaltKey]),
+			ServerKey:      string(credentialsSecret.Data[sha256ServerKeyKey]),
+			StoredKey:      string(credentialsSecret.Data[sha256StoredKeyKey]),
+		}
 	}
 
 	return scramSha1Creds, scramSha256Creds, nil
 }
 
+// readIterationCount parses the stored iteration count. Credentials generated before the iteration
+// counts were configurable don't store them, and use the defaults.
+func readIterationCount(stored []byte, defaultIterationCount int) int {
+	iterationCount, err := strconv.Atoi(string(stored))
+	if err != nil {
+		return defaultIterationCount
+	}
+	return iterationCount
+}
+
 // convertMongoDBResourceUsersToAutomationConfigUsers returns a list of users that are able to be set in the AutomationConfig
 func convertMongoDBResourceUsersToAutomationConfigUsers(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdb Configurable) ([]automationconfig.MongoDBUser, error) {
 	var usersWanted []automationconfig.MongoDBUser
@@ -326,9 +453,13 @@ func convertMongoDBUserToAutomationConfigUser(secretGetUpdateCreateDeleter secre
 	if err != nil {
 		return automationconfig.MongoDBUser{}, errors.Errorf("could not ensure scram credentials: %s", err)
 	}
-	acUser.AuthenticationRestrictions = []string{}
+	acUser.AuthenticationRestrictions = append([]automationconfig.AuthenticationRestriction{}, user.AuthenticationRestrictions...)
 	acUser.Mechanisms = []string{}
-	acUser.ScramSha1Creds = &sha1Creds
-	acUser.ScramSha256Creds = &sha256Creds
+	if user.usesSha1() {
+		acUser.ScramSha1Creds = &sha1Creds
+	}
+	if user.usesSha256() {
+		acUser.ScramSha256Creds = &sha256Creds
+	}
 	return acUser, nil
 }
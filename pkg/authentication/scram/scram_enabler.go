@@ -62,7 +62,8 @@ func enableAgentAuthentication(auth *automationconfig.Auth, agentPassword, agent
 }
 
 func enableDeploymentMechanisms(auth *automationconfig.Auth, opts Options) {
-	for _, authMode := range opts.AutoAuthMechanisms {
+	mechanisms := append(append([]string{}, opts.AutoAuthMechanisms...), opts.DeploymentAuthMechanisms...)
+	for _, authMode := range mechanisms {
 		if !contains.String(auth.DeploymentAuthMechanisms, authMode) {
 			auth.DeploymentAuthMechanisms = append(auth.DeploymentAuthMechanisms, authMode)
 		}
@@ -85,9 +85,9 @@ func TestComputeScramCredentials_ComputesSameStoredAndServerKey_WithSameSalt(t *
 	password := "X6oSVAfD1la8fJwhfN" // nolint
 
 	for i := 0; i < 10; i++ {
-		sha1Creds0, sha256Creds0, err := computeScramShaCredentials(username, password, sha1Salt, sha256SaltKey)
+		sha1Creds0, sha256Creds0, err := computeScramShaCredentials(User{Username: username}, password, sha1Salt, sha256SaltKey)
 		assert.NoError(t, err)
-		sha1Creds1, sha256Creds1, err := computeScramShaCredentials(username, password, sha1Salt, sha256SaltKey)
+		sha1Creds1, sha256Creds1, err := computeScramShaCredentials(User{Username: username}, password, sha1Salt, sha256SaltKey)
 		assert.NoError(t, err)
 
 		assert.True(t, reflect.DeepEqual(sha1Creds0, sha1Creds1))
@@ -139,6 +139,44 @@ func TestEnsureScramCredentials(t *testing.T) {
 
 }
 
+func TestEnsureScramCredentials_PerUserSettings(t *testing.T) {
+	mdb, user := buildConfigurableAndUser("mdb-0")
+	passwordSecret := secret.Builder().
+		SetName(user.PasswordSecretName).
+		SetNamespace(mdb.NamespacedName().Namespace).
+		SetField(user.PasswordSecretKey, "TDg_DESiScDrJV6").
+		Build()
+
+	t.Run("Only SCRAM-SHA-256 credentials are generated when SCRAM-SHA-1 is not used", func(t *testing.T) {
+		sha256User := user
+		sha256User.Mechanisms = []string{Sha256}
+		s := newMockedSecretGetUpdateCreateDeleter(passwordSecret)
+
+		scram1Creds, scram256Creds, err := ensureScramCredentials(s, sha256User, mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Empty(t, scram1Creds.Salt)
+		assert.NotEmpty(t, scram256Creds.Salt)
+
+		credsSecret, err := s.GetSecret(types.NamespacedName{Name: user.ScramCredentialsSecretName, Namespace: mdb.NamespacedName().Namespace})
+		assert.NoError(t, err)
+		assert.False(t, secret.HasAllKeys(credsSecret, sha1SaltKey))
+		assert.True(t, secret.HasAllKeys(credsSecret, sha256SaltKey, sha256ServerKeyKey, sha256StoredKeyKey))
+	})
+
+	t.Run("Changing the iteration count regenerates the credentials", func(t *testing.T) {
+		s := newMockedSecretGetUpdateCreateDeleter(passwordSecret)
+		_, original256Creds, err := ensureScramCredentials(s, user, mdb.NamespacedName())
+		assert.NoError(t, err)
+
+		updatedUser := user
+		updatedUser.ScramSha256Iterations = 20000
+		_, scram256Creds, err := ensureScramCredentials(s, updatedUser, mdb.NamespacedName())
+		assert.NoError(t, err)
+		assert.Equal(t, 20000, scram256Creds.IterationCount)
+		assert.NotEqual(t, original256Creds.StoredKey, scram256Creds.StoredKey)
+	})
+}
+
 func TestConvertMongoDBUserToAutomationConfigUser(t *testing.T) {
 	mdb, user := buildConfigurableAndUser("mdb-0")
 
@@ -161,6 +199,24 @@ func TestConvertMongoDBUserToAutomationConfigUser(t *testing.T) {
 			assert.Equal(t, user.Roles[i].Name, acRole.Role)
 			assert.Equal(t, user.Roles[i].Database, acRole.Database)
 		}
+		assert.Empty(t, acUser.AuthenticationRestrictions)
+	})
+
+	t.Run("Authentication restrictions are added to the automation config user", func(t *testing.T) {
+		passwordSecret := secret.Builder().
+			SetName(user.PasswordSecretName).
+			SetNamespace(mdb.NamespacedName().Namespace).
+			SetField(user.PasswordSecretKey, "TDg_DESiScDrJV6").
+			Build()
+		restrictedUser := user
+		restrictedUser.AuthenticationRestrictions = []automationconfig.AuthenticationRestriction{
+			{ClientSource: []string{"10.0.0.0/16"}, ServerAddress: []string{"10.1.0.1"}},
+		}
+
+		acUser, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(passwordSecret), mdb.NamespacedName(), restrictedUser)
+
+		assert.NoError(t, err)
+		assert.Equal(t, restrictedUser.AuthenticationRestrictions, acUser.AuthenticationRestrictions)
 	})
 
 	t.Run("If there is no password secret, the creation fails", func(t *testing.T) {
@@ -33,14 +33,24 @@ type ScramCreds struct {
 }
 
 func ComputeScramSha256Creds(password string, salt []byte) (ScramCreds, error) {
+	return ComputeScramSha256CredsWithIterations(password, salt, DefaultScramSha256Iterations)
+}
+
+// ComputeScramSha256CredsWithIterations computes SCRAM-SHA-256 credentials with the given iteration count.
+func ComputeScramSha256CredsWithIterations(password string, salt []byte, iterationCount int) (ScramCreds, error) {
 	base64EncodedSalt := base64.StdEncoding.EncodeToString(salt)
-	return computeScramCredentials(sha256.New, DefaultScramSha256Iterations, base64EncodedSalt, password)
+	return computeScramCredentials(sha256.New, iterationCount, base64EncodedSalt, password)
 }
 
 func ComputeScramSha1Creds(username, password string, salt []byte) (ScramCreds, error) {
+	return ComputeScramSha1CredsWithIterations(username, password, salt, DefaultScramSha1Iterations)
+}
+
+// ComputeScramSha1CredsWithIterations computes SCRAM-SHA-1 credentials with the given iteration count.
+func ComputeScramSha1CredsWithIterations(username, password string, salt []byte, iterationCount int) (ScramCreds, error) {
 	base64EncodedSalt := base64.StdEncoding.EncodeToString(salt)
 	password = md5Hex(username + ":mongo:" + password)
-	return computeScramCredentials(sha1.New, DefaultScramSha1Iterations, base64EncodedSalt, password)
+	return computeScramCredentials(sha1.New, iterationCount, base64EncodedSalt, password)
 }
 
 func md5Hex(s string) string {
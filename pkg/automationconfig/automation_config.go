@@ -3,6 +3,7 @@ package automationconfig
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/pkg/authentication/scramcredentials"
 	"github.com/stretchr/objx"
@@ -10,6 +11,7 @@ import (
 
 const (
 	Mongod                ProcessType = "mongod"
+	Mongos                ProcessType = "mongos"
 	DefaultMongoDBDataDir string      = "/data"
 	DefaultAgentLogPath   string      = "/var/log/mongodb-mms-automation"
 )
@@ -30,6 +32,37 @@ type AutomationConfig struct {
 	MonitoringVersions []MonitoringVersion    `json:"monitoringVersions"`
 	Options            Options                `json:"options"`
 	Roles              []CustomRole           `json:"roles,omitempty"`
+	Sharding           []ShardedCluster       `json:"sharding,omitempty"`
+}
+
+// ShardedCluster ties the config server replica set and the shards of a sharded cluster.
+type ShardedCluster struct {
+	Name                string  `json:"name"`
+	ConfigServerReplica string  `json:"configServerReplica"`
+	Shards              []Shard `json:"shards"`
+}
+
+type Shard struct {
+	Id string `json:"_id"`
+	Rs string `json:"rs"`
+}
+
+// ShardedClusterSpec describes the components of a sharded cluster. The replica sets and the mongos
+// processes are named after the StatefulSets they run in.
+type ShardedClusterSpec struct {
+	ConfigServer ReplicaSetSpec
+	Shards       []ReplicaSetSpec
+	Mongos       MongosSpec
+}
+
+type ReplicaSetSpec struct {
+	Name    string
+	Members int
+}
+
+type MongosSpec struct {
+	Name     string
+	Replicas int
 }
 
 type BackupVersion struct {
@@ -51,6 +84,8 @@ type Process struct {
 	ProcessType                 ProcessType `json:"processType"`
 	Version                     string      `json:"version"`
 	AuthSchemaVersion           int         `json:"authSchemaVersion"`
+	// Cluster is the name of the sharded cluster of a mongos process.
+	Cluster string `json:"cluster,omitempty"`
 }
 
 func (p *Process) SetPort(port int) *Process {
@@ -101,6 +136,14 @@ const (
 	TLSModeRequired  TLSMode = "requireTLS"
 )
 
+// TLSModeTransition is the order in which the TLS mode of a running deployment is changed.
+var TLSModeTransition = []TLSMode{
+	TLSModeDisabled,
+	TLSModeAllowed,
+	TLSModePreferred,
+	TLSModeRequired,
+}
+
 type ProcessType string
 
 type SystemLog struct {
@@ -118,37 +161,107 @@ type EngineConfig struct {
 }
 
 type ReplicaSet struct {
-	Id              string             `json:"_id"`
-	Members         []ReplicaSetMember `json:"members"`
-	ProtocolVersion string             `json:"protocolVersion"`
+	Id               string              `json:"_id"`
+	Members          []ReplicaSetMember  `json:"members"`
+	ProtocolVersion  string              `json:"protocolVersion"`
+	ConfigServer     bool                `json:"configsvr,omitempty"`
+	Settings         *ReplicaSetSettings `json:"settings,omitempty"`
+	DefaultRWConcern *DefaultRWConcern   `json:"defaultRWConcern,omitempty"`
+}
+
+// ReplicaSetSettings are the settings of the replica set configuration.
+type ReplicaSetSettings struct {
+	ChainingAllowed       *bool         `json:"chainingAllowed,omitempty"`
+	ElectionTimeoutMillis int           `json:"electionTimeoutMillis,omitempty"`
+	HeartbeatTimeoutSecs  int           `json:"heartbeatTimeoutSecs,omitempty"`
+	GetLastErrorDefaults  *WriteConcern `json:"getLastErrorDefaults,omitempty"`
+}
+
+// DefaultRWConcern is the cluster-wide default read and write concern, set with setDefaultRWConcern.
+type DefaultRWConcern struct {
+	DefaultReadConcern  *ReadConcern  `json:"defaultReadConcern,omitempty"`
+	DefaultWriteConcern *WriteConcern `json:"defaultWriteConcern,omitempty"`
+}
+
+type ReadConcern struct {
+	Level string `json:"level"`
+}
+
+type WriteConcern struct {
+	// W is either a number of members, "majority" or the name of a custom write concern.
+	W        interface{} `json:"w,omitempty"`
+	J        *bool       `json:"j,omitempty"`
+	WTimeout int         `json:"wtimeout,omitempty"`
 }
 
 type ReplicaSetMember struct {
-	Id          int                `json:"_id"`
-	Host        string             `json:"host"`
-	Priority    int                `json:"priority"`
-	ArbiterOnly bool               `json:"arbiterOnly"`
-	Votes       int                `json:"votes"`
-	Horizons    ReplicaSetHorizons `json:"horizons,omitempty"`
+	Id                 int                `json:"_id"`
+	Host               string             `json:"host"`
+	Priority           int                `json:"priority"`
+	ArbiterOnly        bool               `json:"arbiterOnly"`
+	Votes              int                `json:"votes"`
+	Hidden             bool               `json:"hidden"`
+	SecondaryDelaySecs int                `json:"secondaryDelaySecs,omitempty"`
+	BuildIndexes       *bool              `json:"buildIndexes,omitempty"`
+	Tags               map[string]string  `json:"tags,omitempty"`
+	Horizons           ReplicaSetHorizons `json:"horizons,omitempty"`
 }
 
 type ReplicaSetHorizons map[string]string
 
-func newReplicaSetMember(p Process, id int, horizons ReplicaSetHorizons, totalVotesSoFar int) ReplicaSetMember {
+// MemberOptions overrides the default replica set configuration of a member.
+type MemberOptions struct {
+	Votes              *int
+	Priority           *int
+	Hidden             bool
+	SecondaryDelaySecs int
+	BuildIndexes       *bool
+	Tags               map[string]string
+}
+
+// hasVotes returns true if the options set the votes of the member explicitly.
+func (o MemberOptions) hasVotes() bool {
+	return o.Votes != nil
+}
+
+// apply overrides the configuration of the member. Members which are hidden, delayed, don't vote or don't
+// build indexes can't become primary, so their priority defaults to 0.
+func (o MemberOptions) apply(member *ReplicaSetMember) {
+	if o.Votes != nil {
+		member.Votes = *o.Votes
+	}
+	member.Hidden = o.Hidden
+	member.SecondaryDelaySecs = o.SecondaryDelaySecs
+	member.BuildIndexes = o.BuildIndexes
+	member.Tags = o.Tags
+
+	cannotBePrimary := o.Hidden || o.SecondaryDelaySecs > 0 || member.Votes == 0 || (o.BuildIndexes != nil && !*o.BuildIndexes)
+	if o.Priority != nil {
+		member.Priority = *o.Priority
+	} else if cannotBePrimary {
+		member.Priority = 0
+	}
+}
+
+func newReplicaSetMember(p Process, id int, horizons ReplicaSetHorizons, isArbiter bool, totalVotesSoFar int) ReplicaSetMember {
 	// ensure that the number of voting members in the replica set is not more than 7
 	// as this is the maximum number of voting members.
 	votes := 1
 	priority := 1
-	if totalVotesSoFar > maxVotingMembers {
+	if totalVotesSoFar >= maxVotingMembers {
 		votes = 0
 		priority = 0
 	}
+	// arbiters hold no data, so they can't become primary.
+	if isArbiter {
+		priority = 0
+	}
 
 	return ReplicaSetMember{
 		Id:          id,
 		Host:        p.Name,
 		Priority:    priority,
-		ArbiterOnly: false,
+		ArbiterOnly: isArbiter,
 		Votes:       votes,
 		Horizons:    horizons,
 	}
@@ -156,8 +269,10 @@ func newReplicaSetMember(p Process, id int, horizons ReplicaSetHorizons, totalVo
 
 type Auth struct {
 	// Users is a list which contains the desired users at the project level.
-	Users    []MongoDBUser `json:"usersWanted,omitempty"`
-	Disabled bool          `json:"disabled"`
+	Users []MongoDBUser `json:"usersWanted,omitempty"`
+	// UsersDeleted is a list of users which the agents remove from the deployment.
+	UsersDeleted []DeletedUser `json:"usersDeleted,omitempty"`
+	Disabled     bool          `json:"disabled"`
 	// AuthoritativeSet indicates if the MongoDBUsers should be synced with the current list of Users
 	AuthoritativeSet bool `json:"authoritativeSet"`
 	// AutoAuthMechanisms is a list of auth mechanisms the Automation Agent is able to use
@@ -206,22 +321,42 @@ type AuthenticationRestriction struct {
 }
 
 type MongoDBUser struct {
-	Mechanisms                 []string `json:"mechanisms"`
-	Roles                      []Role   `json:"roles"`
-	Username                   string   `json:"user"`
-	Database                   string   `json:"db"`
-	AuthenticationRestrictions []string `json:"authenticationRestrictions"`
+	Mechanisms                 []string                    `json:"mechanisms"`
+	Roles                      []Role                      `json:"roles"`
+	Username                   string                      `json:"user"`
+	Database                   string                      `json:"db"`
+	AuthenticationRestrictions []AuthenticationRestriction `json:"authenticationRestrictions"`
 
 	// ScramShaCreds are generated by the operator.
 	ScramSha256Creds *scramcredentials.ScramCreds `json:"scramSha256Creds"`
 	ScramSha1Creds   *scramcredentials.ScramCreds `json:"scramSha1Creds"`
 }
 
+// DeletedUser is a user which is removed from the given databases of the deployment.
+type DeletedUser struct {
+	User string   `json:"user"`
+	Dbs  []string `json:"dbs"`
+}
+
 type Role struct {
 	Role     string `json:"role"`
 	Database string `json:"db"`
 }
 
+// UserKey returns the id of the user in the deployment, in the <db>.<username> format used by mongod.
+// A user without a database belongs to the admin database.
+func UserKey(db, username string) string {
+	if db == "" {
+		db = "admin"
+	}
+	return fmt.Sprintf("%s.%s", db, username)
+}
+
+// RoleKey returns the id of the role in the deployment, in the <db>.<role> format used by mongod.
+func RoleKey(db, role string) string {
+	return fmt.Sprintf("%s.%s", db, role)
+}
+
 func disabledAuth() Auth {
 	return Auth{
 		Users:                    make([]MongoDBUser, 0),
@@ -242,6 +377,7 @@ const (
 type TLS struct {
 	CAFilePath            string                `json:"CAFilePath"`
 	ClientCertificateMode ClientCertificateMode `json:"clientCertificateMode"`
+	AutoPEMKeyFilePath    string                `json:"autoPEMKeyFilePath,omitempty"`
 }
 
 type LogRotate struct {
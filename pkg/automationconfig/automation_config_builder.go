@@ -14,8 +14,19 @@ import (
 type Topology string
 
 const (
-	ReplicaSetTopology Topology = "ReplicaSet"
-	maxVotingMembers   int      = 7
+	ReplicaSetTopology     Topology = "ReplicaSet"
+	ShardedClusterTopology Topology = "ShardedCluster"
+	StandaloneTopology     Topology = "Standalone"
+	maxVotingMembers       int      = 7
+
+	configServerClusterRole = "configsvr"
+	shardClusterRole        = "shardsvr"
+)
+
+// AnalyticsNodeTypeTag and AnalyticsNodeType are the replica set tag of the analytics members.
+const (
+	AnalyticsNodeTypeTag = "nodeType"
+	AnalyticsNodeType    = "ANALYTICS"
 )
 
 type Modification func(*AutomationConfig)
@@ -28,7 +39,14 @@ type Builder struct {
 	processes          []Process
 	replicaSets        []ReplicaSet
 	replicaSetHorizons []ReplicaSetHorizons
+	memberOptions      []MemberOptions
+	replicaSetSettings *ReplicaSetSettings
+	shardedCluster     ShardedClusterSpec
+	defaultRWConcern   *DefaultRWConcern
 	members            int
+	port               int
+	arbiters           int
+	analyticsMembers   int
 	domain             string
 	name               string
 	fcv                string
@@ -57,6 +75,7 @@ func NewBuilder() *Builder {
 		backupVersions:       []BackupVersion{},
 		monitoringVersions:   []MonitoringVersion{},
 		processModifications: []func(int, *Process){},
+		port:                 27017,
 		tlsConfig:            nil,
 		sslConfig:            nil,
 	}
@@ -67,6 +86,12 @@ func (b *Builder) SetOptions(options Options) *Builder {
 	return b
 }
 
+// SetPort sets the port all the processes listen on.
+func (b *Builder) SetPort(port int) *Builder {
+	b.port = port
+	return b
+}
+
 func (b *Builder) SetTopology(topology Topology) *Builder {
 	b.topology = topology
 	return b
@@ -77,6 +102,37 @@ func (b *Builder) SetReplicaSetHorizons(horizons []ReplicaSetHorizons) *Builder
 	return b
 }
 
+// SetMemberOptions sets the options of the data-bearing members, by index. Members without options
+// keep the default configuration.
+func (b *Builder) SetMemberOptions(memberOptions []MemberOptions) *Builder {
+	b.memberOptions = memberOptions
+	return b
+}
+
+func (b *Builder) SetReplicaSetSettings(settings *ReplicaSetSettings) *Builder {
+	b.replicaSetSettings = settings
+	return b
+}
+
+func (b *Builder) SetDefaultRWConcern(defaultRWConcern *DefaultRWConcern) *Builder {
+	b.defaultRWConcern = defaultRWConcern
+	return b
+}
+
+// SetShardedCluster sets the components of the sharded cluster built with the ShardedClusterTopology.
+func (b *Builder) SetShardedCluster(shardedCluster ShardedClusterSpec) *Builder {
+	b.shardedCluster = shardedCluster
+	return b
+}
+
+// memberOptionsFor returns the options of the member with the given index. Arbiters have no options.
+func (b *Builder) memberOptionsFor(i int) (MemberOptions, bool) {
+	if i >= b.members || i >= len(b.memberOptions) {
+		return MemberOptions{}, false
+	}
+	return b.memberOptions[i], true
+}
+
 func (b *Builder) SetTLSConfig(tlsConfig TLS) *Builder {
 	b.tlsConfig = &tlsConfig
 	return b
@@ -92,6 +148,18 @@ func (b *Builder) SetMembers(members int) *Builder {
 	return b
 }
 
+// SetArbiters sets the number of arbiters, which are added to the replica set after the data-bearing members.
+func (b *Builder) SetArbiters(arbiters int) *Builder {
+	b.arbiters = arbiters
+	return b
+}
+
+// SetAnalyticsMembers sets the number of analytics members, which are added to the replica set after the arbiters.
+func (b *Builder) SetAnalyticsMembers(analyticsMembers int) *Builder {
+	b.analyticsMembers = analyticsMembers
+	return b
+}
+
 func (b *Builder) SetDomain(domain string) *Builder {
 	b.domain = domain
 	return b
@@ -192,54 +260,22 @@ func (b *Builder) setFeatureCompatibilityVersionIfUpgradeIsHappening() error {
 }
 
 func (b *Builder) Build() (AutomationConfig, error) {
-	hostnames := make([]string, b.members)
-	for i := 0; i < b.members; i++ {
-		hostnames[i] = fmt.Sprintf("%s-%d.%s", b.name, i, b.domain)
-	}
-
-	members := make([]ReplicaSetMember, b.members)
-	processes := make([]Process, b.members)
-
 	if err := b.setFeatureCompatibilityVersionIfUpgradeIsHappening(); err != nil {
 		return AutomationConfig{}, errors.Errorf("can't build the automation config: %s", err)
 	}
-	for i, h := range hostnames {
-
-		process := &Process{
-			Name:                        toProcessName(b.name, i),
-			HostName:                    h,
-			FeatureCompatibilityVersion: versions.CalculateFeatureCompatibilityVersion(b.mongodbVersion),
-			ProcessType:                 Mongod,
-			Version:                     b.mongodbVersion,
-			AuthSchemaVersion:           5,
-		}
-		process.SetSystemLog(SystemLog{
-			Destination: "file",
-			Path:        path.Join(DefaultAgentLogPath, "/mongodb.log"),
-			LogAppend:   true,
-		})
-
-		if b.fcv != "" {
-			process.FeatureCompatibilityVersion = b.fcv
-		}
-
-		process.SetPort(27017)
-		process.SetStoragePath(DefaultMongoDBDataDir)
-		process.SetReplicaSetName(b.name)
 
-		for _, mod := range b.processModifications {
-			mod(i, process)
-		}
-
-		processes[i] = *process
-
-		totalVotes := 0
-		if b.replicaSetHorizons != nil {
-			members[i] = newReplicaSetMember(*process, i, b.replicaSetHorizons[i], totalVotes)
-		} else {
-			members[i] = newReplicaSetMember(*process, i, nil, totalVotes)
-		}
-		totalVotes += members[i].Votes
+	var processes []Process
+	var replicaSets []ReplicaSet
+	var sharding []ShardedCluster
+	switch b.topology {
+	case ShardedClusterTopology:
+		processes, replicaSets, sharding = b.buildShardedCluster()
+	case StandaloneTopology:
+		processes, replicaSets = b.buildStandalone(), []ReplicaSet{}
+	default:
+		var replicaSet ReplicaSet
+		processes, replicaSet = b.buildReplicaSet()
+		replicaSets = []ReplicaSet{replicaSet}
 	}
 
 	if b.auth == nil {
@@ -253,15 +289,10 @@ func (b *Builder) Build() (AutomationConfig, error) {
 	}
 
 	currentAc := AutomationConfig{
-		Version:   b.previousAC.Version,
-		Processes: processes,
-		ReplicaSets: []ReplicaSet{
-			{
-				Id:              b.name,
-				Members:         members,
-				ProtocolVersion: "1",
-			},
-		},
+		Version:            b.previousAC.Version,
+		Processes:          processes,
+		ReplicaSets:        replicaSets,
+		Sharding:           sharding,
 		MonitoringVersions: b.monitoringVersions,
 		BackupVersions:     b.backupVersions,
 		Versions:           b.versions,
@@ -303,10 +334,197 @@ func (b *Builder) Build() (AutomationConfig, error) {
 	return currentAc, nil
 }
 
+// buildReplicaSet builds the processes and the replica set of the members, the arbiters and the analytics members.
+func (b *Builder) buildReplicaSet() ([]Process, ReplicaSet) {
+	processNames := make([]string, b.members+b.arbiters+b.analyticsMembers)
+	for i := 0; i < b.members; i++ {
+		processNames[i] = toProcessName(b.name, i)
+	}
+	for i := 0; i < b.arbiters; i++ {
+		processNames[b.members+i] = toArbiterProcessName(b.name, i)
+	}
+	for i := 0; i < b.analyticsMembers; i++ {
+		processNames[b.members+b.arbiters+i] = toAnalyticsProcessName(b.name, i)
+	}
+
+	members := make([]ReplicaSetMember, len(processNames))
+	processes := make([]Process, len(processNames))
+
+	// the members which vote explicitly are counted first, so that the limit of voting members only
+	// applies to the members with the default configuration.
+	totalVotes := 0
+	for i := range processNames {
+		if options, _ := b.memberOptionsFor(i); options.hasVotes() {
+			totalVotes += *options.Votes
+		}
+	}
+	for i, processName := range processNames {
+		isArbiter := i >= b.members && i < b.members+b.arbiters
+		isAnalytics := i >= b.members+b.arbiters
+
+		process := b.newMongodProcess(processName, b.name)
+		for _, mod := range b.processModifications {
+			mod(i, process)
+		}
+
+		processes[i] = *process
+
+		options, hasOptions := b.memberOptionsFor(i)
+		votesSoFar := totalVotes
+		if options.hasVotes() {
+			// the vote of this member has already been counted
+			votesSoFar = 0
+		}
+		// clients don't connect to arbiters, so they have no horizons.
+		if i < len(b.replicaSetHorizons) && !isArbiter && !isAnalytics {
+			members[i] = newReplicaSetMember(*process, i, b.replicaSetHorizons[i], isArbiter, votesSoFar)
+		} else {
+			members[i] = newReplicaSetMember(*process, i, nil, isArbiter, votesSoFar)
+		}
+		if hasOptions {
+			options.apply(&members[i])
+		}
+		if isAnalytics {
+			setAnalyticsMemberOptions(&members[i])
+		}
+		if !options.hasVotes() {
+			totalVotes += members[i].Votes
+		}
+	}
+
+	return processes, ReplicaSet{
+		Id:               b.name,
+		Members:          members,
+		ProtocolVersion:  "1",
+		Settings:         b.replicaSetSettings,
+		DefaultRWConcern: b.defaultRWConcern,
+	}
+}
+
+// buildShardedCluster builds the processes and the replica sets of the config servers and of the shards,
+// the mongos processes, and the sharding section which ties them together.
+func (b *Builder) buildShardedCluster() ([]Process, []ReplicaSet, []ShardedCluster) {
+	processes := []Process{}
+	replicaSets := []ReplicaSet{}
+
+	addReplicaSet := func(spec ReplicaSetSpec, clusterRole string) {
+		members := make([]ReplicaSetMember, spec.Members)
+		totalVotes := 0
+		for i := 0; i < spec.Members; i++ {
+			process := b.newMongodProcess(toProcessName(spec.Name, i), spec.Name)
+			process.SetArgs26Field("sharding.clusterRole", clusterRole)
+			for _, mod := range b.processModifications {
+				mod(len(processes), process)
+			}
+			processes = append(processes, *process)
+			members[i] = newReplicaSetMember(*process, i, nil, false, totalVotes)
+			totalVotes += members[i].Votes
+		}
+		replicaSets = append(replicaSets, ReplicaSet{
+			Id:              spec.Name,
+			Members:         members,
+			ProtocolVersion: "1",
+			ConfigServer:    clusterRole == configServerClusterRole,
+			Settings:        b.replicaSetSettings,
+		})
+	}
+
+	addReplicaSet(b.shardedCluster.ConfigServer, configServerClusterRole)
+	shards := make([]Shard, len(b.shardedCluster.Shards))
+	for i, shard := range b.shardedCluster.Shards {
+		addReplicaSet(shard, shardClusterRole)
+		shards[i] = Shard{Id: shard.Name, Rs: shard.Name}
+	}
+
+	for i := 0; i < b.shardedCluster.Mongos.Replicas; i++ {
+		process := b.newMongosProcess(toProcessName(b.shardedCluster.Mongos.Name, i))
+		for _, mod := range b.processModifications {
+			mod(len(processes), process)
+		}
+		processes = append(processes, *process)
+	}
+
+	return processes, replicaSets, []ShardedCluster{
+		{
+			Name:                b.name,
+			ConfigServerReplica: b.shardedCluster.ConfigServer.Name,
+			Shards:              shards,
+		},
+	}
+}
+
+// buildStandalone returns the single mongod process of a standalone deployment, which is not part of a replica set.
+func (b *Builder) buildStandalone() []Process {
+	process := b.newProcess(toProcessName(b.name, 0), Mongod)
+	process.SetStoragePath(DefaultMongoDBDataDir)
+	for _, mod := range b.processModifications {
+		mod(0, process)
+	}
+	return []Process{*process}
+}
+
+// newMongodProcess returns a mongod process which is a member of the given replica set.
+func (b *Builder) newMongodProcess(name, replSetName string) *Process {
+	process := b.newProcess(name, Mongod)
+	process.SetStoragePath(DefaultMongoDBDataDir)
+	process.SetReplicaSetName(replSetName)
+	return process
+}
+
+// newMongosProcess returns a mongos process which routes the queries to the sharded cluster of the builder.
+func (b *Builder) newMongosProcess(name string) *Process {
+	process := b.newProcess(name, Mongos)
+	process.Cluster = b.name
+	return process
+}
+
+func (b *Builder) newProcess(name string, processType ProcessType) *Process {
+	process := &Process{
+		Name:                        name,
+		HostName:                    fmt.Sprintf("%s.%s", name, b.domain),
+		FeatureCompatibilityVersion: versions.CalculateFeatureCompatibilityVersion(b.mongodbVersion),
+		ProcessType:                 processType,
+		Version:                     b.mongodbVersion,
+		AuthSchemaVersion:           5,
+	}
+	process.SetSystemLog(SystemLog{
+		Destination: "file",
+		Path:        path.Join(DefaultAgentLogPath, "/mongodb.log"),
+		LogAppend:   true,
+	})
+
+	if b.fcv != "" {
+		process.FeatureCompatibilityVersion = b.fcv
+	}
+
+	process.SetPort(b.port)
+	return process
+}
+
 func toProcessName(name string, index int) string {
 	return fmt.Sprintf("%s-%d", name, index)
 }
 
+// toArbiterProcessName returns the name of the arbiter, which is the name of its Pod in the "<name>-arb" StatefulSet.
+func toArbiterProcessName(name string, index int) string {
+	return fmt.Sprintf("%s-arb-%d", name, index)
+}
+
+// toAnalyticsProcessName returns the name of the analytics member, which is the name of its Pod in the "<name>-analytics" StatefulSet.
+func toAnalyticsProcessName(name string, index int) string {
+	return fmt.Sprintf("%s-analytics-%d", name, index)
+}
+
+// setAnalyticsMemberOptions makes the member an analytics member: it never becomes primary and doesn't vote, so that
+// it doesn't count in the majority of the replica set, and it is hidden, so that the clients of the replica set don't
+// read from it. It is tagged so that it can be told apart from the other members.
+func setAnalyticsMemberOptions(member *ReplicaSetMember) {
+	member.Priority = 0
+	member.Votes = 0
+	member.Hidden = true
+	member.Tags = map[string]string{AnalyticsNodeTypeTag: AnalyticsNodeType}
+}
+
 func versionsContain(versions []MongoDbVersionConfig, version MongoDbVersionConfig) bool {
 	for _, v := range versions {
 		if reflect.DeepEqual(v, version) {
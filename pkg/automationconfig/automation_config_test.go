@@ -1,6 +1,7 @@
 package automationconfig
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -62,6 +63,252 @@ func TestBuildAutomationConfig(t *testing.T) {
 	}
 }
 
+func TestBuildAutomationConfig_WithArbiters(t *testing.T) {
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMongoDBVersion("4.2.0").
+		SetMembers(2).
+		SetArbiters(1).
+		SetReplicaSetHorizons([]ReplicaSetHorizons{
+			{"horizon": "test-horizon-0"},
+			{"horizon": "test-horizon-1"},
+		}).
+		Build()
+
+	assert.NoError(t, err)
+	assert.Len(t, ac.Processes, 3)
+	assert.Equal(t, "my-rs-arb-0", ac.Processes[2].Name)
+	assert.Equal(t, "my-rs-arb-0.my-ns.svc.cluster.local", ac.Processes[2].HostName)
+	assert.Equal(t, "my-rs", ac.Processes[2].Args26.Get("replication.replSetName").Data())
+
+	members := ac.ReplicaSets[0].Members
+	assert.Len(t, members, 3)
+	for i, member := range members[:2] {
+		assert.False(t, member.ArbiterOnly)
+		assert.Equal(t, 1, member.Priority)
+		assert.Equal(t, fmt.Sprintf("test-horizon-%d", i), member.Horizons["horizon"])
+	}
+	arbiter := members[2]
+	assert.True(t, arbiter.ArbiterOnly)
+	assert.Equal(t, 2, arbiter.Id)
+	assert.Equal(t, "my-rs-arb-0", arbiter.Host)
+	assert.Equal(t, 0, arbiter.Priority)
+	assert.Equal(t, 1, arbiter.Votes)
+	assert.Nil(t, arbiter.Horizons)
+}
+
+func TestBuildAutomationConfig_WithAnalyticsMembers(t *testing.T) {
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMongoDBVersion("4.2.0").
+		SetMembers(3).
+		SetArbiters(1).
+		SetAnalyticsMembers(2).
+		Build()
+
+	assert.NoError(t, err)
+	assert.Len(t, ac.Processes, 6)
+	assert.Equal(t, "my-rs-analytics-1", ac.Processes[5].Name)
+	assert.Equal(t, "my-rs-analytics-1.my-ns.svc.cluster.local", ac.Processes[5].HostName)
+	assert.Equal(t, "my-rs", ac.Processes[5].Args26.Get("replication.replSetName").Data())
+
+	members := ac.ReplicaSets[0].Members
+	assert.Len(t, members, 6)
+	assert.True(t, members[3].ArbiterOnly)
+	for i, member := range members[4:] {
+		assert.Equal(t, 4+i, member.Id)
+		assert.Equal(t, fmt.Sprintf("my-rs-analytics-%d", i), member.Host)
+		assert.False(t, member.ArbiterOnly)
+		assert.True(t, member.Hidden)
+		assert.Equal(t, 0, member.Priority)
+		assert.Equal(t, 0, member.Votes)
+		assert.Equal(t, map[string]string{"nodeType": "ANALYTICS"}, member.Tags)
+	}
+}
+
+func TestBuildAutomationConfig_WithMemberOptions(t *testing.T) {
+	zero, two := 0, 2
+	buildIndexes := false
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMongoDBVersion("4.2.0").
+		SetMembers(4).
+		SetMemberOptions([]MemberOptions{
+			{Priority: &two, Tags: map[string]string{"dc": "east"}},
+			{Hidden: true, SecondaryDelaySecs: 3600},
+			{Votes: &zero, BuildIndexes: &buildIndexes},
+		}).
+		Build()
+	assert.NoError(t, err)
+
+	members := ac.ReplicaSets[0].Members
+	assert.Equal(t, 2, members[0].Priority)
+	assert.Equal(t, 1, members[0].Votes)
+	assert.Equal(t, map[string]string{"dc": "east"}, members[0].Tags)
+
+	assert.True(t, members[1].Hidden)
+	assert.Equal(t, 3600, members[1].SecondaryDelaySecs)
+	assert.Equal(t, 0, members[1].Priority, "hidden members can't become primary")
+	assert.Equal(t, 1, members[1].Votes)
+
+	assert.Equal(t, 0, members[2].Votes)
+	assert.Equal(t, 0, members[2].Priority, "non-voting members can't become primary")
+	assert.Equal(t, &buildIndexes, members[2].BuildIndexes)
+
+	assert.Equal(t, 1, members[3].Priority, "members without options keep the default configuration")
+	assert.Equal(t, 1, members[3].Votes)
+	assert.False(t, members[3].Hidden)
+	assert.Nil(t, members[3].Tags)
+}
+
+func TestBuildAutomationConfig_ShardedCluster(t *testing.T) {
+	ac, err := NewBuilder().
+		SetTopology(ShardedClusterTopology).
+		SetName("my-sc").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMongoDBVersion("4.4.0").
+		SetShardedCluster(ShardedClusterSpec{
+			ConfigServer: ReplicaSetSpec{Name: "my-sc-configsrv", Members: 3},
+			Shards: []ReplicaSetSpec{
+				{Name: "my-sc-0", Members: 3},
+				{Name: "my-sc-1", Members: 3},
+			},
+			Mongos: MongosSpec{Name: "my-sc-mongos", Replicas: 2},
+		}).
+		Build()
+	assert.NoError(t, err)
+
+	assert.Len(t, ac.Processes, 11)
+	assert.Len(t, ac.ReplicaSets, 3)
+
+	configServer := ac.ReplicaSets[0]
+	assert.Equal(t, "my-sc-configsrv", configServer.Id)
+	assert.True(t, configServer.ConfigServer)
+	assert.Len(t, configServer.Members, 3)
+	assert.Equal(t, "my-sc-configsrv-0", configServer.Members[0].Host)
+
+	for i, rs := range ac.ReplicaSets[1:] {
+		assert.Equal(t, fmt.Sprintf("my-sc-%d", i), rs.Id)
+		assert.False(t, rs.ConfigServer)
+		assert.Len(t, rs.Members, 3)
+	}
+
+	for _, p := range ac.Processes[:3] {
+		assert.Equal(t, Mongod, p.ProcessType)
+		assert.Equal(t, "configsvr", p.Args26.Get("sharding.clusterRole").Data())
+		assert.Equal(t, "my-sc-configsrv", p.Args26.Get("replication.replSetName").Data())
+	}
+	for _, p := range ac.Processes[3:9] {
+		assert.Equal(t, "shardsvr", p.Args26.Get("sharding.clusterRole").Data())
+	}
+	for i, p := range ac.Processes[9:] {
+		assert.Equal(t, Mongos, p.ProcessType)
+		assert.Equal(t, fmt.Sprintf("my-sc-mongos-%d.my-ns.svc.cluster.local", i), p.HostName)
+		assert.Equal(t, "my-sc", p.Cluster)
+		assert.Nil(t, p.Args26.Get("storage.dbPath").Data())
+		assert.Nil(t, p.Args26.Get("replication.replSetName").Data())
+	}
+
+	assert.Equal(t, []ShardedCluster{
+		{
+			Name:                "my-sc",
+			ConfigServerReplica: "my-sc-configsrv",
+			Shards:              []Shard{{Id: "my-sc-0", Rs: "my-sc-0"}, {Id: "my-sc-1", Rs: "my-sc-1"}},
+		},
+	}, ac.Sharding)
+}
+
+func TestBuildAutomationConfig_Standalone(t *testing.T) {
+	ac, err := NewBuilder().
+		SetTopology(StandaloneTopology).
+		SetName("my-mdb").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMongoDBVersion("4.4.0").
+		SetMembers(1).
+		Build()
+	assert.NoError(t, err)
+
+	assert.Len(t, ac.Processes, 1)
+	assert.Empty(t, ac.ReplicaSets)
+
+	p := ac.Processes[0]
+	assert.Equal(t, Mongod, p.ProcessType)
+	assert.Equal(t, "my-mdb-0", p.Name)
+	assert.Equal(t, "my-mdb-0.my-ns.svc.cluster.local", p.HostName)
+	assert.Equal(t, DefaultMongoDBDataDir, p.Args26.Get("storage.dbPath").Data())
+	assert.Nil(t, p.Args26.Get("replication").Data())
+}
+
+func TestBuildAutomationConfig_VotingMembersAreLimited(t *testing.T) {
+	countVotes := func(members []ReplicaSetMember) int {
+		totalVotes := 0
+		for _, member := range members {
+			totalVotes += member.Votes
+		}
+		return totalVotes
+	}
+
+	t.Run("The seventh member votes, the eighth doesn't", func(t *testing.T) {
+		ac, err := NewBuilder().
+			SetName("my-rs").
+			SetDomain("my-ns.svc.cluster.local").
+			SetMongoDBVersion("4.2.0").
+			SetMembers(7).
+			Build()
+		assert.NoError(t, err)
+		assert.Equal(t, maxVotingMembers, countVotes(ac.ReplicaSets[0].Members))
+		assert.Equal(t, 1, ac.ReplicaSets[0].Members[6].Votes)
+
+		ac, err = NewBuilder().
+			SetName("my-rs").
+			SetDomain("my-ns.svc.cluster.local").
+			SetMongoDBVersion("4.2.0").
+			SetMembers(8).
+			Build()
+		assert.NoError(t, err)
+		assert.Equal(t, maxVotingMembers, countVotes(ac.ReplicaSets[0].Members))
+		assert.Equal(t, 0, ac.ReplicaSets[0].Members[7].Votes)
+	})
+
+	t.Run("Members after the seventh don't vote", func(t *testing.T) {
+		ac, err := NewBuilder().
+			SetName("my-rs").
+			SetDomain("my-ns.svc.cluster.local").
+			SetMongoDBVersion("4.2.0").
+			SetMembers(9).
+			Build()
+		assert.NoError(t, err)
+
+		members := ac.ReplicaSets[0].Members
+		assert.Equal(t, maxVotingMembers, countVotes(members))
+		assert.Equal(t, 1, members[6].Votes)
+		assert.Equal(t, 0, members[7].Votes)
+		assert.Equal(t, 0, members[7].Priority)
+		assert.Equal(t, 0, members[8].Votes)
+	})
+
+	t.Run("Members which vote explicitly keep their vote", func(t *testing.T) {
+		one := 1
+		ac, err := NewBuilder().
+			SetName("my-rs").
+			SetDomain("my-ns.svc.cluster.local").
+			SetMongoDBVersion("4.2.0").
+			SetMembers(9).
+			SetMemberOptions([]MemberOptions{8: {Votes: &one}}).
+			Build()
+		assert.NoError(t, err)
+
+		members := ac.ReplicaSets[0].Members
+		assert.Equal(t, maxVotingMembers, countVotes(members))
+		assert.Equal(t, 1, members[8].Votes)
+		assert.Equal(t, 1, members[8].Priority)
+		assert.Equal(t, 0, members[6].Votes)
+	})
+}
+
 func TestReplicaSetHorizons(t *testing.T) {
 	ac, err := NewBuilder().
 		SetName("my-rs").
@@ -84,6 +331,22 @@ func TestReplicaSetHorizons(t *testing.T) {
 	}
 }
 
+func TestReplicaSetHorizons_FewerThanMembers(t *testing.T) {
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMongoDBVersion("4.2.0").
+		SetMembers(3).
+		SetReplicaSetHorizons([]ReplicaSetHorizons{
+			{"horizon": "test-horizon-0"},
+		}).
+		Build()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "test-horizon-0", ac.ReplicaSets[0].Members[0].Horizons["horizon"])
+	assert.Empty(t, ac.ReplicaSets[0].Members[2].Horizons, "the build doesn't panic on a short list of horizons")
+}
+
 func TestMongoDbVersions(t *testing.T) {
 	ac, err := NewBuilder().
 		SetName("my-rs").
@@ -172,6 +435,22 @@ func TestProcessHasPortSetToDefault(t *testing.T) {
 	}
 }
 
+func TestProcessHasPortSet(t *testing.T) {
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMongoDBVersion("4.2.0").
+		SetMembers(3).
+		SetPort(30000).
+		AddVersion(defaultMongoDbVersion("4.3.2")).
+		Build()
+
+	assert.NoError(t, err)
+	for _, process := range ac.Processes {
+		assert.Equal(t, 30000, process.Args26.Get("net.port").Data())
+	}
+}
+
 func TestModifications(t *testing.T) {
 	incrementVersion := func(config *AutomationConfig) {
 		config.Version += 1
@@ -268,6 +547,55 @@ func TestAreEqual(t *testing.T) {
 		assert.NoError(t, err)
 		assert.False(t, areEqual)
 	})
+
+	t.Run("Automation Configs with different replica set settings are not equal", func(t *testing.T) {
+		chainingAllowed := false
+		ac0 := createAutomationConfig("name0", "mdbVersion0", "domain0", Options{DownloadBase: "downloadBase0"}, Auth{Disabled: true}, 5, 2)
+		ac1 := createAutomationConfig("name0", "mdbVersion0", "domain0", Options{DownloadBase: "downloadBase0"}, Auth{Disabled: true}, 5, 2)
+		ac1.ReplicaSets[0].Settings = &ReplicaSetSettings{ChainingAllowed: &chainingAllowed}
+
+		areEqual, err := AreEqual(ac0, ac1)
+		assert.NoError(t, err)
+		assert.False(t, areEqual)
+
+		ac0.ReplicaSets[0].DefaultRWConcern = &DefaultRWConcern{DefaultReadConcern: &ReadConcern{Level: "majority"}}
+		ac1.ReplicaSets[0].Settings = nil
+		areEqual, err = AreEqual(ac0, ac1)
+		assert.NoError(t, err)
+		assert.False(t, areEqual)
+	})
+}
+
+func TestUserKeyAndRoleKey(t *testing.T) {
+	assert.Equal(t, "admin.my-user", UserKey("", "my-user"))
+	assert.Equal(t, "app.my-user", UserKey("app", "my-user"))
+	assert.Equal(t, "app.my-role", RoleKey("app", "my-role"))
+}
+
+func TestBuildAutomationConfig_WithReplicaSetSettings(t *testing.T) {
+	chainingAllowed := false
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetMongoDBVersion("4.4.0").
+		SetMembers(3).
+		SetReplicaSetSettings(&ReplicaSetSettings{ChainingAllowed: &chainingAllowed, ElectionTimeoutMillis: 5000}).
+		SetDefaultRWConcern(&DefaultRWConcern{DefaultWriteConcern: &WriteConcern{W: "majority", WTimeout: 1000}}).
+		Build()
+	assert.NoError(t, err)
+
+	rs := ac.ReplicaSets[0]
+	assert.Equal(t, &chainingAllowed, rs.Settings.ChainingAllowed)
+	assert.Equal(t, 5000, rs.Settings.ElectionTimeoutMillis)
+	assert.Equal(t, "majority", rs.DefaultRWConcern.DefaultWriteConcern.W)
+
+	acBytes, err := json.Marshal(ac)
+	assert.NoError(t, err)
+	readAc, err := FromBytes(acBytes)
+	assert.NoError(t, err)
+	areEqual, err := AreEqual(ac, readAc)
+	assert.NoError(t, err)
+	assert.True(t, areEqual, "the settings are unchanged by the serialization to the secret")
 }
 
 func createAutomationConfig(name, mongodbVersion, domain string, opts Options, auth Auth, members, acVersion int) AutomationConfig {
@@ -24,6 +24,10 @@ type patchValue struct {
 
 const (
 	LastAppliedMongoDBVersion = "mongodb.com/v1.lastAppliedMongoDBVersion"
+
+	// ManagedUsers records the users the operator has created in the deployment, so that the ones removed
+	// from the resource can be dropped while users created by hand are left alone.
+	ManagedUsers = "mongodb.com/v1.managedUsers"
 )
 
 func GetAnnotation(object Versioned, key string) string {
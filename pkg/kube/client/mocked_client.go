@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -82,7 +83,33 @@ func makeStatefulSetReady(set *appsv1.StatefulSet) {
 	set.Status.ReadyReplicas = *set.Spec.Replicas
 }
 
-func (m *mockedClient) List(_ context.Context, _ k8sClient.ObjectList, _ ...k8sClient.ListOption) error {
+// List fills the Items of the list with the stored objects of the same type, in the namespace
+// given by the InNamespace option, or in all namespaces. Other options are ignored.
+func (m *mockedClient) List(_ context.Context, list k8sClient.ObjectList, opts ...k8sClient.ListOption) error {
+	listOpts := k8sClient.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	itemsValue := reflect.ValueOf(list).Elem().FieldByName("Items")
+	if !itemsValue.IsValid() {
+		return fmt.Errorf("list of type %T has no Items field", list)
+	}
+
+	relevantMap := m.backingMap[reflect.PtrTo(itemsValue.Type().Elem())]
+	keys := make([]k8sClient.ObjectKey, 0, len(relevantMap))
+	for key := range relevantMap {
+		if listOpts.Namespace == "" || key.Namespace == listOpts.Namespace {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	items := reflect.MakeSlice(itemsValue.Type(), 0, len(keys))
+	for _, key := range keys {
+		items = reflect.Append(items, reflect.ValueOf(relevantMap[key]).Elem())
+	}
+	itemsValue.Set(items)
 	return nil
 }
 
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func TestMockedClient(t *testing.T) {
@@ -44,3 +45,30 @@ func TestMockedClient(t *testing.T) {
 	assert.Equal(t, "svc-namespace", newSvc.Namespace)
 	assert.Equal(t, "svc-name", newSvc.Name)
 }
+
+func TestMockedClient_List(t *testing.T) {
+	mockedClient := NewMockedClient()
+
+	for _, namespace := range []string{"namespace-1", "namespace-2"} {
+		cm := configmap.Builder().
+			SetName("cm-name").
+			SetNamespace(namespace).
+			Build()
+		assert.NoError(t, mockedClient.Create(context.TODO(), &cm))
+	}
+
+	cmList := corev1.ConfigMapList{}
+	assert.NoError(t, mockedClient.List(context.TODO(), &cmList))
+	assert.Len(t, cmList.Items, 2)
+	assert.Equal(t, "namespace-1", cmList.Items[0].Namespace)
+	assert.Equal(t, "namespace-2", cmList.Items[1].Namespace)
+
+	cmList = corev1.ConfigMapList{}
+	assert.NoError(t, mockedClient.List(context.TODO(), &cmList, k8sClient.InNamespace("namespace-2")))
+	assert.Len(t, cmList.Items, 1)
+	assert.Equal(t, "namespace-2", cmList.Items[0].Namespace)
+
+	svcList := corev1.ServiceList{}
+	assert.NoError(t, mockedClient.List(context.TODO(), &svcList))
+	assert.Empty(t, svcList.Items)
+}
@@ -216,6 +216,13 @@ func WithTolerations(tolerations []corev1.Toleration) Modification {
 	}
 }
 
+// WithNodeSelector sets the PodTemplateSpec's node selector
+func WithNodeSelector(nodeSelector map[string]string) Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		podTemplateSpec.Spec.NodeSelector = nodeSelector
+	}
+}
+
 // WithAnnotations sets the PodTemplateSpec's annotations
 func WithAnnotations(annotations map[string]string) Modification {
 	if annotations == nil {
@@ -0,0 +1,75 @@
+package secret
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// BackendEnv selects where the operator stores user passwords, the agent password and keyfile,
+	// and the generated SCRAM credentials. Either "kubernetes" (the default) or "vault".
+	BackendEnv = "SECRET_BACKEND"
+
+	BackendKubernetes = "kubernetes"
+	BackendVault      = "vault"
+
+	VaultAddressEnv    = "VAULT_ADDR"
+	VaultTokenEnv      = "VAULT_TOKEN"
+	VaultTokenFileEnv  = "VAULT_TOKEN_FILE"
+	VaultMountPathEnv  = "VAULT_KV_MOUNT_PATH"
+	VaultPathPrefixEnv = "VAULT_PATH_PREFIX"
+
+	// VaultResyncIntervalEnv sets how often the MongoDB resources are reconciled to pick up the changes
+	// made in Vault, which doesn't notify the operator. Defaults to DefaultVaultResyncInterval.
+	VaultResyncIntervalEnv = "VAULT_RESYNC_INTERVAL"
+
+	DefaultVaultResyncInterval = 5 * time.Minute
+)
+
+// BackendFromEnv returns the secret backend configured with the environment variables.
+// The given Kubernetes backend is returned when no other backend is configured.
+func BackendFromEnv(kubernetes GetUpdateCreateDeleter) (GetUpdateCreateDeleter, error) {
+	switch backend := os.Getenv(BackendEnv); backend {
+	case "", BackendKubernetes:
+		return kubernetes, nil
+	case BackendVault:
+		opts := VaultOptions{
+			Address:    os.Getenv(VaultAddressEnv),
+			Token:      os.Getenv(VaultTokenEnv),
+			TokenFile:  os.Getenv(VaultTokenFileEnv),
+			MountPath:  os.Getenv(VaultMountPathEnv),
+			PathPrefix: os.Getenv(VaultPathPrefixEnv),
+		}
+		if opts.Address == "" {
+			return nil, errors.Errorf("%s is required when %s is %s", VaultAddressEnv, BackendEnv, BackendVault)
+		}
+		if opts.Token == "" && opts.TokenFile == "" {
+			return nil, errors.Errorf("either %s or %s is required when %s is %s", VaultTokenEnv, VaultTokenFileEnv, BackendEnv, BackendVault)
+		}
+		return NewVaultBackend(opts), nil
+	default:
+		return nil, errors.Errorf("unknown secret backend %s", backend)
+	}
+}
+
+// ResyncIntervalFromEnv returns how often the MongoDB resources must be reconciled to pick up the changes
+// made in the configured backend. It returns 0 for the Kubernetes backend, as its Secrets are watched.
+func ResyncIntervalFromEnv() (time.Duration, error) {
+	if backend := os.Getenv(BackendEnv); backend != BackendVault {
+		return 0, nil
+	}
+	value := os.Getenv(VaultResyncIntervalEnv)
+	if value == "" {
+		return DefaultVaultResyncInterval, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Errorf("invalid %s %s: %s", VaultResyncIntervalEnv, value, err)
+	}
+	if interval <= 0 {
+		return 0, errors.Errorf("%s must be positive, got %s", VaultResyncIntervalEnv, value)
+	}
+	return interval, nil
+}
@@ -0,0 +1,233 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultVaultMountPath  = "secret"
+	defaultVaultPathPrefix = "mongodbcommunity"
+	vaultTokenHeader       = "X-Vault-Token"
+	vaultRequestTimeout    = 10 * time.Second
+)
+
+// VaultOptions configures the HashiCorp Vault KV version 2 secrets engine used to store secrets.
+type VaultOptions struct {
+	// Address is the address of the Vault server, e.g. https://vault.vault.svc:8200.
+	Address string
+
+	// Token is the token used to authenticate to Vault.
+	Token string
+
+	// TokenFile is a file containing the token used to authenticate to Vault, e.g. written by the Vault Agent.
+	// The file is read for every request, so that renewed tokens are used. Takes precedence over Token.
+	TokenFile string
+
+	// MountPath is the path the KV version 2 secrets engine is mounted at. Defaults to "secret".
+	MountPath string
+
+	// PathPrefix is the path, inside of the secrets engine, the secrets are stored under.
+	// A secret is stored at <PathPrefix>/<namespace>/<name>. Defaults to "mongodbcommunity".
+	PathPrefix string
+
+	// HTTPClient is the client used to send the requests to Vault. Defaults to a client with a 10s timeout.
+	HTTPClient *http.Client
+}
+
+// vaultBackend stores secrets in a HashiCorp Vault KV version 2 secrets engine. The keys of a Kubernetes
+// Secret are the keys of the Vault secret. Only the data of the secrets is stored, the metadata such
+// as labels and owner references is dropped.
+type vaultBackend struct {
+	opts VaultOptions
+}
+
+// NewVaultBackend returns a GetUpdateCreateDeleter which stores the secrets in Vault instead of Kubernetes.
+func NewVaultBackend(opts VaultOptions) GetUpdateCreateDeleter {
+	if opts.MountPath == "" {
+		opts.MountPath = defaultVaultMountPath
+	}
+	if opts.PathPrefix == "" {
+		opts.PathPrefix = defaultVaultPathPrefix
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: vaultRequestTimeout}
+	}
+	return vaultBackend{opts: opts}
+}
+
+type vaultSecretResponse struct {
+	Data struct {
+		Data map[string]string `json:"data"`
+	} `json:"data"`
+}
+
+type vaultSecretRequest struct {
+	Options map[string]int    `json:"options,omitempty"`
+	Data    map[string]string `json:"data"`
+}
+
+type vaultErrorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+// GetSecret reads the latest version of the secret. A NotFound error is returned if the secret doesn't exist.
+func (v vaultBackend) GetSecret(objectKey client.ObjectKey) (corev1.Secret, error) {
+	resp, err := v.do(http.MethodGet, v.url("data", objectKey), nil)
+	if err != nil {
+		return corev1.Secret{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return corev1.Secret{}, apiErrors.NewNotFound(corev1.Resource("secrets"), objectKey.Name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return corev1.Secret{}, vaultError(resp, objectKey)
+	}
+
+	vaultSecret := vaultSecretResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&vaultSecret); err != nil {
+		return corev1.Secret{}, errors.Errorf("could not decode Vault secret %s: %s", objectKey, err)
+	}
+
+	data := map[string][]byte{}
+	for key, value := range vaultSecret.Data.Data {
+		data[key] = []byte(value)
+	}
+	return corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: objectKey.Name, Namespace: objectKey.Namespace},
+		Data:       data,
+	}, nil
+}
+
+// CreateSecret writes the secret only if it doesn't exist yet. An AlreadyExists error is returned otherwise.
+func (v vaultBackend) CreateSecret(secret corev1.Secret) error {
+	// a check-and-set version of 0 only allows the write if the secret doesn't exist.
+	err := v.write(secret, map[string]int{"cas": 0})
+	if isVaultCheckAndSetError(err) {
+		return apiErrors.NewAlreadyExists(corev1.Resource("secrets"), secret.Name)
+	}
+	return err
+}
+
+// UpdateSecret writes a new version of the secret, replacing all of its keys.
+func (v vaultBackend) UpdateSecret(secret corev1.Secret) error {
+	return v.write(secret, nil)
+}
+
+// DeleteSecret deletes all the versions of the secret. A NotFound error is returned if the secret doesn't exist.
+func (v vaultBackend) DeleteSecret(objectKey client.ObjectKey) error {
+	if _, err := v.GetSecret(objectKey); err != nil {
+		return err
+	}
+
+	resp, err := v.do(http.MethodDelete, v.url("metadata", objectKey), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return vaultError(resp, objectKey)
+	}
+	return nil
+}
+
+func (v vaultBackend) write(secret corev1.Secret, options map[string]int) error {
+	objectKey := types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}
+	request := vaultSecretRequest{Options: options, Data: map[string]string{}}
+	for key, value := range secret.Data {
+		request.Data[key] = string(value)
+	}
+	for key, value := range secret.StringData {
+		request.Data[key] = value
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	resp, err := v.do(http.MethodPost, v.url("data", objectKey), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return vaultError(resp, objectKey)
+	}
+	return nil
+}
+
+// url returns the URL of the secret for the given KV version 2 endpoint, either "data" or "metadata".
+func (v vaultBackend) url(endpoint string, objectKey client.ObjectKey) string {
+	secretPath := path.Join(v.opts.MountPath, endpoint, v.opts.PathPrefix, objectKey.Namespace, objectKey.Name)
+	return fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(v.opts.Address, "/"), secretPath)
+}
+
+func (v vaultBackend) do(method, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	token := v.opts.Token
+	if v.opts.TokenFile != "" {
+		contents, err := ioutil.ReadFile(v.opts.TokenFile)
+		if err != nil {
+			return nil, errors.Errorf("could not read Vault token: %s", err)
+		}
+		token = strings.TrimSpace(string(contents))
+	}
+	req.Header.Set(vaultTokenHeader, token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, errors.Errorf("could not send request to Vault: %s", err)
+	}
+	return resp, nil
+}
+
+// vaultCheckAndSetError is returned when a check-and-set write is rejected by Vault.
+type vaultCheckAndSetError struct {
+	message string
+}
+
+func (e vaultCheckAndSetError) Error() string {
+	return e.message
+}
+
+func isVaultCheckAndSetError(err error) bool {
+	_, ok := err.(vaultCheckAndSetError)
+	return ok
+}
+
+func vaultError(resp *http.Response, objectKey client.ObjectKey) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	vaultErrors := vaultErrorResponse{}
+	_ = json.Unmarshal(body, &vaultErrors)
+
+	message := fmt.Sprintf("Vault returned status %d for secret %s: %s", resp.StatusCode, objectKey, strings.Join(vaultErrors.Errors, ", "))
+	for _, e := range vaultErrors.Errors {
+		if strings.Contains(e, "check-and-set") {
+			return vaultCheckAndSetError{message: message}
+		}
+	}
+	return errors.New(message)
+}
@@ -0,0 +1,192 @@
+package secret
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const testVaultToken = "test-token"
+
+// newFakeVaultServer returns a server implementing the subset of the KV version 2 API used by the Vault backend.
+func newFakeVaultServer() *httptest.Server {
+	mu := sync.Mutex{}
+	secrets := map[string]map[string]string{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Header.Get(vaultTokenHeader) != testVaultToken {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		secretPath := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v1/secret/data/"), "/v1/secret/metadata/")
+		switch r.Method {
+		case http.MethodGet:
+			data, ok := secrets[secretPath]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data}})
+		case http.MethodPost:
+			request := vaultSecretRequest{}
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			if cas, ok := request.Options["cas"]; ok && cas == 0 {
+				if _, exists := secrets[secretPath]; exists {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"errors":["check-and-set parameter did not match the current version"]}`))
+					return
+				}
+			}
+			secrets[secretPath] = request.Data
+			_, _ = w.Write([]byte(`{"data":{"version":1}}`))
+		case http.MethodDelete:
+			delete(secrets, secretPath)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+// testVaultBackend returns a backend using the Vault dev server at VAULT_DEV_SERVER_ADDR, started with
+// `vault server -dev -dev-root-token-id=test-token`, or a fake server if it is not set.
+func testVaultBackend(t *testing.T) GetUpdateCreateDeleter {
+	address := os.Getenv("VAULT_DEV_SERVER_ADDR")
+	if address == "" {
+		server := newFakeVaultServer()
+		t.Cleanup(server.Close)
+		address = server.URL
+	}
+	return NewVaultBackend(VaultOptions{Address: address, Token: testVaultToken, PathPrefix: "test/" + strings.ToLower(t.Name())})
+}
+
+func TestVaultBackend(t *testing.T) {
+	backend := testVaultBackend(t)
+	nsName := types.NamespacedName{Name: "my-user-password", Namespace: "my-ns"}
+
+	_, err := backend.GetSecret(nsName)
+	assert.True(t, apiErrors.IsNotFound(err))
+
+	s := Builder().SetName(nsName.Name).SetNamespace(nsName.Namespace).SetField("password", "my-password").Build()
+	assert.NoError(t, backend.CreateSecret(s))
+
+	password, err := ReadKey(backend, "password", nsName)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-password", password)
+
+	err = backend.CreateSecret(s)
+	assert.True(t, apiErrors.IsAlreadyExists(err))
+
+	assert.NoError(t, UpdateField(backend, nsName, "password", "new-password"))
+	password, err = ReadKey(backend, "password", nsName)
+	assert.NoError(t, err)
+	assert.Equal(t, "new-password", password)
+
+	assert.NoError(t, backend.DeleteSecret(nsName))
+	_, err = backend.GetSecret(nsName)
+	assert.True(t, apiErrors.IsNotFound(err))
+	assert.True(t, apiErrors.IsNotFound(backend.DeleteSecret(nsName)))
+}
+
+func TestVaultBackend_EnsureSecretWithKey(t *testing.T) {
+	backend := testVaultBackend(t)
+	nsName := types.NamespacedName{Name: "my-rs-agent-password", Namespace: "my-ns"}
+
+	value, err := EnsureSecretWithKey(backend, nsName, "password", "generated")
+	assert.NoError(t, err)
+	assert.Equal(t, "generated", value)
+
+	value, err = EnsureSecretWithKey(backend, nsName, "password", "generated-again")
+	assert.NoError(t, err)
+	assert.Equal(t, "generated", value)
+}
+
+func TestVaultBackend_InvalidToken(t *testing.T) {
+	server := newFakeVaultServer()
+	defer server.Close()
+	backend := NewVaultBackend(VaultOptions{Address: server.URL, Token: "invalid"})
+
+	_, err := backend.GetSecret(types.NamespacedName{Name: "name", Namespace: "namespace"})
+	assert.Error(t, err)
+	assert.False(t, apiErrors.IsNotFound(err))
+	assert.Contains(t, err.Error(), "permission denied")
+}
+
+func TestBackendFromEnv(t *testing.T) {
+	kubernetes := NewVaultBackend(VaultOptions{Address: "kubernetes"})
+
+	t.Run("Kubernetes is used by default", func(t *testing.T) {
+		backend, err := BackendFromEnv(kubernetes)
+		assert.NoError(t, err)
+		assert.Equal(t, kubernetes, backend)
+	})
+
+	t.Run("Vault requires an address and a token", func(t *testing.T) {
+		t.Setenv(BackendEnv, BackendVault)
+
+		_, err := BackendFromEnv(kubernetes)
+		assert.Error(t, err)
+
+		t.Setenv(VaultAddressEnv, "https://vault:8200")
+		_, err = BackendFromEnv(kubernetes)
+		assert.Error(t, err)
+
+		t.Setenv(VaultTokenEnv, testVaultToken)
+		backend, err := BackendFromEnv(kubernetes)
+		assert.NoError(t, err)
+		assert.NotEqual(t, kubernetes, backend)
+	})
+
+	t.Run("Unknown backends are rejected", func(t *testing.T) {
+		t.Setenv(BackendEnv, "aws")
+
+		_, err := BackendFromEnv(kubernetes)
+		assert.Error(t, err)
+	})
+}
+
+func TestResyncIntervalFromEnv(t *testing.T) {
+	t.Run("Kubernetes Secrets are not resynced", func(t *testing.T) {
+		interval, err := ResyncIntervalFromEnv()
+		assert.NoError(t, err)
+		assert.Zero(t, interval)
+	})
+
+	t.Run("Vault is resynced every 5 minutes by default", func(t *testing.T) {
+		t.Setenv(BackendEnv, BackendVault)
+
+		interval, err := ResyncIntervalFromEnv()
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultVaultResyncInterval, interval)
+	})
+
+	t.Run("The Vault resync interval is configurable", func(t *testing.T) {
+		t.Setenv(BackendEnv, BackendVault)
+		t.Setenv(VaultResyncIntervalEnv, "30s")
+
+		interval, err := ResyncIntervalFromEnv()
+		assert.NoError(t, err)
+		assert.Equal(t, 30*time.Second, interval)
+
+		t.Setenv(VaultResyncIntervalEnv, "soon")
+		_, err = ResyncIntervalFromEnv()
+		assert.Error(t, err)
+
+		t.Setenv(VaultResyncIntervalEnv, "0s")
+		_, err = ResyncIntervalFromEnv()
+		assert.Error(t, err)
+	})
+}
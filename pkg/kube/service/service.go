@@ -2,6 +2,8 @@ package service
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -32,6 +34,13 @@ type GetUpdateCreator interface {
 // a new service will be created and returned.
 // The "merging" process is arbitrary and it only handle specific attributes
 func Merge(dest corev1.Service, source corev1.Service) corev1.Service {
+	if dest.ObjectMeta.Annotations == nil {
+		dest.ObjectMeta.Annotations = map[string]string{}
+	}
+	if dest.ObjectMeta.Labels == nil {
+		dest.ObjectMeta.Labels = map[string]string{}
+	}
+
 	for k, v := range source.ObjectMeta.Annotations {
 		dest.ObjectMeta.Annotations[k] = v
 	}
@@ -60,3 +69,16 @@ func Merge(dest corev1.Service, source corev1.Service) corev1.Service {
 	dest.Spec.ExternalTrafficPolicy = source.Spec.ExternalTrafficPolicy
 	return dest
 }
+
+// CreateOrUpdate creates the Service if it doesn't exist, otherwise it merges it into the existing one,
+// which keeps the addresses and ports allocated by Kubernetes.
+func CreateOrUpdate(getUpdateCreator GetUpdateCreator, svc corev1.Service) error {
+	existing, err := getUpdateCreator.GetService(types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace})
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return getUpdateCreator.CreateService(svc)
+		}
+		return err
+	}
+	return getUpdateCreator.UpdateService(Merge(existing, svc))
+}
@@ -280,6 +280,17 @@ func WithVolumeClaim(name string, f func(*corev1.PersistentVolumeClaim)) Modific
 	}
 }
 
+// WithoutVolumeClaim removes the volume claim template with the given name.
+func WithoutVolumeClaim(name string) Modification {
+	return func(set *appsv1.StatefulSet) {
+		idx := findVolumeClaimIndexByName(name, set.Spec.VolumeClaimTemplates)
+		if idx == notFound {
+			return
+		}
+		set.Spec.VolumeClaimTemplates = append(set.Spec.VolumeClaimTemplates[:idx], set.Spec.VolumeClaimTemplates[idx+1:]...)
+	}
+}
+
 func WithCustomSpecs(spec appsv1.StatefulSetSpec) Modification {
 	return func(set *appsv1.StatefulSet) {
 		set.Spec = merge.StatefulSetSpecs(set.Spec, spec)
@@ -123,6 +123,19 @@ func TestAddVolumeClaimTemplates(t *testing.T) {
 	assert.Equal(t, sts.Spec.Template.Spec.Containers[0].VolumeMounts[0].Name, "mount-0")
 }
 
+func TestWithoutVolumeClaim(t *testing.T) {
+	sts := New(
+		WithVolumeClaim("claim-0", func(pvc *corev1.PersistentVolumeClaim) { pvc.Name = "claim-0" }),
+		WithVolumeClaim("claim-1", func(pvc *corev1.PersistentVolumeClaim) { pvc.Name = "claim-1" }),
+	)
+	WithoutVolumeClaim("claim-0")(&sts)
+	assert.Len(t, sts.Spec.VolumeClaimTemplates, 1)
+	assert.Equal(t, "claim-1", sts.Spec.VolumeClaimTemplates[0].Name)
+
+	WithoutVolumeClaim("claim-2")(&sts)
+	assert.Len(t, sts.Spec.VolumeClaimTemplates, 1)
+}
+
 func TestBuildStructImmutable(t *testing.T) {
 	labels := map[string]string{"label_1": "a", "label_2": "b"}
 
@@ -16,6 +16,7 @@ const (
 	agentHealthStatusFilePathEnv     = "AGENT_STATUS_FILEPATH"
 	logPathEnv                       = "LOG_FILE_PATH"
 	hostNameEnv                      = "HOSTNAME"
+	analyticsMemberEnv               = "ANALYTICS_MEMBER"
 )
 
 type Config struct {
@@ -25,6 +26,8 @@ type Config struct {
 	AutomationConfigSecretName string
 	HealthStatusFilePath       string
 	LogFilePath                string
+	// AnalyticsMember is true in the pods of the analytics members.
+	AnalyticsMember bool
 }
 
 func BuildFromEnvVariables(clientSet kubernetes.Interface, isHeadless bool) (Config, error) {
@@ -54,6 +57,7 @@ func BuildFromEnvVariables(clientSet kubernetes.Interface, isHeadless bool) (Con
 		Hostname:                   hostname,
 		HealthStatusFilePath:       healthStatusFilePath,
 		LogFilePath:                logFilePath,
+		AnalyticsMember:            os.Getenv(analyticsMemberEnv) == "true",
 	}, nil
 }
 
@@ -26,9 +26,24 @@ func PerformCheckHeadlessMode(health health.Status, conf config.Config) (bool, e
 		return false, err
 	}
 
+	// the Services of the primary and of the secondaries select the members with their role label
+	if err = pod.PatchPodRoleLabel(conf.Namespace, podRole(health, conf), conf.Hostname, conf.ClientSet); err != nil {
+		return false, err
+	}
+
 	return targetVersion == currentAgentVersion, nil
 }
 
+// podRole returns the role label of the pod. The analytics members are not labelled as secondaries, so that
+// they are left out of the Service of the secondaries.
+func podRole(status health.Status, conf config.Config) string {
+	role := status.ReplicaSetRole()
+	if conf.AnalyticsMember && role == health.RoleSecondary {
+		return health.RoleAnalytics
+	}
+	return role
+}
+
 // readCurrentAgentInfo returns the version the Agent has reached and the rs member name
 func readCurrentAgentInfo(health health.Status, targetVersion int64) int64 {
 	for _, v := range health.ProcessPlans {
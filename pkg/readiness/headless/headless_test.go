@@ -2,6 +2,7 @@ package headless
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
 	"github.com/mongodb/mongodb-kubernetes-operator/cmd/readiness/testdata"
@@ -40,3 +41,14 @@ func testConfig() config.Config {
 		Hostname:                   "test-mongodb-0",
 	}
 }
+
+func TestPodRole(t *testing.T) {
+	status := health.Status{}
+	require.NoError(t, json.Unmarshal([]byte(`{"statuses": {"test-mongodb-0": {"ReplicationStatus": 2}}}`), &status))
+
+	c := testConfig()
+	assert.Equal(t, health.RoleSecondary, podRole(status, c))
+
+	c.AnalyticsMember = true
+	assert.Equal(t, health.RoleAnalytics, podRole(status, c))
+}
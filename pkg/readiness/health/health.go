@@ -33,6 +33,40 @@ type processHealth struct {
 	ReplicaStatus   *replicationStatus `json:"ReplicationStatus"`
 }
 
+// Roles of the process in the replica set, as published in the role label of the pod.
+const (
+	RolePrimary   = "primary"
+	RoleSecondary = "secondary"
+	RoleNone      = "none"
+	// RoleAnalytics replaces the secondary role on the analytics members, which only serve the clients
+	// targeting them with read preference tags.
+	RoleAnalytics = "analytics"
+)
+
+// ReplicaSetRole returns the role of the process in the replica set: primary, secondary,
+// or none in any other state, and for the processes which are not part of a replica set.
+func (h processHealth) ReplicaSetRole() string {
+	if h.ReplicaStatus == nil {
+		return RoleNone
+	}
+	switch *h.ReplicaStatus {
+	case replicationStatusPrimary:
+		return RolePrimary
+	case replicationStatusSecondary:
+		return RoleSecondary
+	default:
+		return RoleNone
+	}
+}
+
+// ReplicaSetRole returns the role of the process managed by the agent, as there's only one per pod.
+func (s Status) ReplicaSetRole() string {
+	for _, processHealth := range s.Healthiness {
+		return processHealth.ReplicaSetRole()
+	}
+	return RoleNone
+}
+
 func (h processHealth) String() string {
 	return fmt.Sprintf("ExpectedToBeUp: %t, IsInGoalState: %t, LastMongoUpTime: %v", h.ExpectedToBeUp,
 		h.IsInGoalState, time.Unix(h.LastMongoUpTime, 0))
@@ -30,3 +30,17 @@ func TestIsNotReady(t *testing.T) {
 		assert.False(t, h.IsReadyState())
 	}
 }
+
+// TestReplicaSetRole checks that only the primary and the secondaries get a role.
+func TestReplicaSetRole(t *testing.T) {
+	primary, secondary, recovering, undefined := replicationStatusPrimary, replicationStatusSecondary, replicationStatusRecovering, replicationStatusUndefined
+
+	assert.Equal(t, RolePrimary, processHealth{ReplicaStatus: &primary}.ReplicaSetRole())
+	assert.Equal(t, RoleSecondary, processHealth{ReplicaStatus: &secondary}.ReplicaSetRole())
+	assert.Equal(t, RoleNone, processHealth{ReplicaStatus: &recovering}.ReplicaSetRole())
+	assert.Equal(t, RoleNone, processHealth{ReplicaStatus: &undefined}.ReplicaSetRole())
+	assert.Equal(t, RoleNone, processHealth{}.ReplicaSetRole())
+
+	assert.Equal(t, RoleSecondary, Status{Healthiness: map[string]processHealth{"foo": {ReplicaStatus: &secondary}}}.ReplicaSetRole())
+	assert.Equal(t, RoleNone, Status{}.ReplicaSetRole())
+}
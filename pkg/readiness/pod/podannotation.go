@@ -30,3 +30,27 @@ func patchPod(patcher Patcher, podNamespace string, mdbAgentVersion mongodbAgent
 	}
 	return err
 }
+
+// RoleLabel is the label selected by the Services of the primary and of the secondaries. Its values are the
+// roles of the health package.
+const RoleLabel = "mongodb.com/role"
+
+// PatchPodRoleLabel sets the role of the member in the replica set on its pod.
+func PatchPodRoleLabel(podNamespace string, role string, memberName string, clientSet kubernetes.Interface) error {
+	return patchPodLabel(NewKubernetesPodPatcher(clientSet), podNamespace, RoleLabel, role, memberName)
+}
+
+func patchPodLabel(patcher Patcher, podNamespace string, key, value string, memberName string) error {
+	// a merge patch also works for the pods which have no labels yet
+	payload := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]string{key: value},
+		},
+	}
+
+	pod, err := patcher.mergePatchPod(podNamespace, memberName, payload)
+	if pod != nil {
+		zap.S().Debugf("Updated Pod labels: %v (%s)", pod.Labels, memberName)
+	}
+	return err
+}
@@ -37,3 +37,22 @@ func TestPatchPodAnnotation(t *testing.T) {
 func TestUpdatePodAnnotationPodNotFound(t *testing.T) {
 	assert.True(t, apiErrors.IsNotFound(PatchPodAnnotation("wrong-ns", 1, "my-replica-set-0", fake.NewSimpleClientset())))
 }
+
+// TestPatchPodRoleLabel verifies that the role label is added to the pod, and kept current
+func TestPatchPodRoleLabel(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-replica-set-0",
+			Namespace: "test-ns",
+			Labels:    map[string]string{"app": "my-replica-set-svc"},
+		},
+	})
+
+	assert.NoError(t, PatchPodRoleLabel("test-ns", "primary", "my-replica-set-0", clientset))
+	pod, _ := clientset.CoreV1().Pods("test-ns").Get(context.TODO(), "my-replica-set-0", metav1.GetOptions{})
+	assert.Equal(t, map[string]string{"app": "my-replica-set-svc", "mongodb.com/role": "primary"}, pod.Labels)
+
+	assert.NoError(t, PatchPodRoleLabel("test-ns", "secondary", "my-replica-set-0", clientset))
+	pod, _ = clientset.CoreV1().Pods("test-ns").Get(context.TODO(), "my-replica-set-0", metav1.GetOptions{})
+	assert.Equal(t, map[string]string{"app": "my-replica-set-svc", "mongodb.com/role": "secondary"}, pod.Labels)
+}
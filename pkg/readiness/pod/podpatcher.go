@@ -31,3 +31,11 @@ func (p Patcher) patchPod(namespace, podName string, payload []patchValue) (*v1.
 	}
 	return p.clientset.CoreV1().Pods(namespace).Patch(context.TODO(), podName, types.JSONPatchType, data, metav1.PatchOptions{})
 }
+
+func (p Patcher) mergePatchPod(namespace, podName string, payload interface{}) (*v1.Pod, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return p.clientset.CoreV1().Pods(namespace).Patch(context.TODO(), podName, types.MergePatchType, data, metav1.PatchOptions{})
+}
@@ -4,3 +4,5 @@ kind create cluster --kubeconfig "${KUBECONFIG}"
 
 echo "Creating CRDs"
 kubectl apply -f config/crd/bases/mongodbcommunity.mongodb.com_mongodbcommunity.yaml
+kubectl apply -f config/crd/bases/mongodbcommunity.mongodb.com_mongodbcommunityusers.yaml
+kubectl apply -f config/crd/bases/mongodbcommunity.mongodb.com_mongodbcommunityroles.yaml